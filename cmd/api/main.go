@@ -2,24 +2,39 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"expvar"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
-	"time"
 
+	"github.com/riverqueue/river"
 	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/config"
 	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/handler"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/middleware"
 	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/repository"
 	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/service"
 	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/database"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/eventbus"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/filestore"
 	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/logger"
 	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/riverenqueuer"
 )
 
 func main() {
+	// The spec command generates the OpenAPI document and exits
+	if len(os.Args) > 1 && os.Args[1] == "spec" {
+		if err := runSpec(os.Args[2:]); err != nil {
+			log.Fatalf("spec: %v", err)
+		}
+		return
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -27,54 +42,234 @@ func main() {
 	}
 
 	// Initialize logger
-	appLogger := logger.New(cfg.Log.Level)
+	appLogger := logger.New(logger.Config{
+		Level:  cfg.Log.Level,
+		Format: cfg.Log.Format,
+		Output: cfg.Log.Output,
+		File: logger.FileConfig{
+			Path:       cfg.Log.File.Path,
+			MaxSizeMB:  cfg.Log.File.MaxSizeMB,
+			MaxAgeDays: cfg.Log.File.MaxAgeDays,
+			MaxBackups: cfg.Log.File.MaxBackups,
+			Compress:   cfg.Log.File.Compress,
+		},
+		Sampling: logger.SamplingConfig{
+			Enabled:    cfg.Log.Sampling.Enabled,
+			Tick:       cfg.Log.Sampling.Tick,
+			Initial:    cfg.Log.Sampling.Initial,
+			Thereafter: cfg.Log.Sampling.Thereafter,
+		},
+		RedactKeys:      cfg.Log.RedactKeys,
+		ComponentLevels: cfg.Log.ComponentLevels,
+	})
 	appLogger.Info("Starting application", "environment", cfg.Server.Environment)
 
+	// Background work is stopped when the server shuts down
+	appCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Per-statement query metrics, served at /admin/debug/vars
+	queryStats := database.NewQueryStats()
+	expvar.Publish("db_statements", expvar.Func(func() any { return queryStats.Snapshot() }))
+
 	// Initialize database connection
 	dbConfig := database.DBConfig{
 		DSN:             cfg.GetDSN(),
-		MaxOpenConns:    25,
-		MaxIdleConns:    5,
-		ConnMaxLifetime: 5 * time.Minute,
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Database.ConnMaxIdleTime,
+		Retry: database.RetryConfig{
+			MaxAttempts:    cfg.Database.ConnectMaxAttempts,
+			InitialBackoff: cfg.Database.ConnectInitialBackoff,
+			MaxBackoff:     cfg.Database.ConnectMaxBackoff,
+		},
+		Instrument: database.InstrumentConfig{
+			SlowThreshold: cfg.Database.SlowQueryThreshold,
+			Comment:       cfg.Database.QueryComments,
+			Tagger:        middleware.QueryTags,
+			Stats:         queryStats,
+			Logger:        appLogger.Component(logger.ComponentRepository),
+		},
 	}
 
-	db, err := database.NewPostgresConnection(dbConfig)
+	db, dbReady := connectDatabase(appCtx, dbConfig, cfg.Database.DegradedMode, appLogger.Logger)
+
+	// Domain events; asynchronous subscribers only see committed changes
+	eventBus := eventbus.New(eventbus.Config{
+		Schedule: database.AfterCommit,
+		Logger:   appLogger.Component(logger.ComponentService),
+	})
+
+	// Background jobs, including events bridged to durable subscribers
+	riverLogger := appLogger.Component(logger.ComponentRiver)
+	workers := river.NewWorkers()
+	eventBridge, err := riverenqueuer.NewEventBridge(db, eventBus, workers, riverLogger)
 	if err != nil {
-		appLogger.Error("Failed to connect to database", "error", err)
-		log.Fatalf("Database connection error: %v", err)
+		appLogger.Error("Failed to create event bridge", "error", err)
+		log.Fatalf("River client error: %v", err)
 	}
-	defer db.Close()
-	appLogger.Info("Database connection established")
+	jobEnqueuer, err := riverenqueuer.NewEnqueuer(db, riverLogger)
+	if err != nil {
+		appLogger.Error("Failed to create River enqueuer", "error", err)
+		log.Fatalf("River client error: %v", err)
+	}
+
+	dbMonitor := database.NewMonitor(db, cfg.Database.HealthCheckInterval, dbReady, appLogger.Logger)
 
-	// Run River migrations (idempotent - safe on every startup)
-	if err := riverenqueuer.MigrateUp(context.Background(), db); err != nil {
-		appLogger.Error("Failed to run River migrations", "error", err)
-		log.Fatalf("River migration error: %v", err)
+	// Route reads to replicas when any are configured
+	dbCluster := database.NewDB(db, appLogger.Logger)
+	for i, dsn := range cfg.GetReplicaDSNs() {
+		replicaConfig := dbConfig
+		replicaConfig.DSN = dsn
+
+		replica, err := database.Open(replicaConfig)
+		if err != nil {
+			appLogger.Error("Failed to open read replica", "error", err)
+			log.Fatalf("Database connection error: %v", err)
+		}
+		dbCluster.AddReplica(fmt.Sprintf("replica-%d", i+1), replica, cfg.Database.HealthCheckInterval)
 	}
-	appLogger.Info("River migrations completed")
+	defer dbCluster.Close()
+	dbCluster.Run(appCtx)
+
+	// Postgres notifications for cache invalidation and user events
+	listener := database.NewListener(cfg.GetDSN(), appLogger.Component(logger.ComponentRepository))
 
 	// Initialize repositories
-	userRepo := repository.NewUserRepository(db)
+	userRepo := repository.NewUserRepository(dbCluster)
+	userEventRepo := repository.NewUserEventRepository(dbCluster)
+	auditRepo := repository.NewAuditRepository(dbCluster, cfg.Audit.HashChain)
+	userTransferRepo := repository.NewUserTransferRepository(dbCluster)
+	if cfg.Cache.Enabled {
+		cacheStats := repository.NewCacheStats()
+		expvar.Publish("user_cache", expvar.Func(func() any { return cacheStats.Snapshot() }))
+
+		userRepo = repository.NewCachedUserRepository(userRepo, dbCluster, listener, repository.CacheConfig{
+			Size:        cfg.Cache.Size,
+			TTL:         cfg.Cache.TTL,
+			NegativeTTL: cfg.Cache.NegativeTTL,
+			Stats:       cacheStats,
+		}, appLogger.Component(logger.ComponentRepository))
+	}
 
 	// Initialize services
-	userService := service.NewUserService(userRepo, appLogger)
+	serviceLogger := appLogger.Component(logger.ComponentService)
+	userService := service.NewUserService(userRepo, dbCluster, eventBus, serviceLogger)
+	userEventService := service.NewUserEventService(userEventRepo, listener, service.UserEventConfig{
+		Retention:  cfg.Events.Retention,
+		BufferSize: cfg.Events.BufferSize,
+	}, serviceLogger)
+	go userEventService.Run(appCtx)
+	auditService := service.NewAuditService(auditRepo, eventBus, serviceLogger)
+	if cfg.Events.WebhookURL != "" {
+		err := service.NewUserWebhook(eventBridge, service.UserWebhookConfig{
+			URL:     cfg.Events.WebhookURL,
+			Timeout: cfg.Events.WebhookTimeout,
+		}, serviceLogger)
+		if err != nil {
+			appLogger.Error("Failed to subscribe user webhook", "error", err)
+			log.Fatalf("Configuration error: %v", err)
+		}
+	}
+
+	// Files uploaded for imports and written by exports
+	fileStore, err := filestore.NewDir(cfg.Transfer.Dir)
+	if err != nil {
+		appLogger.Error("Failed to open file store", "error", err)
+		log.Fatalf("File store error: %v", err)
+	}
+	userTransferService := service.NewUserTransferService(userTransferRepo, userRepo, dbCluster, eventBus, jobEnqueuer, workers, fileStore, service.UserTransferConfig{
+		ChunkSize:  cfg.Transfer.ChunkSize,
+		JobTimeout: cfg.Transfer.JobTimeout,
+		Retention:  cfg.Transfer.Retention,
+	}, serviceLogger)
+	go userTransferService.Run(appCtx)
+
+	// Every worker is registered, create the client that works jobs
+	jobClient, err := riverenqueuer.NewClient(db, workers, cfg.Jobs.Workers, riverLogger)
+	if err != nil {
+		appLogger.Error("Failed to create River client", "error", err)
+		log.Fatalf("River client error: %v", err)
+	}
+
+	// Run River migrations (idempotent - safe on every startup), then work jobs
+	startJobs := func(ctx context.Context) error {
+		if err := riverenqueuer.MigrateUp(ctx, db, riverLogger); err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+		appLogger.Info("River migrations completed")
+		return jobClient.Start(appCtx)
+	}
+
+	if dbReady {
+		if err := startJobs(appCtx); err != nil {
+			appLogger.Error("Failed to start River", "error", err)
+			log.Fatalf("River error: %v", err)
+		}
+	} else {
+		// Start once the database first comes back, retrying failures with
+		// backoff rather than waiting for the next recovery
+		var startOnce sync.Once
+		dbMonitor.OnRecover(func(context.Context) {
+			startOnce.Do(func() {
+				go func() {
+					retry := database.RetryConfig{
+						InitialBackoff: cfg.Database.ConnectInitialBackoff,
+						MaxBackoff:     cfg.Database.ConnectMaxBackoff,
+					}
+					if err := database.Retry(appCtx, retry, "failed to start River", riverLogger, startJobs); err != nil {
+						appLogger.Error("Failed to start River", "error", err)
+					}
+				}()
+			})
+		})
+	}
+	go dbMonitor.Run(appCtx)
+
+	// Every subscription is registered, start listening
+	go listener.Run(appCtx)
 
 	// Initialize handlers
-	userHandler := handler.NewUserHandler(userService, appLogger)
-	healthHandler := handler.NewHealthHandler()
+	handlerLogger := appLogger.Component(logger.ComponentHandler)
+	userHandler := handler.NewUserHandler(userService, cfg.Server.BatchMaxItems, handlerLogger)
+	userEventHandler := handler.NewUserEventHandler(userEventService, cfg.Events.Heartbeat, handlerLogger)
+	userTransferHandler := handler.NewUserTransferHandler(userTransferService, int64(cfg.Transfer.MaxUploadBytes), cfg.Transfer.FileTimeout, handlerLogger)
+	auditHandler := handler.NewAuditHandler(auditService, handlerLogger)
+	healthHandler := handler.NewHealthHandler(dbMonitor)
+	adminHandler := handler.NewAdminHandler(appLogger.Levels(), handlerLogger)
+
+	// Requests to deprecated API versions and routes, per route and API key
+	deprecatedRequests := expvar.NewMap("deprecated_requests")
 
 	// Setup router
-	router := handler.NewRouter(userHandler, healthHandler, appLogger)
+	router := handler.NewRouter(userHandler, userEventHandler, userTransferHandler, auditHandler, healthHandler, adminHandler, handler.RouterOptions{
+		APIKeys:  apiKeys(cfg.Auth),
+		Database: dbMonitor,
+		Timeouts: middleware.TimeoutBudgets{
+			Default: cfg.Server.RequestTimeout,
+			Routes:  cfg.Server.RouteTimeouts,
+		},
+		Problems: middleware.ProblemOptions{
+			Versions:    cfg.Server.ProblemDetailsVersions,
+			TypeBaseURL: cfg.Server.ProblemTypeBaseURL,
+		},
+		Deprecations:       apiDeprecations(cfg.Server),
+		DeprecatedRequests: deprecatedRequests,
+	}, handlerLogger)
 
 	// Create HTTP server
 	serverAddr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
 	srv := &http.Server{
-		Addr:         serverAddr,
-		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:              serverAddr,
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
 	}
+	srv.RegisterOnShutdown(userEventHandler.Shutdown)
 
 	// Start server in a goroutine
 	go func() {
@@ -88,11 +283,21 @@ func main() {
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	// Reload log levels on SIGHUP without restarting
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			reloadLogConfig(appLogger)
+		}
+	}()
+
 	<-quit
 
 	appLogger.Info("Server shutting down...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
@@ -100,5 +305,77 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Jobs such as imports publish events, so they stop before the bus
+	if err := jobClient.Stop(ctx); err != nil {
+		appLogger.Error("River did not stop cleanly", "error", err)
+	}
+	if err := eventBus.Close(ctx); err != nil {
+		appLogger.Error("Event subscribers did not finish", "error", err)
+	}
+
 	appLogger.Info("Server stopped gracefully")
 }
+
+// connectDatabase waits for the database using the configured retry policy.
+// In degraded mode a database that is still unreachable after the retries is
+// not fatal: the pool is returned with ready set to false and the monitor
+// reconnects in the background.
+func connectDatabase(ctx context.Context, cfg database.DBConfig, degraded bool, appLogger *slog.Logger) (*sql.DB, bool) {
+	if !degraded {
+		db, err := database.NewPostgresConnection(ctx, cfg, appLogger)
+		if err != nil {
+			appLogger.Error("Failed to connect to database", "error", err)
+			log.Fatalf("Database connection error: %v", err)
+		}
+		appLogger.Info("Database connection established")
+		return db, true
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		appLogger.Error("Failed to connect to database", "error", err)
+		log.Fatalf("Database connection error: %v", err)
+	}
+
+	if err := database.PingWithRetry(ctx, db, cfg.Retry, appLogger); err != nil {
+		appLogger.Warn("Database unavailable, starting in degraded mode", "error", err)
+		return db, false
+	}
+
+	appLogger.Info("Database connection established")
+	return db, true
+}
+
+// reloadLogConfig re-reads configuration and applies the root and
+// component log levels, discarding any overrides made at runtime.
+func reloadLogConfig(appLogger *logger.Logger) {
+	cfg, err := config.Load()
+	if err != nil {
+		appLogger.Error("Failed to reload configuration", "error", err)
+		return
+	}
+
+	appLogger.Levels().Apply(cfg.Log.Level, cfg.Log.ComponentLevels)
+	appLogger.Info("Log configuration reloaded", "level", cfg.Log.Level, "components", cfg.Log.ComponentLevels)
+}
+
+func apiKeys(cfg config.AuthConfig) []middleware.APIKey {
+	keys := make([]middleware.APIKey, 0, len(cfg.APIKeys))
+	for _, k := range cfg.APIKeys {
+		keys = append(keys, middleware.APIKey{
+			Key:       k.Key,
+			Principal: middleware.Principal{ID: k.Principal, Scopes: k.Scopes},
+		})
+	}
+	return keys
+}
+
+// apiDeprecations returns the deprecated API versions of cfg
+func apiDeprecations(cfg config.ServerConfig) map[string]middleware.Deprecation {
+	if cfg.V1DeprecatedSince.IsZero() {
+		return nil
+	}
+	return map[string]middleware.Deprecation{
+		"v1": {Since: cfg.V1DeprecatedSince, Sunset: cfg.V1Sunset},
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/config"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/handler"
+)
+
+// defaultSpecFile is where the OpenAPI document is committed
+const defaultSpecFile = "api/openapi.json"
+
+// runSpec writes the OpenAPI document of the router to a file, or with
+// -check fails when the file is not up to date. It needs neither the
+// configuration nor the database: the router is built with the default
+// settings and without handlers, which are never called.
+func runSpec(args []string) error {
+	flags := flag.NewFlagSet("spec", flag.ContinueOnError)
+	out := flags.String("o", defaultSpecFile, "file to write the document to, or - for standard output")
+	check := flags.Bool("check", false, "compare the document with the file instead of writing it")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	deprecations := apiDeprecations(config.Defaults().Server)
+	router := handler.NewRouter(nil, nil, nil, nil, nil, nil, handler.RouterOptions{Deprecations: deprecations}, slog.New(slog.DiscardHandler))
+	doc, err := handler.OpenAPI(router, deprecations)
+	if err != nil {
+		return err
+	}
+	data, err := doc.Marshal()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *check:
+		current, err := os.ReadFile(*out)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(current, data) {
+			return fmt.Errorf("%s is out of date, regenerate it with: go run ./cmd/api spec -o %s", *out, *out)
+		}
+		return nil
+	case *out == "-":
+		_, err := os.Stdout.Write(data)
+		return err
+	default:
+		return os.WriteFile(*out, data, 0o644)
+	}
+}
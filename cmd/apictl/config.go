@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// profilesFile is the apictl configuration file:
+//
+//	current_profile: staging
+//	profiles:
+//	  local:
+//	    base_url: http://localhost:8080
+//	  staging:
+//	    base_url: https://api.staging.example.com
+//	    api_key_file: staging.key
+//	    output: yaml
+//	    timeout: 1m
+type profilesFile struct {
+	CurrentProfile string             `yaml:"current_profile"`
+	Profiles       map[string]profile `yaml:"profiles"`
+}
+
+// profile is a server and the credentials to call it with. APIKeyFile is
+// read when APIKey is empty; relative paths are relative to the file.
+type profile struct {
+	BaseURL    string        `yaml:"base_url"`
+	APIKey     string        `yaml:"api_key"`
+	APIKeyFile string        `yaml:"api_key_file"`
+	Output     string        `yaml:"output"`
+	Timeout    time.Duration `yaml:"timeout"`
+}
+
+func defaultConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "apictl", "config.yaml")
+}
+
+// loadProfile reads the profile name from the file at path, or from the
+// default file when path is empty. Without a name it uses the file's
+// current profile, if any. A missing default file, or a missing profile
+// that was not asked for, is an empty profile; anything else asked for
+// that cannot be found is an error.
+func loadProfile(path, name string) (profile, error) {
+	explicitPath := path != ""
+	if !explicitPath {
+		path = defaultConfigFile()
+	}
+
+	var file profilesFile
+	content, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && !explicitPath && name == "":
+		return profile{}, nil
+	case errors.Is(err, fs.ErrNotExist) && !explicitPath:
+		return profile{}, fmt.Errorf("profile %q: no config file at %s", name, path)
+	case err != nil:
+		return profile{}, fmt.Errorf("reading config file: %w", err)
+	}
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return profile{}, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	if name == "" {
+		name = file.CurrentProfile
+	}
+	if name == "" {
+		return profile{}, nil
+	}
+	p, ok := file.Profiles[name]
+	if !ok {
+		return profile{}, fmt.Errorf("profile %q is not defined in %s", name, path)
+	}
+
+	if p.APIKey == "" && p.APIKeyFile != "" {
+		keyFile := p.APIKeyFile
+		if !filepath.IsAbs(keyFile) {
+			keyFile = filepath.Join(filepath.Dir(path), keyFile)
+		}
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return profile{}, fmt.Errorf("profile %q: reading API key: %w", name, err)
+		}
+		p.APIKey = strings.TrimSpace(string(key))
+	}
+	return p, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// errDryRun stops a request that --dry-run printed instead of sending
+var errDryRun = errors.New("dry run: request not sent")
+
+// dryRunTransport sends requests that only read, and prints the others,
+// with their body, instead of sending them
+type dryRunTransport struct {
+	next http.RoundTripper
+	w    io.Writer
+}
+
+func (t *dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return t.next.RoundTrip(req)
+	}
+
+	fmt.Fprintf(t.w, "%s %s\n", req.Method, req.URL)
+	for _, name := range []string{"Content-Type", "Authorization"} {
+		value := req.Header.Get(name)
+		if value == "" {
+			continue
+		}
+		if name == "Authorization" {
+			value = "Bearer <redacted>"
+		}
+		fmt.Fprintf(t.w, "%s: %s\n", name, value)
+	}
+
+	if req.Body != nil {
+		defer req.Body.Close()
+		if strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			var indented bytes.Buffer
+			if json.Indent(&indented, body, "", "  ") == nil {
+				body = indented.Bytes()
+			}
+			fmt.Fprintf(t.w, "\n%s\n", body)
+		} else {
+			size, err := io.Copy(io.Discard, req.Body)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(t.w, "\n<%d bytes>\n", size)
+		}
+	}
+	return nil, errDryRun
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/client"
+)
+
+const (
+	defaultBaseURL = "http://localhost:8080"
+	defaultTimeout = 30 * time.Second
+)
+
+// settings are the flags shared by every command. Empty values are taken
+// from the profile, then from the defaults.
+type settings struct {
+	configFile string
+	profile    string
+	baseURL    string
+	apiKey     string
+	output     string
+	timeout    time.Duration
+	dryRun     bool
+}
+
+// defaults reads the settings that can be set in the environment
+func (s *settings) defaults() {
+	s.configFile = os.Getenv("APICTL_CONFIG")
+	s.profile = os.Getenv("APICTL_PROFILE")
+	s.baseURL = os.Getenv("APICTL_BASE_URL")
+	s.apiKey = os.Getenv("APICTL_API_KEY")
+}
+
+// globalFlags are the names of the settings' flags, left out of the usage
+// of each command
+var globalFlags = map[string]bool{
+	"config": true, "profile": true, "base-url": true, "api-key": true,
+	"o": true, "output": true, "timeout": true, "dry-run": true,
+}
+
+// env is what a command runs with: the settings, resolved once the
+// command's flags are parsed, and where to write
+type env struct {
+	ctx      context.Context
+	stdout   io.Writer
+	stderr   io.Writer
+	settings settings
+}
+
+// flagSet returns a flag set for a command, with the settings' flags
+// registered alongside the command's own, so they can follow the command
+func (e *env) flagSet(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+
+	s := &e.settings
+	fs.StringVar(&s.configFile, "config", s.configFile, "profiles file")
+	fs.StringVar(&s.profile, "profile", s.profile, "profile to use")
+	fs.StringVar(&s.baseURL, "base-url", s.baseURL, "server root")
+	fs.StringVar(&s.apiKey, "api-key", s.apiKey, "API key")
+	fs.StringVar(&s.output, "o", s.output, "output format: table, json or yaml")
+	fs.StringVar(&s.output, "output", s.output, "output format: table, json or yaml")
+	fs.DurationVar(&s.timeout, "timeout", s.timeout, "timeout of each request")
+	fs.BoolVar(&s.dryRun, "dry-run", s.dryRun, "print requests that change data instead of sending them")
+
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "Usage: apictl %s [flags] %s\n", name, synopsis)
+		var own strings.Builder
+		fs.VisitAll(func(f *flag.Flag) {
+			if globalFlags[f.Name] {
+				return
+			}
+			kind, usage := flag.UnquoteUsage(f)
+			fmt.Fprintf(&own, "  --%s %s\n    \t%s", f.Name, kind, usage)
+			if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+				fmt.Fprintf(&own, " (default %s)", f.DefValue)
+			}
+			own.WriteString("\n")
+		})
+		if own.Len() > 0 {
+			fmt.Fprintf(e.stderr, "\nFlags:\n%s", own.String())
+		}
+		fmt.Fprint(e.stderr, "\nRun 'apictl -h' for the flags of every command.\n")
+	}
+	return fs
+}
+
+// parse parses a command's flags, which may come before, between or after
+// its n positional arguments, and resolves the settings against the profile
+func (e *env) parse(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return nil, errHelp
+			}
+			return nil, usageError{}
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if len(positional) != n {
+		fs.Usage()
+		return nil, usageError{}
+	}
+	if err := e.resolve(); err != nil {
+		return nil, err
+	}
+	return positional, nil
+}
+
+// resolve fills the settings left empty from the profile and the defaults
+func (e *env) resolve() error {
+	s := &e.settings
+
+	p, err := loadProfile(s.configFile, s.profile)
+	if err != nil {
+		return err
+	}
+	if s.baseURL == "" {
+		s.baseURL = cmp.Or(p.BaseURL, defaultBaseURL)
+	}
+	if s.apiKey == "" {
+		s.apiKey = p.APIKey
+	}
+	if s.output == "" {
+		s.output = cmp.Or(p.Output, outputTable)
+	}
+	if s.timeout == 0 {
+		s.timeout = cmp.Or(p.Timeout, defaultTimeout)
+	}
+
+	if !validOutput(s.output) {
+		return usageError{fmt.Sprintf("unknown output format %q; use table, json or yaml", s.output)}
+	}
+	return nil
+}
+
+// client returns a client of the resolved server. With --dry-run, requests
+// that would change data are printed to stdout and fail with errDryRun.
+func (e *env) client() (*client.Client, error) {
+	httpClient := &http.Client{Timeout: e.settings.timeout}
+	cfg := client.Config{
+		BaseURL:    e.settings.baseURL,
+		APIKey:     e.settings.apiKey,
+		HTTPClient: httpClient,
+		UserAgent:  "apictl",
+	}
+	if e.settings.dryRun {
+		httpClient.Transport = &dryRunTransport{next: http.DefaultTransport, w: e.stdout}
+		cfg.Retry.MaxAttempts = 1
+	}
+	return client.New(cfg)
+}
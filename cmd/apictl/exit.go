@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/client"
+)
+
+// Exit codes, documented in the usage
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2
+	exitNotFound    = 3
+	exitInvalid     = 4
+	exitAuth        = 5
+	exitConflict    = 6
+	exitUnavailable = 7
+	exitServer      = 8
+)
+
+// exitCodes maps the API error codes whose meaning does not depend on the
+// status they come with
+var exitCodes = map[string]int{
+	"NOT_FOUND":           exitNotFound,
+	"UNAUTHORIZED":        exitAuth,
+	"FORBIDDEN":           exitAuth,
+	"SERVICE_UNAVAILABLE": exitUnavailable,
+	"TIMEOUT":             exitUnavailable,
+	"CANCELLED":           exitUnavailable,
+}
+
+var errHelp = flag.ErrHelp
+
+// usageError is a command used wrongly. Its message is empty when the
+// flag package has already reported the problem.
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+// exitCode returns the exit code of a command's error: by the API error
+// code when it has one of its own, else by the response's status
+func exitCode(err error) int {
+	var usageErr usageError
+	switch {
+	case err == nil, errors.Is(err, errHelp), errors.Is(err, errDryRun):
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	}
+
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		return exitError
+	}
+	if code, ok := exitCodes[apiErr.Code()]; ok {
+		return code
+	}
+
+	switch status := apiErr.StatusCode; {
+	case status == http.StatusNotFound:
+		return exitNotFound
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return exitAuth
+	case status == http.StatusConflict:
+		return exitConflict
+	case status == http.StatusTooManyRequests, status == http.StatusServiceUnavailable, status == http.StatusGatewayTimeout:
+		return exitUnavailable
+	case status >= http.StatusInternalServerError:
+		return exitServer
+	default:
+		return exitInvalid
+	}
+}
+
+// report writes err to w, one line per API error detail
+func report(w io.Writer, err error) {
+	var usageErr usageError
+	if errors.Is(err, errHelp) || (errors.As(err, &usageErr) && usageErr.msg == "") {
+		return
+	}
+
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		fmt.Fprintf(w, "apictl: %v\n", err)
+		return
+	}
+	for _, d := range apiErr.Details {
+		fmt.Fprintf(w, "apictl: %s: %s", d.Code, d.Message)
+		if d.Field != "" {
+			fmt.Fprintf(w, " (field %s)", d.Field)
+		}
+		fmt.Fprintln(w)
+	}
+	if apiErr.APIID != "" {
+		fmt.Fprintf(w, "apictl: api_id %s, status %d\n", apiErr.APIID, apiErr.StatusCode)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/client"
+)
+
+// runHealth checks liveness, then readiness. A server that is alive but not
+// ready is still reported, and exits as unavailable.
+func runHealth(e *env, args []string) error {
+	fs := e.flagSet("health", "")
+	if _, err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	live, err := c.Health(e.ctx)
+	if err != nil {
+		return err
+	}
+	ready, readyErr := c.Ready(e.ctx)
+	if readyErr != nil && client.StatusCode(readyErr) == 0 {
+		return readyErr
+	}
+	if ready == nil {
+		ready = &client.Health{Status: "not ready"}
+	}
+
+	result := map[string]*client.Health{"live": live, "ready": ready}
+	err = e.print(result, func() table {
+		rows := table{{"CHECK", "STATUS"}, {"live", live.Status}, {"ready", ready.Status}}
+		for _, name := range slices.Sorted(maps.Keys(ready.Checks)) {
+			rows = append(rows, []string{name, ready.Checks[name]})
+		}
+		return rows
+	})
+	if err != nil {
+		return err
+	}
+	return readyErr
+}
@@ -0,0 +1,119 @@
+// Command apictl is a command-line client of the API for operators. It is
+// built on package client, so requests are retried like any other client's,
+// and reads its server and credentials from named profiles.
+//
+//	apictl --profile staging users list -o yaml
+//	apictl users create --email jane@example.com --name Jane --dry-run
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+const usage = `Usage: apictl [flags] <command> [arguments]
+
+Commands:
+  health                     report whether the server is alive and ready
+  users list                 list users
+  users get <id>             show a user, with --as-of for a past version
+  users create               create a user from --email and --name
+  users update <id>          change a user's --email or --name
+  users delete <id>          delete a user
+  users import <file>        import users from a CSV or NDJSON file
+  users export               export users, with --out to download the file
+
+Flags, accepted before or after the command:
+  --config FILE      profiles file (default $APICTL_CONFIG, or apictl/config.yaml
+                     in the user config directory)
+  --profile NAME     profile to use (default $APICTL_PROFILE, or current_profile)
+  --base-url URL     server root, overriding the profile ($APICTL_BASE_URL)
+  --api-key KEY      API key, overriding the profile ($APICTL_API_KEY)
+  -o, --output FMT   table, json or yaml (default table)
+  --timeout DUR      timeout of each request (default 30s)
+  --dry-run          print requests that would change data instead of sending them
+
+Exit codes:
+  0  success              5  unauthorized or forbidden
+  1  other failure        6  conflict
+  2  usage error          7  unavailable, rate limited or timed out
+  3  not found            8  server error
+  4  invalid request
+
+Run 'apictl <command> -h' for the flags of a command.
+`
+
+// command is a subcommand, named by one or two words
+type command struct {
+	name string
+	run  func(e *env, args []string) error
+}
+
+var commands = []command{
+	{"health", runHealth},
+	{"users list", runUsersList},
+	{"users get", runUsersGet},
+	{"users create", runUsersCreate},
+	{"users update", runUsersUpdate},
+	{"users delete", runUsersDelete},
+	{"users import", runUsersImport},
+	{"users export", runUsersExport},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	e := &env{ctx: ctx, stdout: stdout, stderr: stderr}
+	e.settings.defaults()
+
+	root := e.flagSet("apictl", "")
+	root.Usage = func() { fmt.Fprint(stderr, usage) }
+	if err := root.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	cmd, rest, ok := lookup(root.Args())
+	if !ok {
+		if len(root.Args()) > 0 {
+			fmt.Fprintf(stderr, "apictl: unknown command %q\n\n", strings.Join(root.Args(), " "))
+		}
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	err := cmd.run(e, rest)
+	switch {
+	case errors.Is(err, errDryRun):
+		fmt.Fprintln(stderr, "apictl: dry run, request not sent")
+	case err != nil:
+		report(stderr, err)
+	}
+	return exitCode(err)
+}
+
+// lookup finds the command named by the first words of args and returns
+// the arguments that follow its name
+func lookup(args []string) (command, []string, bool) {
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == cmd.name {
+			return cmd, args[len(words):], true
+		}
+	}
+	return command{}, nil, false
+}
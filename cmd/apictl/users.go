@@ -0,0 +1,312 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/client"
+)
+
+func userRows(users ...*client.User) func() table {
+	return func() table {
+		rows := table{{"ID", "EMAIL", "NAME", "CREATED", "UPDATED"}}
+		for _, u := range users {
+			rows = append(rows, []string{strconv.FormatInt(u.ID, 10), u.Email, u.Name, formatTime(&u.CreatedAt), formatTime(&u.UpdatedAt)})
+		}
+		return rows
+	}
+}
+
+func transferRows(t *client.UserTransfer) func() table {
+	return func() table {
+		c := t.Counts
+		rows := table{
+			{"ID", "KIND", "STATUS", "FORMAT", "PROCESSED", "CREATED", "UPDATED", "UNCHANGED", "FAILED", "FINISHED"},
+			{t.ID, t.Kind, t.Status, t.Format, strconv.Itoa(c.Processed), strconv.Itoa(c.Created), strconv.Itoa(c.Updated),
+				strconv.Itoa(c.Unchanged), strconv.Itoa(c.Failed), formatTime(t.FinishedAt)},
+		}
+		if t.Error != "" {
+			rows = append(rows, []string{"", "error: " + t.Error})
+		}
+		return rows
+	}
+}
+
+func parseID(value string) (int64, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 1 {
+		return 0, usageError{fmt.Sprintf("invalid user id %q", value)}
+	}
+	return id, nil
+}
+
+func runUsersList(e *env, args []string) error {
+	fs := e.flagSet("users list", "")
+	if _, err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	users, err := c.Users.List(e.ctx)
+	if err != nil {
+		return err
+	}
+	return e.print(users, userRows(users...))
+}
+
+func runUsersGet(e *env, args []string) error {
+	fs := e.flagSet("users get", "<id>")
+	asOf := fs.String("as-of", "", "show the user as it was at this RFC 3339 time")
+	positional, err := e.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(positional[0])
+	if err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	var user *client.User
+	if *asOf != "" {
+		at, parseErr := time.Parse(time.RFC3339, *asOf)
+		if parseErr != nil {
+			return usageError{fmt.Sprintf("invalid --as-of time %q; use RFC 3339, such as 2024-01-02T15:04:05Z", *asOf)}
+		}
+		user, err = c.Users.GetAsOf(e.ctx, id, at)
+	} else {
+		user, err = c.Users.Get(e.ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+	return e.print(user, userRows(user))
+}
+
+func runUsersCreate(e *env, args []string) error {
+	fs := e.flagSet("users create", "--email EMAIL --name NAME")
+	email := fs.String("email", "", "email of the user")
+	name := fs.String("name", "", "name of the user")
+	if _, err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *email == "" || *name == "" {
+		return usageError{"--email and --name are required"}
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	user, err := c.Users.Create(e.ctx, client.CreateUserRequest{Email: *email, Name: *name})
+	if err != nil {
+		return err
+	}
+	return e.print(user, userRows(user))
+}
+
+func runUsersUpdate(e *env, args []string) error {
+	fs := e.flagSet("users update", "<id> [--email EMAIL] [--name NAME]")
+	email := fs.String("email", "", "new email of the user")
+	name := fs.String("name", "", "new name of the user")
+	positional, err := e.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(positional[0])
+	if err != nil {
+		return err
+	}
+	if *email == "" && *name == "" {
+		return usageError{"nothing to update; set --email or --name"}
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	user, err := c.Users.Update(e.ctx, id, client.UpdateUserRequest{Email: *email, Name: *name})
+	if err != nil {
+		return err
+	}
+	return e.print(user, userRows(user))
+}
+
+func runUsersDelete(e *env, args []string) error {
+	fs := e.flagSet("users delete", "<id>")
+	positional, err := e.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(positional[0])
+	if err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	if err := c.Users.Delete(e.ctx, id); err != nil {
+		return err
+	}
+	result := map[string]interface{}{"id": id, "deleted": true}
+	return e.print(result, func() table {
+		return table{{fmt.Sprintf("Deleted user %d", id)}}
+	})
+}
+
+// mappingFlag collects repeated field=column flags
+type mappingFlag map[string]string
+
+func (m mappingFlag) String() string {
+	pairs := make([]string, 0, len(m))
+	for field, column := range m {
+		pairs = append(pairs, field+"="+column)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m mappingFlag) Set(value string) error {
+	field, column, ok := strings.Cut(value, "=")
+	if !ok || field == "" || column == "" {
+		return errors.New("want field=column")
+	}
+	m[field] = column
+	return nil
+}
+
+func runUsersImport(e *env, args []string) error {
+	fs := e.flagSet("users import", "<file>")
+	format := fs.String("format", "", "csv or ndjson (default from the file extension)")
+	mapping := mappingFlag{}
+	fs.Var(mapping, "map", "read a user field from another column or key, as field=column; repeatable")
+	delimiter := fs.String("delimiter", "", "CSV field delimiter (default ,)")
+	validate := fs.Bool("validate", false, "have the server validate the rows without writing them")
+	wait := fs.Bool("wait", false, "wait until the import has finished")
+	errorsFile := fs.String("errors", "", "with --wait, write the report of rejected rows to this file")
+	interval := fs.Duration("interval", 2*time.Second, "how often to check the import with --wait")
+	positional, err := e.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	path := positional[0]
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	if *format != client.FormatCSV && *format != client.FormatNDJSON {
+		return usageError{fmt.Sprintf("cannot import %q files; set --format csv or ndjson", *format)}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	opts := client.TransferOptions{Mapping: mapping, Delimiter: *delimiter, DryRun: *validate}
+	transfer, err := c.Users.StartImport(e.ctx, file, *format, opts)
+	if err != nil {
+		return err
+	}
+	if *wait {
+		if transfer, err = c.Users.WaitForTransfer(e.ctx, transfer, *interval); err != nil {
+			return err
+		}
+	}
+	if err := e.print(transfer, transferRows(transfer)); err != nil {
+		return err
+	}
+
+	if *errorsFile != "" && transfer.Counts.Failed > 0 {
+		report, err := c.Users.ImportErrors(e.ctx, transfer.ID)
+		if err != nil {
+			return err
+		}
+		defer report.Close()
+		if err := writeFile(*errorsFile, report); err != nil {
+			return err
+		}
+	}
+	if transfer.Status == client.TransferFailed {
+		return fmt.Errorf("import %s failed: %s", transfer.ID, transfer.Error)
+	}
+	return nil
+}
+
+func runUsersExport(e *env, args []string) error {
+	fs := e.flagSet("users export", "[--out FILE]")
+	format := fs.String("format", client.FormatCSV, "csv, ndjson or json")
+	out := fs.String("out", "", "wait for the export and download it to this file, or - for standard output")
+	wait := fs.Bool("wait", false, "wait until the export has finished")
+	interval := fs.Duration("interval", 2*time.Second, "how often to check the export while waiting")
+	if _, err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	transfer, err := c.Users.StartExport(e.ctx, *format)
+	if err != nil {
+		return err
+	}
+	if *wait || *out != "" {
+		if transfer, err = c.Users.WaitForTransfer(e.ctx, transfer, *interval); err != nil {
+			return err
+		}
+	}
+	if transfer.Status == client.TransferFailed {
+		return fmt.Errorf("export %s failed: %s", transfer.ID, transfer.Error)
+	}
+
+	if *out == "" {
+		return e.print(transfer, transferRows(transfer))
+	}
+	file, err := c.Users.DownloadExport(e.ctx, transfer.ID)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if *out == "-" {
+		_, err = io.Copy(e.stdout, file)
+		return err
+	}
+	if err := writeFile(*out, file); err != nil {
+		return err
+	}
+	return e.print(transfer, transferRows(transfer))
+}
+
+// writeFile writes r to path, removing what was written if it fails
+func writeFile(path string, r io.Reader) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
@@ -3,66 +3,270 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Config is assembled from layered sources, each overriding the previous:
+// struct tag defaults, the built-in profile for the environment, the config
+// file, the file's profile for the environment, environment variables and
+// finally command-line flags.
+//
+// Field tags:
+//   - config: key in the config file; nested keys form the flag name (--server.port)
+//   - env: environment variable; a matching *_FILE variable reads the value from a file
+//   - default: value used when no source sets the field
+//   - validate: comma-separated rules checked after loading (see validate.go)
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Log      LogConfig
+	Server   ServerConfig   `config:"server"`
+	Database DatabaseConfig `config:"database"`
+	Log      LogConfig      `config:"log"`
+	Auth     AuthConfig     `config:"auth"`
+	Cache    CacheConfig    `config:"cache"`
+	Events   EventsConfig   `config:"events"`
+	Jobs     JobsConfig     `config:"jobs"`
+	Audit    AuditConfig    `config:"audit"`
+	Transfer TransferConfig `config:"transfer"`
 }
 
 type ServerConfig struct {
-	Port        string
-	Host        string
-	Environment string
+	Port              string        `config:"port" env:"SERVER_PORT" default:"8080" validate:"required,port"`
+	Host              string        `config:"host" env:"SERVER_HOST" default:"0.0.0.0"`
+	Environment       string        `config:"environment" env:"ENVIRONMENT" default:"development" validate:"oneof=development test staging production"`
+	ReadTimeout       time.Duration `config:"read_timeout" env:"SERVER_READ_TIMEOUT" default:"15s" validate:"min=0"`
+	ReadHeaderTimeout time.Duration `config:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT" default:"5s" validate:"min=0"`
+	WriteTimeout      time.Duration `config:"write_timeout" env:"SERVER_WRITE_TIMEOUT" default:"15s" validate:"min=0"`
+	IdleTimeout       time.Duration `config:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" default:"60s" validate:"min=0"`
+	ShutdownTimeout   time.Duration `config:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" default:"30s" validate:"min=1s"`
+	MaxHeaderBytes    int           `config:"max_header_bytes" env:"SERVER_MAX_HEADER_BYTES" default:"1048576" validate:"min=1024"`
+
+	// Request budgets: RouteTimeouts overrides RequestTimeout for routes
+	// keyed by method and pattern, e.g. "GET /api/v1/users": 30s.
+	// A zero budget disables the timeout for that route.
+	RequestTimeout time.Duration            `config:"request_timeout" env:"SERVER_REQUEST_TIMEOUT" default:"10s" validate:"min=0"`
+	RouteTimeouts  map[string]time.Duration `config:"route_timeouts" env:"SERVER_ROUTE_TIMEOUTS"`
+
+	// Maximum items accepted by the batch endpoints
+	BatchMaxItems int `config:"batch_max_items" env:"SERVER_BATCH_MAX_ITEMS" default:"1000" validate:"min=1,max=10000"`
+
+	// Error format: API versions, e.g. "v1", whose errors are RFC 9457
+	// Problem Details unless the client asks for application/json, and the
+	// prefix of the type URIs of problems, followed by the error code
+	ProblemDetailsVersions []string `config:"problem_details_versions" env:"SERVER_PROBLEM_DETAILS_VERSIONS" default:"v2"`
+	ProblemTypeBaseURL     string   `config:"problem_type_base_url" env:"SERVER_PROBLEM_TYPE_BASE_URL" default:"/api/v1/errors/"`
+
+	// Deprecation of API v1 in favour of v2, as dates such as 2027-05-01 or
+	// RFC 3339 times: since when it is deprecated, and when it stops being
+	// served. Neither is set by default, so v1 is neither deprecated nor
+	// sunset unless configured; without a sunset it is served indefinitely.
+	V1DeprecatedSince time.Time `config:"v1_deprecated_since" env:"SERVER_V1_DEPRECATED_SINCE"`
+	V1Sunset          time.Time `config:"v1_sunset" env:"SERVER_V1_SUNSET"`
 }
 
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Host            string        `config:"host" env:"DB_HOST" default:"localhost" validate:"required"`
+	Port            string        `config:"port" env:"DB_PORT" default:"5432" validate:"required,port"`
+	User            string        `config:"user" env:"DB_USER" default:"postgres" validate:"required"`
+	Password        string        `config:"password" env:"DB_PASSWORD" default:"postgres"`
+	DBName          string        `config:"name" env:"DB_NAME" default:"go_api_db" validate:"required"`
+	SSLMode         string        `config:"sslmode" env:"DB_SSLMODE" default:"disable" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+	MaxOpenConns    int           `config:"max_open_conns" env:"DB_MAX_OPEN_CONNS" default:"25" validate:"min=1"`
+	MaxIdleConns    int           `config:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" default:"5" validate:"min=0"`
+	ConnMaxLifetime time.Duration `config:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" default:"5m" validate:"min=0"`
+	ConnMaxIdleTime time.Duration `config:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" default:"0s" validate:"min=0"`
+
+	// Query instrumentation
+	SlowQueryThreshold time.Duration `config:"slow_query_threshold" env:"DB_SLOW_QUERY_THRESHOLD" default:"200ms" validate:"min=0"`
+	QueryComments      bool          `config:"query_comments" env:"DB_QUERY_COMMENTS" default:"false"`
+
+	// Read replicas as host or host:port, sharing the primary's credentials
+	ReplicaHosts []string `config:"replica_hosts" env:"DB_REPLICA_HOSTS"`
+
+	// Startup retry and degraded mode
+	ConnectMaxAttempts    int           `config:"connect_max_attempts" env:"DB_CONNECT_MAX_ATTEMPTS" default:"10" validate:"min=0"`
+	ConnectInitialBackoff time.Duration `config:"connect_initial_backoff" env:"DB_CONNECT_INITIAL_BACKOFF" default:"500ms" validate:"min=1ms"`
+	ConnectMaxBackoff     time.Duration `config:"connect_max_backoff" env:"DB_CONNECT_MAX_BACKOFF" default:"30s" validate:"min=1ms"`
+	DegradedMode          bool          `config:"degraded_mode" env:"DB_DEGRADED_MODE" default:"false"`
+	HealthCheckInterval   time.Duration `config:"health_check_interval" env:"DB_HEALTH_CHECK_INTERVAL" default:"5s" validate:"min=100ms"`
 }
 
 type LogConfig struct {
-	Level string
+	Level           string            `config:"level" env:"LOG_LEVEL" default:"info" validate:"oneof=debug info warn error"`
+	Format          string            `config:"format" env:"LOG_FORMAT" default:"json" validate:"oneof=json text pretty"`
+	Output          string            `config:"output" env:"LOG_OUTPUT" default:"stdout" validate:"oneof=stdout stderr file"`
+	File            LogFileConfig     `config:"file"`
+	Sampling        LogSamplingConfig `config:"sampling"`
+	RedactKeys      []string          `config:"redact_keys" env:"LOG_REDACT_KEYS" default:"email,password,authorization"`
+	ComponentLevels map[string]string `config:"component_levels" env:"LOG_COMPONENT_LEVELS"`
+}
+
+type LogFileConfig struct {
+	Path       string `config:"path" env:"LOG_FILE_PATH" default:"logs/app.log"`
+	MaxSizeMB  int    `config:"max_size_mb" env:"LOG_FILE_MAX_SIZE_MB" default:"100" validate:"min=1"`
+	MaxAgeDays int    `config:"max_age_days" env:"LOG_FILE_MAX_AGE_DAYS" default:"7" validate:"min=0"`
+	MaxBackups int    `config:"max_backups" env:"LOG_FILE_MAX_BACKUPS" default:"5" validate:"min=0"`
+	Compress   bool   `config:"compress" env:"LOG_FILE_COMPRESS" default:"true"`
+}
+
+type LogSamplingConfig struct {
+	Enabled    bool          `config:"enabled" env:"LOG_SAMPLING_ENABLED" default:"false"`
+	Tick       time.Duration `config:"tick" env:"LOG_SAMPLING_TICK" default:"1s" validate:"min=1ms"`
+	Initial    int           `config:"initial" env:"LOG_SAMPLING_INITIAL" default:"100" validate:"min=1"`
+	Thereafter int           `config:"thereafter" env:"LOG_SAMPLING_THEREAFTER" default:"100" validate:"min=1"`
+}
+
+type AuthConfig struct {
+	APIKeys []APIKeyConfig `config:"api_keys" env:"AUTH_API_KEYS"`
+}
+
+// CacheConfig sizes the in-memory user cache. Instances invalidate each
+// other's entries through Postgres LISTEN/NOTIFY.
+type CacheConfig struct {
+	Enabled     bool          `config:"enabled" env:"CACHE_ENABLED" default:"true"`
+	Size        int           `config:"size" env:"CACHE_SIZE" default:"10000" validate:"min=1"`
+	TTL         time.Duration `config:"ttl" env:"CACHE_TTL" default:"5m" validate:"min=1s"`
+	NegativeTTL time.Duration `config:"negative_ttl" env:"CACHE_NEGATIVE_TTL" default:"30s" validate:"min=0"`
+}
+
+// EventsConfig controls the user event stream at /api/v1/users/events.
+type EventsConfig struct {
+	Heartbeat  time.Duration `config:"heartbeat" env:"EVENTS_HEARTBEAT" default:"15s" validate:"min=1s"`
+	Retention  time.Duration `config:"retention" env:"EVENTS_RETENTION" default:"24h" validate:"min=1m"`
+	BufferSize int           `config:"buffer_size" env:"EVENTS_BUFFER_SIZE" default:"64" validate:"min=1"`
+	// URL user events are POSTed to by a durable subscriber, none when empty
+	WebhookURL     string        `config:"webhook_url" env:"EVENTS_WEBHOOK_URL"`
+	WebhookTimeout time.Duration `config:"webhook_timeout" env:"EVENTS_WEBHOOK_TIMEOUT" default:"10s" validate:"min=1s"`
+}
+
+// JobsConfig controls the River client that works background jobs.
+type JobsConfig struct {
+	Workers int `config:"workers" env:"JOBS_WORKERS" default:"10" validate:"min=1"`
+}
+
+// AuditConfig controls the audit trail. With HashChain, each audit event
+// stores a hash covering the previous one so that edits are detectable.
+type AuditConfig struct {
+	HashChain bool `config:"hash_chain" env:"AUDIT_HASH_CHAIN" default:"false"`
+}
+
+// TransferConfig controls user imports and exports. Uploaded and generated
+// files are kept in Dir until Retention after the transfer finished.
+type TransferConfig struct {
+	Dir            string        `config:"dir" env:"TRANSFER_DIR" default:"data/transfers" validate:"required"`
+	MaxUploadBytes int           `config:"max_upload_bytes" env:"TRANSFER_MAX_UPLOAD_BYTES" default:"104857600" validate:"min=1"`
+	ChunkSize      int           `config:"chunk_size" env:"TRANSFER_CHUNK_SIZE" default:"500" validate:"min=1,max=10000"`
+	JobTimeout     time.Duration `config:"job_timeout" env:"TRANSFER_JOB_TIMEOUT" default:"1h" validate:"min=1m"`
+	// How long an upload or download may take, beyond the server's read and
+	// write timeouts
+	FileTimeout time.Duration `config:"file_timeout" env:"TRANSFER_FILE_TIMEOUT" default:"10m" validate:"min=1s"`
+	Retention   time.Duration `config:"retention" env:"TRANSFER_RETENTION" default:"168h" validate:"min=1h"`
+}
+
+// APIKeyConfig maps a bearer token to the principal it authenticates
+// and the scopes that principal is granted.
+type APIKeyConfig struct {
+	Principal string   `config:"principal"`
+	Key       string   `config:"key"`
+	Scopes    []string `config:"scopes"`
+}
+
+// profiles are applied on top of the tag defaults for the matching
+// environment, before any file, env or flag values.
+var profiles = map[string]map[string]any{
+	"development": {
+		"log": map[string]any{"format": "pretty"},
+	},
+	"production": {
+		"log": map[string]any{"format": "json"},
+	},
 }
 
+// Defaults returns the configuration of the struct tag defaults alone,
+// without reading any file, environment variable or flag.
+func Defaults() *Config {
+	cfg := &Config{}
+	l := newLoader(cfg)
+	l.applyDefaults()
+	if len(l.errs) > 0 {
+		panic(&ValidationError{Fields: l.errs})
+	}
+	return cfg
+}
+
+// Load builds the configuration from os.Args. Every invalid field is
+// reported in the returned error.
 func Load() (*Config, error) {
+	return LoadArgs(os.Args[1:])
+}
+
+// LoadArgs builds the configuration using args as command-line flags.
+// The config file is taken from --config or CONFIG_FILE.
+func LoadArgs(args []string) (*Config, error) {
 	// Load .env file if it exists (ignore error if file doesn't exist)
 	_ = godotenv.Load()
 
-	cfg := &Config{
-		Server: ServerConfig{
-			Port:        getEnv("SERVER_PORT", "8080"),
-			Host:        getEnv("SERVER_HOST", "0.0.0.0"),
-			Environment: getEnv("ENVIRONMENT", "development"),
-		},
-		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "postgres"),
-			DBName:   getEnv("DB_NAME", "go_api_db"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
-		},
-		Log: LogConfig{
-			Level: getEnv("LOG_LEVEL", "info"),
-		},
+	cfg := &Config{}
+	l := newLoader(cfg)
+
+	flags, err := l.parseFlags(args)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := readFile(firstNonEmpty(flags["config"], os.Getenv("CONFIG_FILE")))
+	if err != nil {
+		return nil, err
+	}
+
+	// The environment selects profiles, so resolve it before layering
+	envEnvironment, _ := l.lookupEnv("ENVIRONMENT")
+	environment := firstNonEmpty(flags["server.environment"], envEnvironment, fileEnvironment(file), "development")
+
+	l.applyDefaults()
+	l.applyMap(profiles[environment], "profile "+environment)
+	if file != nil {
+		l.applyMap(file, "config file")
+		if fileProfiles, ok := file["profiles"].(map[string]any); ok {
+			if p, ok := fileProfiles[environment].(map[string]any); ok {
+				l.applyMap(p, "config file profile "+environment)
+			}
+		}
+	}
+	l.applyEnv()
+	l.applyFlags(flags)
+
+	l.validate()
+	if len(l.errs) > 0 {
+		return nil, &ValidationError{Fields: l.errs}
 	}
 
 	return cfg, nil
 }
 
 func (c *Config) GetDSN() string {
+	return c.dsn(c.Database.Host, c.Database.Port)
+}
+
+// GetReplicaDSNs returns a DSN for every configured read replica.
+func (c *Config) GetReplicaDSNs() []string {
+	dsns := make([]string, 0, len(c.Database.ReplicaHosts))
+	for _, hostPort := range c.Database.ReplicaHosts {
+		host, port, found := strings.Cut(hostPort, ":")
+		if !found {
+			port = c.Database.Port
+		}
+		dsns = append(dsns, c.dsn(host, port))
+	}
+	return dsns
+}
+
+func (c *Config) dsn(host, port string) string {
 	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		c.Database.Host,
-		c.Database.Port,
+		host,
+		port,
 		c.Database.User,
 		c.Database.Password,
 		c.Database.DBName,
@@ -70,9 +274,34 @@ func (c *Config) GetDSN() string {
 	)
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// UnmarshalText parses the env and flag form principal:key[:scope1|scope2].
+func (k *APIKeyConfig) UnmarshalText(text []byte) error {
+	parts := strings.SplitN(string(text), ":", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("expected principal:key[:scopes], got %q", text)
+	}
+
+	*k = APIKeyConfig{Principal: parts[0], Key: parts[1]}
+	if len(parts) == 3 && parts[2] != "" {
+		k.Scopes = strings.Split(parts[2], "|")
+	}
+	return nil
+}
+
+func fileEnvironment(file map[string]any) string {
+	if server, ok := file["server"].(map[string]any); ok {
+		if env, ok := server["environment"].(string); ok {
+			return env
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
 	}
-	return defaultValue
+	return ""
 }
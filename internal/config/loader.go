@@ -0,0 +1,385 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// loader maps the layered sources onto a Config through its struct tags,
+// collecting every error instead of stopping at the first one.
+type loader struct {
+	root   reflect.Value
+	fields []field
+	errs   []FieldError
+}
+
+// field is a leaf setting of the Config tree.
+type field struct {
+	path  string // dotted config file path, also used as the flag name
+	env   string
+	def   string
+	rules string
+	value reflect.Value
+}
+
+func newLoader(cfg *Config) *loader {
+	l := &loader{root: reflect.ValueOf(cfg).Elem()}
+	l.collect(l.root, "")
+	return l
+}
+
+func (l *loader) collect(v reflect.Value, prefix string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		path := joinPath(prefix, sf.Tag.Get("config"))
+
+		if isSection(sf.Type) {
+			l.collect(v.Field(i), path)
+			continue
+		}
+
+		l.fields = append(l.fields, field{
+			path:  path,
+			env:   sf.Tag.Get("env"),
+			def:   sf.Tag.Get("default"),
+			rules: sf.Tag.Get("validate"),
+			value: v.Field(i),
+		})
+	}
+}
+
+func (l *loader) applyDefaults() {
+	for _, f := range l.fields {
+		f.value.Set(reflect.Zero(f.value.Type()))
+		if f.def == "" {
+			continue
+		}
+		if err := setValue(f.value, f.def); err != nil {
+			l.fail(f.path, "default", err.Error())
+		}
+	}
+}
+
+// applyMap sets fields from a decoded config file or profile.
+func (l *loader) applyMap(values map[string]any, source string) {
+	l.applySection(l.root, "", values, source)
+}
+
+func (l *loader) applySection(v reflect.Value, prefix string, values map[string]any, source string) {
+	for key, raw := range values {
+		if prefix == "" && key == "profiles" {
+			continue
+		}
+
+		path := joinPath(prefix, key)
+		fv, ok := fieldByKey(v, key)
+		if !ok {
+			l.fail(path, source, "unknown setting")
+			continue
+		}
+
+		if isSection(fv.Type()) {
+			section, ok := raw.(map[string]any)
+			if !ok {
+				l.fail(path, source, "expected a mapping")
+				continue
+			}
+			l.applySection(fv, path, section, source)
+			continue
+		}
+
+		if err := setValue(fv, raw); err != nil {
+			l.fail(path, source, err.Error())
+		}
+	}
+}
+
+func (l *loader) applyEnv() {
+	for _, f := range l.fields {
+		if f.env == "" {
+			continue
+		}
+
+		value, err := l.lookupEnv(f.env)
+		if err != nil {
+			l.fail(f.path, f.env, err.Error())
+			continue
+		}
+		if value == "" {
+			continue
+		}
+
+		if err := setValue(f.value, value); err != nil {
+			l.fail(f.path, f.env, err.Error())
+		}
+	}
+}
+
+// lookupEnv reads key from the environment, or from the file named by
+// key_FILE so secrets can be mounted rather than passed as variables.
+func (l *loader) lookupEnv(key string) (string, error) {
+	value := os.Getenv(key)
+
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return value, nil
+	}
+	if value != "" {
+		return "", fmt.Errorf("both %s and %s_FILE are set", key, key)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %w", key, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// parseFlags defines a flag for every field plus --config and returns the
+// values of the flags that were explicitly set.
+func (l *loader) parseFlags(args []string) (map[string]string, error) {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.String("config", "", "path to a YAML or JSON config file (env CONFIG_FILE)")
+	for _, f := range l.fields {
+		usage := f.path
+		if f.env != "" {
+			usage = "env " + f.env
+		}
+		fs.String(f.path, f.def, usage)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]string)
+	fs.Visit(func(fl *flag.Flag) {
+		set[fl.Name] = fl.Value.String()
+	})
+	return set, nil
+}
+
+func (l *loader) applyFlags(flags map[string]string) {
+	for _, f := range l.fields {
+		value, ok := flags[f.path]
+		if !ok {
+			continue
+		}
+		if err := setValue(f.value, value); err != nil {
+			l.fail(f.path, "--"+f.path, err.Error())
+		}
+	}
+}
+
+func (l *loader) fail(path, source, message string) {
+	l.errs = append(l.errs, FieldError{Field: path, Source: source, Message: message})
+}
+
+// readFile decodes a YAML or JSON config file. An empty path means no file.
+func readFile(path string) (map[string]any, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(content, &values)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &values)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// setValue converts raw, either a string from env/flags or a decoded file
+// value, into the type of v.
+func setValue(v reflect.Value, raw any) error {
+	if s, ok := raw.(string); ok && v.Type() != timeType && reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch {
+	case v.Type() == durationType:
+		s, ok := raw.(string)
+		if !ok {
+			return errors.New("expected a duration such as 30s")
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+
+	case v.Type() == timeType:
+		s, ok := raw.(string)
+		if !ok {
+			return errors.New("expected a date such as 2027-05-01 or an RFC 3339 time")
+		}
+		t, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			if t, err = time.Parse(time.RFC3339, s); err != nil {
+				return fmt.Errorf("invalid date %q", s)
+			}
+		}
+		v.Set(reflect.ValueOf(t))
+
+	case v.Kind() == reflect.String:
+		v.SetString(fmt.Sprint(raw))
+
+	case v.Kind() == reflect.Int:
+		switch n := raw.(type) {
+		case int:
+			v.SetInt(int64(n))
+		case float64:
+			if n != float64(int64(n)) {
+				return fmt.Errorf("expected an integer, got %v", n)
+			}
+			v.SetInt(int64(n))
+		default:
+			i, err := strconv.Atoi(fmt.Sprint(raw))
+			if err != nil {
+				return fmt.Errorf("expected an integer, got %q", raw)
+			}
+			v.SetInt(int64(i))
+		}
+
+	case v.Kind() == reflect.Bool:
+		if b, ok := raw.(bool); ok {
+			v.SetBool(b)
+			return nil
+		}
+		b, err := strconv.ParseBool(fmt.Sprint(raw))
+		if err != nil {
+			return fmt.Errorf("expected a boolean, got %q", raw)
+		}
+		v.SetBool(b)
+
+	case v.Kind() == reflect.Slice:
+		var items []any
+		switch r := raw.(type) {
+		case []any:
+			items = r
+		case string:
+			for _, item := range strings.Split(r, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		default:
+			return errors.New("expected a list")
+		}
+
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(slice.Index(i), item); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		v.Set(slice)
+
+	case v.Kind() == reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		setEntry := func(key string, value any) error {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(elem, value); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			m.SetMapIndex(reflect.ValueOf(key), elem)
+			return nil
+		}
+
+		switch r := raw.(type) {
+		case map[string]any:
+			for key, value := range r {
+				if err := setEntry(key, value); err != nil {
+					return err
+				}
+			}
+		case string:
+			for _, item := range strings.Split(r, ",") {
+				key, value, ok := strings.Cut(item, "=")
+				if !ok {
+					return fmt.Errorf("expected key=value pairs, got %q", item)
+				}
+				if err := setEntry(strings.TrimSpace(key), strings.TrimSpace(value)); err != nil {
+					return err
+				}
+			}
+		default:
+			return errors.New("expected a mapping")
+		}
+		v.Set(m)
+
+	case v.Kind() == reflect.Struct:
+		values, ok := raw.(map[string]any)
+		if !ok {
+			return errors.New("expected a mapping")
+		}
+		for key, value := range values {
+			fv, ok := fieldByKey(v, key)
+			if !ok {
+				return fmt.Errorf("unknown setting %q", key)
+			}
+			if err := setValue(fv, value); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+
+	return nil
+}
+
+// isSection reports whether t is a nested group of settings rather than a
+// single value.
+func isSection(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func fieldByKey(v reflect.Value, key string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("config") == key {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError describes a single invalid setting.
+type FieldError struct {
+	Field   string
+	Source  string
+	Message string
+}
+
+// ValidationError lists every invalid setting found while loading.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid configuration:")
+	for _, f := range e.Fields {
+		b.WriteString("\n  - ")
+		b.WriteString(f.Field)
+		if f.Source != "" {
+			fmt.Fprintf(&b, " (%s)", f.Source)
+		}
+		b.WriteString(": ")
+		b.WriteString(f.Message)
+	}
+	return b.String()
+}
+
+// validate checks the validate tag rules of every field, then the rules
+// that span several fields.
+//
+// Supported rules:
+//   - required: the value must not be empty
+//   - port: a TCP port number between 1 and 65535
+//   - oneof=a b c: the value must be one of the listed words
+//   - min=N, max=N: numeric bounds; durations use duration syntax (min=1s)
+func (l *loader) validate() {
+	for _, f := range l.fields {
+		if f.rules == "" {
+			continue
+		}
+		for _, rule := range strings.Split(f.rules, ",") {
+			if msg := checkRule(f.value, rule); msg != "" {
+				l.fail(f.path, f.env, msg)
+			}
+		}
+	}
+
+	cfg := l.root.Addr().Interface().(*Config)
+	if cfg.Database.MaxIdleConns > cfg.Database.MaxOpenConns {
+		l.fail("database.max_idle_conns", "DB_MAX_IDLE_CONNS", "must not exceed database.max_open_conns")
+	}
+	// A budget must expire before the write timeout closes the connection,
+	// or the timeout response can never be written
+	if cfg.Server.WriteTimeout > 0 && cfg.Server.RequestTimeout >= cfg.Server.WriteTimeout {
+		l.fail("server.request_timeout", "SERVER_REQUEST_TIMEOUT", "must be less than server.write_timeout")
+	}
+	for route, budget := range cfg.Server.RouteTimeouts {
+		if cfg.Server.WriteTimeout > 0 && budget >= cfg.Server.WriteTimeout {
+			l.fail("server.route_timeouts", "SERVER_ROUTE_TIMEOUTS", fmt.Sprintf("budget for %q must be less than server.write_timeout", route))
+		}
+	}
+	if !cfg.Server.V1Sunset.IsZero() {
+		if cfg.Server.V1DeprecatedSince.IsZero() {
+			l.fail("server.v1_sunset", "SERVER_V1_SUNSET", "requires server.v1_deprecated_since")
+		} else if !cfg.Server.V1Sunset.After(cfg.Server.V1DeprecatedSince) {
+			l.fail("server.v1_sunset", "SERVER_V1_SUNSET", "must be after server.v1_deprecated_since")
+		}
+	}
+	if cfg.Log.Output == "file" && cfg.Log.File.Path == "" {
+		l.fail("log.file.path", "LOG_FILE_PATH", "is required when log.output is file")
+	}
+	for i, key := range cfg.Auth.APIKeys {
+		if key.Principal == "" || key.Key == "" {
+			l.fail(fmt.Sprintf("auth.api_keys[%d]", i), "AUTH_API_KEYS", "principal and key are required")
+		}
+	}
+}
+
+func checkRule(v reflect.Value, rule string) string {
+	name, arg, _ := strings.Cut(rule, "=")
+
+	switch name {
+	case "required":
+		if v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+			return "is required"
+		}
+
+	case "port":
+		port, err := strconv.Atoi(v.String())
+		if err != nil || port < 1 || port > 65535 {
+			return fmt.Sprintf("must be a port number between 1 and 65535, got %q", v.String())
+		}
+
+	case "oneof":
+		options := strings.Fields(arg)
+		if !slices.Contains(options, v.String()) {
+			return fmt.Sprintf("must be one of %s, got %q", strings.Join(options, ", "), v.String())
+		}
+
+	case "min", "max":
+		var limit int64
+		if v.Type() == durationType {
+			d, err := time.ParseDuration(arg)
+			if err != nil {
+				return fmt.Sprintf("invalid %s rule %q", name, arg)
+			}
+			limit = int64(d)
+		} else {
+			n, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				return fmt.Sprintf("invalid %s rule %q", name, arg)
+			}
+			limit = n
+		}
+
+		if name == "min" && v.Int() < limit {
+			return fmt.Sprintf("must be at least %s", arg)
+		}
+		if name == "max" && v.Int() > limit {
+			return fmt.Sprintf("must be at most %s", arg)
+		}
+
+	default:
+		return fmt.Sprintf("unknown validation rule %q", name)
+	}
+
+	return ""
+}
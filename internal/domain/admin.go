@@ -0,0 +1,9 @@
+package domain
+
+// SetLogLevelRequest changes the root level, or a single component's level
+// when Component is set. TTL is a Go duration after which the change reverts.
+type SetLogLevelRequest struct {
+	Component string `json:"component,omitempty" xml:"component"`
+	Level     string `json:"level" xml:"level"`
+	TTL       string `json:"ttl,omitempty" xml:"ttl"`
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Audited entity types
+const (
+	EntityUser = "user"
+)
+
+// AuditEvent records who changed an entity, when, from where and how.
+// Diff holds the changed fields; on creation From is null and on deletion
+// To is null.
+type AuditEvent struct {
+	ID         int64                  `json:"id"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	Actor      string                 `json:"actor"`
+	APIID      string                 `json:"api_id"`
+	IP         string                 `json:"ip"`
+	Action     string                 `json:"action"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   string                 `json:"entity_id"`
+	Diff       map[string]FieldChange `json:"diff"`
+	PrevHash   string                 `json:"prev_hash,omitempty"`
+	Hash       string                 `json:"hash,omitempty"`
+}
+
+// AuditFilter selects audit events, newest first. Zero fields match
+// everything. Results start after the event with id BeforeID when set.
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   string
+	Since      time.Time
+	Until      time.Time
+	BeforeID   int64
+	Limit      int
+}
+
+// AuditVerification is the result of checking the audit hash chain.
+type AuditVerification struct {
+	Valid          bool  `json:"valid"`
+	Checked        int64 `json:"checked"`
+	FirstInvalidID int64 `json:"first_invalid_id,omitempty"`
+}
+
+// Actor identifies who made a request and from where, for auditing.
+type Actor struct {
+	ID    string
+	APIID string
+	IP    string
+}
+
+// AnonymousActor is recorded for requests without credentials
+const AnonymousActor = "anonymous"
+
+type actorKey struct{}
+
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor of ctx, or an anonymous actor with unknown
+// origin for work not started by a request.
+func ActorFrom(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+	return Actor{ID: AnonymousActor, APIID: "unknown", IP: "unknown"}
+}
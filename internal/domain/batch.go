@@ -0,0 +1,42 @@
+package domain
+
+// Batch modes
+const (
+	// BatchAtomic applies every item or none of them
+	BatchAtomic = "atomic"
+	// BatchBestEffort applies the items that succeed and reports the others
+	BatchBestEffort = "best_effort"
+)
+
+// BatchRequest is the body of the :batchCreate, :batchUpdate and
+// :batchDelete endpoints. Mode defaults to BatchAtomic.
+type BatchRequest[T any] struct {
+	Items []T    `json:"items" xml:"items>item"`
+	Mode  string `json:"mode,omitempty" xml:"mode"`
+}
+
+type BatchUpdateItem struct {
+	ID int64 `json:"id" xml:"id"`
+	UpdateUserRequest
+}
+
+type BatchDeleteItem struct {
+	ID int64 `json:"id" xml:"id"`
+}
+
+// BatchItemResult is the outcome of the item at Index in the request.
+// Errors is empty when the item succeeded.
+type BatchItemResult struct {
+	Index  int           `json:"index"`
+	User   *User         `json:"user,omitempty"`
+	Errors []ErrorDetail `json:"errors"`
+}
+
+// BatchResult lists a result for every item, in request order. Applied is
+// false when an atomic batch was rolled back.
+type BatchResult struct {
+	Applied   bool              `json:"applied"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []BatchItemResult `json:"results"`
+}
@@ -0,0 +1,44 @@
+package domain
+
+// Domain events published by the service layer. Their names match the
+// user event types recorded in the database.
+
+type UserCreated struct {
+	User *User `json:"user"`
+}
+
+func (UserCreated) EventName() string { return UserEventCreated }
+
+// UserUpdated carries the user after the update and the fields that changed.
+type UserUpdated struct {
+	User    *User                  `json:"user"`
+	Changes map[string]FieldChange `json:"changes"`
+}
+
+func (UserUpdated) EventName() string { return UserEventUpdated }
+
+// UserDeleted carries the user as it was before deletion.
+type UserDeleted struct {
+	User *User `json:"user"`
+}
+
+func (UserDeleted) EventName() string { return UserEventDeleted }
+
+// FieldChange is the value of a field before and after an update.
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// DiffUsers returns the user-editable fields that differ between before
+// and after, keyed by their JSON name.
+func DiffUsers(before, after *User) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	if before.Email != after.Email {
+		changes["email"] = FieldChange{From: before.Email, To: after.Email}
+	}
+	if before.Name != after.Name {
+		changes["name"] = FieldChange{From: before.Name, To: after.Name}
+	}
+	return changes
+}
@@ -3,27 +3,46 @@ package domain
 // StandardResponse is the standard API response format
 // All API responses should follow this structure for consistency
 type StandardResponse struct {
-	APIID  string        `json:"api_id"`            // Unique identifier for request tracing
-	Errors []ErrorDetail `json:"errors,omitempty"`  // List of errors (empty on success)
-	Data   interface{}   `json:"data,omitempty"`    // Response data (structured based on endpoint)
+	APIID  string        `json:"api_id"`           // Unique identifier for request tracing
+	Errors []ErrorDetail `json:"errors,omitempty"` // List of errors (empty on success)
+	Data   interface{}   `json:"data,omitempty"`   // Response data (structured based on endpoint)
 }
 
 // ErrorDetail represents a single error in the response
 type ErrorDetail struct {
-	Code    string `json:"code"`              // Error code (e.g., "VALIDATION_ERROR", "NOT_FOUND")
-	Message string `json:"message"`           // Human-readable error message
-	Field   string `json:"field,omitempty"`   // Field name (for validation errors)
-}
+	Code    string `json:"code"`            // Error code (e.g., "VALIDATION_ERROR", "NOT_FOUND")
+	Message string `json:"message"`         // Human-readable error message
+	Field   string `json:"field,omitempty"` // Field name (for validation errors)
 
-// Deprecated: Use StandardResponse instead
-type Response struct {
-	Success bool        `json:"success"`
-	Message string      `json:"message,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
+	// Catalog key and arguments of Message, used to translate it
+	Key  string         `json:"-"`
+	Args map[string]any `json:"-"`
 }
 
 type HealthResponse struct {
 	Status    string `json:"status"`
 	Timestamp string `json:"timestamp"`
 }
+
+// ProblemDetails is an error response in the RFC 9457 format, sent instead
+// of a StandardResponse to clients that ask for application/problem+json.
+// Errors and Data are extension members carrying what the StandardResponse
+// would.
+type ProblemDetails struct {
+	Type     string        `json:"type"`               // URI identifying the error code
+	Title    string        `json:"title"`              // Summary of the status
+	Status   int           `json:"status"`             // HTTP status code
+	Detail   string        `json:"detail,omitempty"`   // Message of the first error
+	Instance string        `json:"instance,omitempty"` // api_id of the request
+	Errors   []ErrorDetail `json:"errors"`             // Every error, with its field
+	Data     interface{}   `json:"data,omitempty"`     // Data sent alongside the errors, if any
+}
+
+// ErrorCode describes an error code in the error catalog
+type ErrorCode struct {
+	Code        string `json:"code"`        // Error code, as in ErrorDetail.Code
+	Status      int    `json:"status"`      // HTTP status of responses with the code
+	Retryable   bool   `json:"retryable"`   // Whether the request may succeed when sent again
+	Description string `json:"description"` // What the code means and how to resolve it
+	DocURL      string `json:"doc_url"`     // Documentation, also the Problem Details type
+}
@@ -0,0 +1,65 @@
+package domain
+
+import "time"
+
+// Transfer kinds
+const (
+	TransferImport = "import"
+	TransferExport = "export"
+)
+
+// Transfer statuses
+const (
+	TransferPending   = "pending"
+	TransferRunning   = "running"
+	TransferCompleted = "completed"
+	TransferFailed    = "failed"
+)
+
+// File formats for imports and exports. JSON is only written, as a single
+// array; imports read NDJSON, one object per line.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatJSON   = "json"
+)
+
+// UserTransfer is a user import or export worked by a background job.
+// Counts are updated as the job progresses.
+type UserTransfer struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	Status      string          `json:"status"`
+	Format      string          `json:"format"`
+	Options     TransferOptions `json:"options"`
+	Counts      TransferCounts  `json:"counts"`
+	Error       string          `json:"error,omitempty"`
+	RequestedBy Actor           `json:"-"`
+	CreatedAt   time.Time       `json:"created_at"`
+	StartedAt   *time.Time      `json:"started_at"`
+	FinishedAt  *time.Time      `json:"finished_at"`
+}
+
+// TransferOptions control how an import reads its file. Mapping maps user
+// fields to the CSV columns or NDJSON keys holding them; fields that are
+// not mapped are read from the column or key of the same name. With DryRun
+// rows are validated and reported but nothing is written.
+type TransferOptions struct {
+	Mapping   map[string]string `json:"mapping,omitempty"`
+	Delimiter string            `json:"delimiter,omitempty"`
+	DryRun    bool              `json:"dry_run,omitempty"`
+}
+
+// TransferCounts tally the rows of a transfer. An export only counts
+// Processed.
+type TransferCounts struct {
+	Processed int `json:"processed"`
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Failed    int `json:"failed"`
+}
+
+type CreateExportRequest struct {
+	Format string `json:"format,omitempty" xml:"format"`
+}
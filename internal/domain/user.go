@@ -10,12 +10,97 @@ type User struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// UserFields are the fields of a User that reads can select
+var UserFields = []string{"id", "email", "name", "created_at", "updated_at"}
+
+// UserExpansions are the resources related to a user that reads can expand
+var UserExpansions = []string{"history"}
+
+// UserQuery selects the fields of users to read, every field when Fields is
+// nil, and the related resources to expand with them
+type UserQuery struct {
+	Fields []string
+	Expand []string
+}
+
+// UserView is a user as read with a UserQuery: only the selected fields are
+// set, and related resources only when expanded.
+type UserView struct {
+	ID        *int64     `json:"id,omitempty"`
+	Email     *string    `json:"email,omitempty"`
+	Name      *string    `json:"name,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+
+	History []*UserVersion `json:"history,omitempty"`
+}
+
+// NewUserView selects fields of user, every field when fields is nil
+func NewUserView(user *User, fields []string) *UserView {
+	if fields == nil {
+		fields = UserFields
+	}
+
+	v := &UserView{}
+	for _, field := range fields {
+		switch field {
+		case "id":
+			v.ID = &user.ID
+		case "email":
+			v.Email = &user.Email
+		case "name":
+			v.Name = &user.Name
+		case "created_at":
+			v.CreatedAt = &user.CreatedAt
+		case "updated_at":
+			v.UpdatedAt = &user.UpdatedAt
+		}
+	}
+	return v
+}
+
 type CreateUserRequest struct {
-	Email string `json:"email"`
-	Name  string `json:"name"`
+	Email string `json:"email" xml:"email"`
+	Name  string `json:"name" xml:"name"`
 }
 
 type UpdateUserRequest struct {
-	Email string `json:"email,omitempty"`
-	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty" xml:"email"`
+	Name  string `json:"name,omitempty" xml:"name"`
+}
+
+// UserVersion is the state of a user between ValidFrom and ValidTo. ValidTo
+// is nil for the current version.
+type UserVersion struct {
+	Version   int        `json:"version"`
+	User      *User      `json:"user"`
+	ValidFrom time.Time  `json:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to"`
+}
+
+type RevertUserRequest struct {
+	Version int `json:"version" xml:"version"`
+}
+
+// UserSearch selects users matching Query, best match first. With After,
+// results start after that result.
+type UserSearch struct {
+	Query string
+	After *UserSearchCursor
+	Limit int
+}
+
+// UserSearchCursor is the position of a search result
+type UserSearchCursor struct {
+	Score float32
+	ID    int64
+}
+
+// UserSearchResult is a matching user with its relevance score. Highlights
+// holds, per matching field, its HTML-escaped value with the matching parts
+// wrapped in <mark> tags.
+type UserSearchResult struct {
+	User
+	Score      float32           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
 }
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// User event types, as recorded by the users table trigger
+const (
+	UserEventCreated = "user.created"
+	UserEventUpdated = "user.updated"
+	UserEventDeleted = "user.deleted"
+)
+
+// UserEvent is a change to a user. User holds the row after the change, or
+// before it for deletions.
+type UserEvent struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	UserID    int64     `json:"user_id"`
+	User      *User     `json:"user,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
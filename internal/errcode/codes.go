@@ -0,0 +1,82 @@
+package errcode
+
+import "net/http"
+
+// Authentication and availability, sent by the middleware of every route
+var (
+	Unauthorized       = register("UNAUTHORIZED", http.StatusUnauthorized, false, "The request has no API key, an invalid one, or an Authorization header that does not use the Bearer scheme.")
+	Forbidden          = register("FORBIDDEN", http.StatusForbidden, false, "The API key lacks a scope the route requires, such as admin.")
+	ServiceUnavailable = register("SERVICE_UNAVAILABLE", http.StatusServiceUnavailable, true, "The database is unreachable. Retry after the Retry-After delay.")
+	Timeout            = register("TIMEOUT", http.StatusGatewayTimeout, true, "The request ran out of its time budget. It may have been applied; only retry idempotent requests blindly.")
+	Cancelled          = register("CANCELLED", http.StatusServiceUnavailable, true, "The request was cancelled before it completed, because the client went away or the server is shutting down.")
+)
+
+// Invalid requests
+var (
+	InvalidRequest     = register("INVALID_REQUEST", http.StatusBadRequest, false, "The request body or a parameter is malformed, such as a body that is not valid JSON.")
+	InvalidID          = register("INVALID_ID", http.StatusBadRequest, false, "A user ID is not a positive integer.")
+	InvalidQuery       = register("INVALID_QUERY", http.StatusBadRequest, false, "The search query q is missing or longer than 255 characters.")
+	InvalidLimit       = register("INVALID_LIMIT", http.StatusBadRequest, false, "The page size limit is out of the route's range.")
+	InvalidCursor      = register("INVALID_CURSOR", http.StatusBadRequest, false, "The pagination cursor was not returned by this route, or has been tampered with.")
+	InvalidField       = register("INVALID_FIELD", http.StatusBadRequest, false, "A name in fields is not a field of the resource, or a name in expand is not a resource related to it.")
+	InvalidTime        = register("INVALID_TIME", http.StatusBadRequest, false, "A time parameter is not in RFC 3339 format.")
+	InvalidFilter      = register("INVALID_FILTER", http.StatusBadRequest, false, "An event filter parameter is not valid.")
+	InvalidLastEventID = register("INVALID_LAST_EVENT_ID", http.StatusBadRequest, false, "The Last-Event-ID header is not a positive integer.")
+	InvalidComponent   = register("INVALID_COMPONENT", http.StatusBadRequest, false, "The log level component is not one the server logs with.")
+	InvalidLevel       = register("INVALID_LEVEL", http.StatusBadRequest, false, "The log level is not one of debug, info, warn or error.")
+	InvalidTTL         = register("INVALID_TTL", http.StatusBadRequest, false, "The log level override TTL is not a positive duration.")
+	InvalidVersion     = register("INVALID_VERSION", http.StatusBadRequest, false, "The user version to revert to is not a positive integer.")
+	InvalidMode        = register("INVALID_MODE", http.StatusBadRequest, false, "The batch mode is not atomic or best_effort.")
+	EmptyBatch         = register("EMPTY_BATCH", http.StatusBadRequest, false, "The batch has no items.")
+	TooManyItems       = register("TOO_MANY_ITEMS", http.StatusBadRequest, false, "The batch has more items than the server accepts.")
+	InvalidFormat      = register("INVALID_FORMAT", http.StatusBadRequest, false, "The file format is not supported for the transfer.")
+	InvalidMapping     = register("INVALID_MAPPING", http.StatusBadRequest, false, "The import mapping is not a JSON object mapping email or name to a column.")
+	InvalidDelimiter   = register("INVALID_DELIMITER", http.StatusBadRequest, false, "The CSV delimiter is not a single character, or was set for a file that is not CSV.")
+	InvalidFile        = register("INVALID_FILE", http.StatusBadRequest, false, "The uploaded file is empty or its header cannot be read.")
+	MissingColumn      = register("MISSING_COLUMN", http.StatusBadRequest, false, "The CSV header lacks a column the import needs.")
+	MissingFile        = register("MISSING_FILE", http.StatusBadRequest, false, "The multipart upload has no file field.")
+	FileTooLarge       = register("FILE_TOO_LARGE", http.StatusRequestEntityTooLarge, false, "The upload is larger than the server accepts.")
+	BodyTooLarge       = register("BODY_TOO_LARGE", http.StatusRequestEntityTooLarge, false, "The request body is larger than the route accepts, such as a batch of more items than the server accepts.")
+	UnsupportedVersion = register("UNSUPPORTED_VERSION", http.StatusNotAcceptable, false, "The API version asked for with Accept: application/vnd.api+json;version=N does not exist.")
+)
+
+// Media types of request and response bodies
+var (
+	NotAcceptable        = register("NOT_ACCEPTABLE", http.StatusNotAcceptable, false, "Accept allows none of the media types the route responds with, such as application/x-ndjson for a route that does not respond with a list.")
+	UnsupportedMediaType = register("UNSUPPORTED_MEDIA_TYPE", http.StatusUnsupportedMediaType, false, "The Content-Type of the request body is not one the server reads: JSON, MessagePack, CBOR or XML.")
+)
+
+// Resource state
+var (
+	NotFound    = register("NOT_FOUND", http.StatusNotFound, false, "The resource does not exist, or did not at the requested time.")
+	NotReady    = register("NOT_READY", http.StatusConflict, true, "The transfer's file is not available until the transfer has completed.")
+	EmailTaken  = register("EMAIL_TAKEN", http.StatusConflict, false, "Another user already has the email. Batches report it per item.")
+	BatchFailed = register("BATCH_FAILED", http.StatusUnprocessableEntity, false, "No item of the batch was applied; the per-item results say why.")
+	Sunset      = register("SUNSET", http.StatusGone, false, "The route or API version is past its Sunset date and no longer served. Its successor-version link says what replaces it.")
+)
+
+// Errors of single items of a batch, or rows of an import, reported in
+// their results rather than as the status of a response
+var (
+	ValidationError = register("VALIDATION_ERROR", http.StatusUnprocessableEntity, false, "A field is missing or too long.")
+	DuplicateID     = register("DUPLICATE_ID", http.StatusUnprocessableEntity, false, "The user ID appears more than once in the batch.")
+	NotApplied      = register("NOT_APPLIED", http.StatusUnprocessableEntity, false, "The item was valid but not applied, because other items of its atomic batch failed.")
+	InvalidRow      = register("INVALID_ROW", http.StatusUnprocessableEntity, false, "The import row cannot be parsed.")
+	InvalidValue    = register("INVALID_VALUE", http.StatusUnprocessableEntity, false, "A value of the import row has the wrong type.")
+)
+
+// Server failures. Reads may succeed when retried.
+var (
+	FetchFailed     = register("FETCH_FAILED", http.StatusInternalServerError, true, "Reading the resource failed.")
+	SearchFailed    = register("SEARCH_FAILED", http.StatusInternalServerError, true, "The search failed.")
+	SubscribeFailed = register("SUBSCRIBE_FAILED", http.StatusInternalServerError, true, "Subscribing to user events failed.")
+	DownloadFailed  = register("DOWNLOAD_FAILED", http.StatusInternalServerError, true, "Reading the transfer's file failed.")
+	VerifyFailed    = register("VERIFY_FAILED", http.StatusInternalServerError, true, "Verifying the audit hash chain failed.")
+	SpecFailed      = register("SPEC_FAILED", http.StatusInternalServerError, false, "The OpenAPI document could not be built.")
+	CreateFailed    = register("CREATE_FAILED", http.StatusInternalServerError, false, "Creating the user failed.")
+	UpdateFailed    = register("UPDATE_FAILED", http.StatusInternalServerError, false, "Updating the user failed.")
+	DeleteFailed    = register("DELETE_FAILED", http.StatusInternalServerError, false, "Deleting the user failed.")
+	RevertFailed    = register("REVERT_FAILED", http.StatusInternalServerError, false, "Reverting the user failed.")
+	ImportFailed    = register("IMPORT_FAILED", http.StatusInternalServerError, false, "Storing the upload or queueing the import failed.")
+	ExportFailed    = register("EXPORT_FAILED", http.StatusInternalServerError, false, "Queueing the export failed.")
+)
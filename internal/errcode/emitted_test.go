@@ -0,0 +1,120 @@
+package errcode
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// emitters are the packages that build error responses and per-item
+// errors, relative to this one
+var emitters = []string{"../handler", "../middleware", "../service"}
+
+// TestEmittedCodesAreRegistered fails when an emitter builds an error
+// detail from anything but a registered Code: a domain.ErrorDetail literal
+// setting Code, a Code field assigned a string, or Lookup of a name that
+// is not registered.
+func TestEmittedCodesAreRegistered(t *testing.T) {
+	fset := token.NewFileSet()
+	for _, dir := range emitters {
+		files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) == 0 {
+			t.Fatalf("no Go files in %s", dir)
+		}
+
+		for _, path := range files {
+			if strings.HasSuffix(path, "_test.go") {
+				continue
+			}
+			file, err := parser.ParseFile(fset, path, nil, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ast.Inspect(file, func(n ast.Node) bool {
+				checkEmission(t, fset, n)
+				return true
+			})
+		}
+	}
+}
+
+func checkEmission(t *testing.T, fset *token.FileSet, n ast.Node) {
+	t.Helper()
+
+	switch n := n.(type) {
+	case *ast.CompositeLit:
+		for _, lit := range errorDetailLits(n) {
+			for _, elt := range lit.Elts {
+				if kv, ok := elt.(*ast.KeyValueExpr); ok && isIdent(kv.Key, "Code") {
+					t.Errorf("%s: error detail with a Code of its own; use Code.Detail or Code.Message", fset.Position(kv.Pos()))
+				}
+			}
+		}
+
+	case *ast.AssignStmt:
+		for i, lhs := range n.Lhs {
+			if sel, ok := lhs.(*ast.SelectorExpr); ok && sel.Sel.Name == "Code" && i < len(n.Rhs) {
+				if name, ok := stringLit(n.Rhs[i]); ok {
+					checkRegistered(t, fset, n.Rhs[i], name)
+				}
+			}
+		}
+
+	case *ast.CallExpr:
+		if sel, ok := n.Fun.(*ast.SelectorExpr); ok && isIdent(sel.X, "errcode") && sel.Sel.Name == "Lookup" && len(n.Args) == 1 {
+			if name, ok := stringLit(n.Args[0]); ok {
+				checkRegistered(t, fset, n.Args[0], name)
+			}
+		}
+	}
+}
+
+func checkRegistered(t *testing.T, fset *token.FileSet, at ast.Node, name string) {
+	t.Helper()
+	if _, ok := Lookup(name); !ok {
+		t.Errorf("%s: %s is not a registered code", fset.Position(at.Pos()), name)
+	}
+}
+
+// errorDetailLits returns lit when it is a domain.ErrorDetail, or else its
+// elements when it is a slice of them, whose elements may omit the type
+func errorDetailLits(lit *ast.CompositeLit) []*ast.CompositeLit {
+	switch typ := lit.Type.(type) {
+	case *ast.SelectorExpr:
+		if typ.Sel.Name == "ErrorDetail" {
+			return []*ast.CompositeLit{lit}
+		}
+	case *ast.ArrayType:
+		if sel, ok := typ.Elt.(*ast.SelectorExpr); ok && sel.Sel.Name == "ErrorDetail" {
+			var lits []*ast.CompositeLit
+			for _, elt := range lit.Elts {
+				if elt, ok := elt.(*ast.CompositeLit); ok && elt.Type == nil {
+					lits = append(lits, elt)
+				}
+			}
+			return lits
+		}
+	}
+	return nil
+}
+
+func isIdent(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
@@ -0,0 +1,157 @@
+// Package errcode is the registry of the error codes the API responds with.
+// Every code declares its HTTP status, whether a request that failed with
+// it may succeed when retried, and a description for the error catalog.
+//
+// A Code can only be obtained from the registry: its fields are unexported
+// and the zero Code is rejected when used. Functions that send errors take
+// a Code rather than a string, so only registered codes reach clients.
+package errcode
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/i18n"
+)
+
+// Code is a registered error code
+type Code struct {
+	def *definition
+}
+
+type definition struct {
+	name        string
+	status      int
+	retryable   bool
+	description string
+}
+
+var (
+	registry  = make(map[string]Code)
+	validName = regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)*$`)
+)
+
+// register adds a code to the registry. It panics on a duplicate or
+// malformed name, a status that is not an error, or a missing description,
+// so mistakes fail at startup.
+func register(name string, status int, retryable bool, description string) Code {
+	switch {
+	case !validName.MatchString(name):
+		panic(fmt.Sprintf("errcode: invalid code name %q", name))
+	case status < http.StatusBadRequest || status > 599:
+		panic(fmt.Sprintf("errcode: %s has status %d, not an error status", name, status))
+	case description == "":
+		panic(fmt.Sprintf("errcode: %s has no description", name))
+	}
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("errcode: %s is registered twice", name))
+	}
+
+	code := Code{def: &definition{name: name, status: status, retryable: retryable, description: description}}
+	registry[name] = code
+	return code
+}
+
+// definition returns the code's definition, and panics for the zero Code
+func (c Code) definition() *definition {
+	if c.def == nil {
+		panic("errcode: use of an unregistered code")
+	}
+	return c.def
+}
+
+// Name is the code as sent to clients, such as NOT_FOUND
+func (c Code) Name() string {
+	return c.definition().name
+}
+
+// Status is the HTTP status of responses with the code
+func (c Code) Status() int {
+	return c.definition().status
+}
+
+// Retryable reports whether the same request may succeed when sent again
+func (c Code) Retryable() bool {
+	return c.definition().retryable
+}
+
+func (c Code) Description() string {
+	return c.definition().description
+}
+
+// DocURL is the documentation URL of the code under base, which is also
+// its Problem Details type
+func (c Code) DocURL(base string) string {
+	return base + c.Name()
+}
+
+func (c Code) String() string {
+	return c.Name()
+}
+
+// Detail returns an error detail with the code and an English message,
+// translated by the code's message in the i18n bundles
+func (c Code) Detail(message, field string) domain.ErrorDetail {
+	return domain.ErrorDetail{Code: c.Name(), Message: message, Field: field, Key: c.Name()}
+}
+
+// Message returns an error detail with the code's message for rule, such as
+// max for VALIDATION_ERROR.max, rendered in English with args. An empty rule
+// uses the code's own message. The key is the message when English has none.
+func (c Code) Message(rule, field string, args i18n.Args) domain.ErrorDetail {
+	key := c.Name()
+	if rule != "" {
+		key += "." + rule
+	}
+	message, ok := i18n.Default().Message(i18n.Fallback, key, args)
+	if !ok {
+		message = key
+	}
+	return domain.ErrorDetail{Code: c.Name(), Message: message, Field: field, Key: key, Args: args}
+}
+
+// Describe returns the catalog entry of the code, documented under base
+func (c Code) Describe(base string) domain.ErrorCode {
+	def := c.definition()
+	return domain.ErrorCode{
+		Code:        def.name,
+		Status:      def.status,
+		Retryable:   def.retryable,
+		Description: def.description,
+		DocURL:      c.DocURL(base),
+	}
+}
+
+// init checks that every message of the i18n bundles belongs to a
+// registered code, so a renamed code cannot leave translations behind
+func init() {
+	for _, key := range i18n.Default().Keys() {
+		name, _, _ := strings.Cut(key, ".")
+		if _, ok := registry[name]; !ok {
+			panic(fmt.Sprintf("errcode: i18n message %s is not of a registered code", key))
+		}
+	}
+}
+
+// Lookup returns the code registered under name
+func Lookup(name string) (Code, bool) {
+	code, ok := registry[name]
+	return code, ok
+}
+
+// All returns every registered code, by name
+func All() []Code {
+	codes := make([]Code, 0, len(registry))
+	for _, code := range registry {
+		codes = append(codes, code)
+	}
+	slices.SortFunc(codes, func(a, b Code) int {
+		return cmp.Compare(a.Name(), b.Name())
+	})
+	return codes
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/i18n"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/service"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+type AuditHandler struct {
+	service service.AuditService
+	logger  *slog.Logger
+}
+
+func NewAuditHandler(service service.AuditService, logger *slog.Logger) *AuditHandler {
+	return &AuditHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListEvents returns audit events newest first. Filters: actor, action,
+// entity_type, entity_id, since and until (RFC 3339). Pages are limit
+// events long; pass next_cursor as cursor to get the following page.
+func (h *AuditHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := domain.AuditFilter{
+		Actor:      query.Get("actor"),
+		Action:     query.Get("action"),
+		EntityType: query.Get("entity_type"),
+		EntityID:   query.Get("entity_id"),
+		Limit:      defaultAuditPageSize,
+	}
+
+	for param, dst := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := query.Get(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				respondWithStandardError(r.Context(), w, errcode.InvalidTime, "Time must be in RFC 3339 format", param)
+				return
+			}
+			*dst = t
+		}
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAuditPageSize {
+			respondWithErrorDetail(r.Context(), w, errcode.InvalidLimit, errcode.InvalidLimit.Message("range", "limit", i18n.Args{"Min": 1, "Max": 200}))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		id, err := decodeCursor(value)
+		if err != nil {
+			respondWithStandardError(r.Context(), w, errcode.InvalidCursor, "Invalid cursor", "cursor")
+			return
+		}
+		filter.BeforeID = id
+	}
+
+	// Fetch one extra event to know whether there is another page
+	pageSize := filter.Limit
+	filter.Limit++
+
+	events, err := h.service.ListEvents(r.Context(), filter)
+	if err != nil {
+		respondWithStandardError(r.Context(), w, errcode.FetchFailed, err.Error(), "")
+		return
+	}
+
+	data := map[string]interface{}{}
+	if len(events) > pageSize {
+		events = events[:pageSize]
+		data["next_cursor"] = encodeCursor(events[pageSize-1].ID)
+	}
+	data["audit_events"] = events
+
+	respondWithList(w, r, "audit_events", data)
+}
+
+// VerifyChain checks the audit hash chain for tampering
+func (h *AuditHandler) VerifyChain(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.VerifyChain(r.Context())
+	if err != nil {
+		respondWithStandardError(r.Context(), w, errcode.VerifyFailed, err.Error(), "")
+		return
+	}
+
+	respondWithStandardJSON(r.Context(), w, http.StatusOK, map[string]interface{}{
+		"verification": result,
+	})
+}
+
+// Cursors are opaque to clients so the pagination key can change
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(raw), 10, 64)
+}
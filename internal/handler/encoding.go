@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/i18n"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/middleware"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/codec"
+)
+
+// codecs encode responses and decode requests, JSON first so that it is
+// the default
+var codecs = func() *codec.Registry {
+	codecs := codec.NewRegistry()
+	codecs.Register(codec.JSON{})
+	codecs.Register(codec.MessagePack{})
+	codecs.Register(codec.CBOR{})
+	codecs.Register(codec.XML{})
+	codecs.Register(codec.NDJSON{})
+	return codecs
+}()
+
+// listRoutes respond with a list, with respondWithList, so they may be sent
+// as NDJSON
+var listRoutes = everyVersion(
+	"GET /errors",
+	"GET /users",
+	"GET /users/search",
+	"GET /users/{id}/history",
+	"GET /audit-events",
+)
+
+// rawRoutes respond in media types of their own rather than with a
+// StandardResponse, so their Accept header is not negotiated
+var rawRoutes = append(everyVersion(
+	"GET /users/events",
+	"GET /users/imports/{id}/errors",
+	"GET /users/exports/{id}/download",
+), "GET /openapi.json", "GET /admin/debug/vars")
+
+// encodingOptions configures the encoding middleware for codecs
+func encodingOptions() middleware.EncodingOptions {
+	return middleware.EncodingOptions{
+		Codecs:     codecs,
+		ListRoutes: listRoutes,
+		RawRoutes:  rawRoutes,
+	}
+}
+
+// respondWithList sends data, which holds a list under key, like
+// respondWithStandardJSON. With a list encoder, such as NDJSON, it sends
+// the elements of the list alone, and data's next_cursor as a Link to the
+// next page.
+func respondWithList(w http.ResponseWriter, r *http.Request, key string, data map[string]interface{}) {
+	encoder, ok := middleware.ResponseEncoder(r.Context()).(codec.ListEncoder)
+	if !ok {
+		respondWithStandardJSON(r.Context(), w, http.StatusOK, data)
+		return
+	}
+
+	if cursor, ok := data["next_cursor"].(string); ok {
+		next := *r.URL
+		query := next.Query()
+		query.Set("cursor", cursor)
+		next.RawQuery = query.Encode()
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	}
+
+	w.Header().Set("Content-Type", encoder.ContentType())
+	w.WriteHeader(http.StatusOK)
+	encoder.Encode(w, data[key])
+}
+
+// respondWithDecodeError reports a request body that DecodeBody could not
+// read: UNSUPPORTED_MEDIA_TYPE for its Content-Type, BODY_TOO_LARGE past
+// the limit of an http.MaxBytesReader, or else INVALID_REQUEST
+func respondWithDecodeError(ctx context.Context, w http.ResponseWriter, err error) {
+	var unsupported *middleware.UnsupportedMediaTypeError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &unsupported):
+		args := i18n.Args{"Type": unsupported.ContentType, "Types": strings.Join(unsupported.Supported, ", ")}
+		respondWithErrorDetail(ctx, w, errcode.UnsupportedMediaType, errcode.UnsupportedMediaType.Message("types", "", args))
+		return
+	case errors.As(err, &tooLarge):
+		respondWithStandardError(ctx, w, errcode.BodyTooLarge, "The request body is larger than "+strconv.FormatInt(tooLarge.Limit, 10)+" bytes", "")
+		return
+	}
+	respondWithStandardError(ctx, w, errcode.InvalidRequest, "Invalid request payload", "")
+}
+
+// streamFlushEvery is how many elements respondWithStream writes between
+// flushes
+const streamFlushEvery = 100
+
+// streamWriteTimeout is how long a streamed list has to write the elements
+// between flushes. Each flush extends the connection's write deadline by
+// it, so lists outlive the server's write timeout.
+const streamWriteTimeout = 30 * time.Second
+
+// streamTrailerTime is how long before the deadline of a request with a
+// budget a streamed list stops reading, leaving time for its trailing
+// error before the budget runs out
+const streamTrailerTime = time.Second
+
+// respondWithStream sends the elements of the sequence list returns under
+// key like respondWithList, writing each as it is read rather than
+// collecting them, in JSON or a list encoder such as NDJSON. Other encoders
+// get the collected list. An error before the first element is sent as an
+// error response with code; after it, the response has begun, so the error
+// ends the list as a trailing record instead. Running out of time is a
+// TIMEOUT error. Streamed responses are not transformed for older versions.
+func respondWithStream[T any](w http.ResponseWriter, r *http.Request, key string, code errcode.Code, list func(ctx context.Context) iter.Seq2[T, error]) {
+	ctx := r.Context()
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-streamTrailerTime))
+		defer cancel()
+	}
+	seq := list(ctx)
+	failure := func(err error) (errcode.Code, string) {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errcode.Timeout, "Request exceeded its time budget"
+		}
+		return code, err.Error()
+	}
+
+	encoder := middleware.ResponseEncoder(ctx)
+
+	var stream listStream
+	switch e := encoder.(type) {
+	case codec.JSON:
+		stream = &jsonStream{apiID: middleware.GetAPIID(ctx), key: key}
+	case codec.ListEncoder:
+		stream = &listEncoderStream{encoder: e, apiID: middleware.GetAPIID(ctx)}
+	default:
+		items := []T{}
+		for item, err := range seq {
+			if err != nil {
+				code, message := failure(err)
+				respondWithStandardError(ctx, w, code, message, "")
+				return
+			}
+			items = append(items, item)
+		}
+		respondWithList(w, r, key, map[string]interface{}{key: items})
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	begin := func() error {
+		rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		w.Header().Set("Content-Type", encoder.ContentType())
+		w.WriteHeader(http.StatusOK)
+		return stream.begin(w)
+	}
+
+	n := 0
+	for item, err := range seq {
+		if err != nil {
+			code, message := failure(err)
+			if n == 0 {
+				respondWithStandardError(ctx, w, code, message, "")
+				return
+			}
+			details := []domain.ErrorDetail{code.Detail(message, "")}
+			middleware.LocalizeDetails(ctx, details)
+			stream.end(w, details)
+			return
+		}
+
+		if n == 0 {
+			if err := begin(); err != nil {
+				return
+			}
+		}
+		// A failed write means the client has gone
+		if err := stream.element(w, item); err != nil {
+			return
+		}
+		if n++; n%streamFlushEvery == 0 {
+			rc.Flush()
+			rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		}
+	}
+
+	if n == 0 {
+		if err := begin(); err != nil {
+			return
+		}
+	}
+	stream.end(w, nil)
+}
+
+// listStream writes a list one element at a time
+type listStream interface {
+	begin(w io.Writer) error
+	element(w io.Writer, v any) error
+	// end finishes the list, with a trailing record of details if any
+	end(w io.Writer, details []domain.ErrorDetail) error
+}
+
+// jsonStream writes a StandardResponse whose data holds the list under key,
+// with the errors after the data
+type jsonStream struct {
+	apiID string
+	key   string
+	n     int
+}
+
+func (s *jsonStream) begin(w io.Writer) error {
+	apiID, _ := json.Marshal(s.apiID)
+	key, _ := json.Marshal(s.key)
+	_, err := fmt.Fprintf(w, `{"api_id":%s,"data":{%s:[`, apiID, key)
+	return err
+}
+
+func (s *jsonStream) element(w io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if s.n++; s.n > 1 {
+		b = append([]byte{','}, b...)
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (s *jsonStream) end(w io.Writer, details []domain.ErrorDetail) error {
+	if len(details) == 0 {
+		_, err := io.WriteString(w, "]}}\n")
+		return err
+	}
+	b, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, `]},"errors":%s}`+"\n", b)
+	return err
+}
+
+// listEncoderStream writes the elements of a list alone, with errors as a
+// last element that is a StandardResponse without data
+type listEncoderStream struct {
+	encoder codec.ListEncoder
+	apiID   string
+}
+
+func (s *listEncoderStream) begin(io.Writer) error {
+	return nil
+}
+
+func (s *listEncoderStream) element(w io.Writer, v any) error {
+	return s.encoder.EncodeElement(w, v)
+}
+
+func (s *listEncoderStream) end(w io.Writer, details []domain.ErrorDetail) error {
+	if len(details) == 0 {
+		return nil
+	}
+	return s.encoder.EncodeElement(w, domain.StandardResponse{APIID: s.apiID, Errors: details})
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+)
+
+// catalogRoutes serve the error catalog, which is built into the binary, so
+// they are routed outside the API versions' database middleware
+var catalogRoutes = everyVersion(
+	"GET /errors",
+	"GET /errors/{code}",
+)
+
+// serveErrorCatalog lists every registered error code, with documentation
+// URLs under docBaseURL
+func serveErrorCatalog(docBaseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		codes := errcode.All()
+		catalog := make([]domain.ErrorCode, len(codes))
+		for i, code := range codes {
+			catalog[i] = code.Describe(docBaseURL)
+		}
+
+		respondWithList(w, r, "error_codes", map[string]interface{}{
+			"error_codes": catalog,
+		})
+	}
+}
+
+// serveErrorCode describes one error code. Its URL is the code's default
+// documentation URL, so Problem Details types can be dereferenced.
+func serveErrorCode(docBaseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code, ok := errcode.Lookup(chi.URLParam(r, "code"))
+		if !ok {
+			respondWithStandardError(r.Context(), w, errcode.NotFound, "Unknown error code", "code")
+			return
+		}
+
+		respondWithStandardJSON(r.Context(), w, http.StatusOK, map[string]interface{}{
+			"error_code": code.Describe(docBaseURL),
+		})
+	}
+}
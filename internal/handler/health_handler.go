@@ -4,15 +4,19 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
 	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/middleware"
 )
 
-type HealthHandler struct{}
+type HealthHandler struct {
+	db middleware.HealthChecker
+}
 
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{}
+func NewHealthHandler(db middleware.HealthChecker) *HealthHandler {
+	return &HealthHandler{db: db}
 }
 
+// Health reports that the process is alive, regardless of its dependencies
 func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	respondWithStandardJSON(r.Context(), w, http.StatusOK, map[string]interface{}{
 		"status":    "healthy",
@@ -20,3 +24,20 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 		"api_id":    middleware.GetAPIID(r.Context()),
 	})
 }
+
+// Ready reports whether the service can handle traffic, i.e. whether the
+// database is reachable
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	if !h.db.Healthy() {
+		respondWithStandardError(r.Context(), w, errcode.ServiceUnavailable, "Database is unavailable", "")
+		return
+	}
+
+	respondWithStandardJSON(r.Context(), w, http.StatusOK, map[string]interface{}{
+		"status":    "ready",
+		"timestamp": time.Now().Format(time.RFC3339),
+		"checks": map[string]string{
+			"database": "up",
+		},
+	})
+}
@@ -0,0 +1,351 @@
+package handler
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/middleware"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/codec"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/openapi"
+)
+
+// bearerAuth names the API key security scheme in the OpenAPI document
+const bearerAuth = "bearerAuth"
+
+// operation documents a route, see operations
+type operation struct {
+	id          string
+	summary     string
+	description string
+	tag         string
+	// Scopes the route requires; without any, credentials are optional
+	scopes []string
+	params []param
+	// JSON request body, and other media types the route accepts. Bodies
+	// are given as a value of their type, or as an *openapi.Schema.
+	body         any
+	optionalBody bool
+	bodyContent  map[string]any
+	// Success status, 200 when unset, with the response data, or media
+	// types for responses that are not a StandardResponse
+	status  int
+	data    any
+	content map[string]any
+	headers map[string]string
+	// Key of the data holding the list of routes in listRoutes, which is
+	// sent alone as NDJSON
+	list string
+	// Error codes the route responds with. Errors from the middleware are
+	// added when the document is built.
+	errors []errcode.Code
+	// Set when the document is built for deprecated routes
+	deprecation *middleware.Deprecation
+}
+
+type param struct {
+	in          string
+	name        string
+	description string
+	required    bool
+	// A value of the parameter's type
+	value any
+}
+
+func pathParam(name string, value any, description string) param {
+	return param{in: openapi.InPath, name: name, description: description, required: true, value: value}
+}
+
+func queryParam(name string, value any, description string) param {
+	return param{in: openapi.InQuery, name: name, description: description, value: value}
+}
+
+func headerParam(name string, value any, description string) param {
+	return param{in: openapi.InHeader, name: name, description: description, value: value}
+}
+
+// OpenAPI documents every route of router from its entry in operations,
+// marking the API versions of deprecations deprecated as the router was
+// told to. A route without an entry, or an entry without a route, is an
+// error, so the document cannot drift from the router.
+func OpenAPI(router chi.Routes, deprecations map[string]middleware.Deprecation) (*openapi.Document, error) {
+	spec := openapi.New(openapi.Info{
+		Title:       "Go API Service",
+		Version:     "1.0.0",
+		Description: "Every JSON response is a StandardResponse: data on success, errors otherwise. Error codes are listed by GET /api/v1/errors. Error messages are translated to the Accept-Language of the request; codes are not. The API is versioned by path, /api/v1 and /api/v2; paths under /api without a version are served by the version asked for with Accept: application/vnd.api+json;version=N, else the latest. Deprecated versions and routes send Deprecation and Sunset headers. Responses, errors included, are JSON unless Accept asks for MessagePack, CBOR or XML, and lists may be sent as NDJSON; request bodies are read in the same media types, by Content-Type.",
+	})
+	spec.AddSecurityScheme(bearerAuth, &openapi.SecurityScheme{
+		Type:        "http",
+		Scheme:      "bearer",
+		Description: "An API key, sent as Authorization: Bearer <key>",
+	})
+
+	versions := versionOptions(deprecations, nil)
+	documented := make(map[string]bool, len(operations))
+	err := chi.Walk(router, func(method, pattern string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route := method + " " + openapi.Path(pattern)
+		op, ok := operations[route]
+		if !ok {
+			return fmt.Errorf("route %s is not documented", route)
+		}
+		documented[route] = true
+		if slices.Contains(listRoutes, route) != (op.list != "") {
+			return fmt.Errorf("route %s must document its list if and only if it is in listRoutes", route)
+		}
+
+		if deprecation, ok := versions.Deprecation(route); ok {
+			op = op.deprecated(deprecation)
+		}
+		spec.Add(method, pattern, op.describe(spec, route))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, route := range slices.Sorted(maps.Keys(operations)) {
+		if !documented[route] {
+			return nil, fmt.Errorf("documented route %s does not exist", route)
+		}
+	}
+
+	return spec.Document()
+}
+
+// withVersions adds the operations of api, documented within a version,
+// under every API version, then the operations of versions that differ.
+// Operation IDs of versions after v1 end with the version, e.g. getUserV2.
+func withVersions(ops, api, versions map[string]operation) map[string]operation {
+	for _, version := range apiVersions {
+		for route, op := range api {
+			method, path, _ := strings.Cut(route, " ")
+			if version.Name != "v1" {
+				op.id += strings.ToUpper(version.Name[:1]) + version.Name[1:]
+			}
+			ops[method+" /api/"+version.Name+path] = op
+		}
+	}
+	for route, op := range versions {
+		ops[route] = op
+	}
+	return ops
+}
+
+// deprecated marks op as deprecated, with the headers the middleware sends
+// and the error once its sunset has passed
+func (op operation) deprecated(d middleware.Deprecation) operation {
+	op.deprecation = &d
+	op.headers = maps.Clone(op.headers)
+	if op.headers == nil {
+		op.headers = make(map[string]string, 3)
+	}
+	op.headers["Deprecation"] = "When the route was deprecated, as @ followed by a Unix time"
+	op.headers["Link"] = "The route replacing this one, with rel=\"successor-version\""
+	if !d.Sunset.IsZero() {
+		op.headers["Sunset"] = "When the route stops being served"
+	}
+	return op
+}
+
+func (op operation) describe(spec *openapi.Spec, route string) *openapi.Operation {
+	doc := &openapi.Operation{
+		OperationID: op.id,
+		Summary:     op.summary,
+		Description: op.description,
+		Responses:   make(map[string]*openapi.Response),
+		Deprecated:  op.deprecation != nil,
+	}
+	if op.tag != "" {
+		doc.Tags = []string{op.tag}
+	}
+
+	for _, p := range op.params {
+		doc.Parameters = append(doc.Parameters, &openapi.Parameter{
+			Name:        p.name,
+			In:          p.in,
+			Description: p.description,
+			Required:    p.required,
+			Schema:      spec.Schema(p.value),
+		})
+	}
+
+	if op.body != nil || op.bodyContent != nil {
+		doc.RequestBody = &openapi.RequestBody{
+			Required: !op.optionalBody,
+			Content:  mediaTypes(spec, op.bodyContent),
+		}
+		if op.body != nil {
+			for _, contentType := range codecs.DecoderTypes() {
+				doc.RequestBody.Content[contentType] = &openapi.MediaType{Schema: spec.Schema(op.body)}
+			}
+		}
+	}
+
+	status := op.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &openapi.Response{Description: http.StatusText(status)}
+	if op.content != nil {
+		success.Content = mediaTypes(spec, op.content)
+	} else {
+		success.Content = make(map[string]*openapi.MediaType)
+		for _, contentType := range codecs.ContentTypes(false) {
+			success.Content[contentType] = &openapi.MediaType{Schema: envelope(spec, op.data)}
+		}
+		if op.list != "" {
+			success.Content[codec.NDJSON{}.ContentType()] = &openapi.MediaType{Schema: listItem(spec, op.data, op.list)}
+		}
+	}
+	for name, description := range op.headers {
+		if success.Headers == nil {
+			success.Headers = make(map[string]*openapi.Header)
+		}
+		success.Headers[name] = &openapi.Header{Description: description, Schema: spec.Schema("")}
+	}
+	doc.Responses[strconv.Itoa(status)] = success
+
+	for status, codes := range op.withMiddlewareErrors(route) {
+		description := http.StatusText(status)
+		for _, code := range codes {
+			description += fmt.Sprintf("\n- `%s`: %s", code.Name(), code.Description())
+		}
+		doc.Responses[strconv.Itoa(status)] = &openapi.Response{
+			Description: description,
+			Content: map[string]*openapi.MediaType{
+				"application/json":            {Schema: errorEnvelope(spec, domain.StandardResponse{}, codes)},
+				middleware.ProblemContentType: {Schema: errorEnvelope(spec, domain.ProblemDetails{}, codes)},
+			},
+		}
+	}
+
+	// An empty requirement makes credentials optional
+	if len(op.scopes) > 0 {
+		doc.Security = []map[string][]string{{bearerAuth: op.scopes}}
+	} else {
+		doc.Security = []map[string][]string{{}, {bearerAuth: {}}}
+	}
+
+	return doc
+}
+
+// withMiddlewareErrors adds the errors the router's middleware may respond
+// with on route, and groups the codes by status
+func (op operation) withMiddlewareErrors(route string) map[int][]errcode.Code {
+	errors := make(map[int][]errcode.Code, len(op.errors)+4)
+	add := func(code errcode.Code) {
+		if !slices.Contains(errors[code.Status()], code) {
+			errors[code.Status()] = append(errors[code.Status()], code)
+		}
+	}
+
+	for _, code := range op.errors {
+		add(code)
+	}
+	add(errcode.Unauthorized)
+	if len(op.scopes) > 0 {
+		add(errcode.Forbidden)
+	}
+	if _, path, _ := strings.Cut(route, " "); strings.HasPrefix(path, "/api/") && !slices.Contains(catalogRoutes, route) {
+		add(errcode.ServiceUnavailable)
+	}
+	if !slices.Contains(streamingRoutes, route) {
+		add(errcode.Timeout)
+		add(errcode.Cancelled)
+	}
+	if !slices.Contains(rawRoutes, route) {
+		add(errcode.NotAcceptable)
+	}
+	if op.body != nil {
+		add(errcode.UnsupportedMediaType)
+	}
+	if op.deprecation != nil && !op.deprecation.Sunset.IsZero() {
+		add(errcode.Sunset)
+	}
+	return errors
+}
+
+func mediaTypes(spec *openapi.Spec, content map[string]any) map[string]*openapi.MediaType {
+	media := make(map[string]*openapi.MediaType, len(content)+1)
+	for contentType, body := range content {
+		media[contentType] = &openapi.MediaType{Schema: spec.Schema(body)}
+	}
+	return media
+}
+
+// envelope is the StandardResponse of a success, carrying data
+func envelope(spec *openapi.Spec, data any) *openapi.Schema {
+	schema := spec.Schema(data)
+	if schema == nil {
+		schema = &openapi.Schema{}
+	}
+
+	return &openapi.Schema{AllOf: []*openapi.Schema{
+		spec.Schema(domain.StandardResponse{}),
+		{
+			Required:   []string{"data"},
+			Properties: map[string]*openapi.Schema{"data": schema},
+		},
+	}}
+}
+
+// listItem is the schema of an element of the list under key in data,
+// an openapi.Object
+func listItem(spec *openapi.Spec, data any, key string) *openapi.Schema {
+	list := reflect.TypeOf(data.(openapi.Object)[key])
+	return spec.Schema(reflect.Zero(list.Elem()).Interface())
+}
+
+// errorEnvelope is the StandardResponse or ProblemDetails of an error with
+// one of codes
+func errorEnvelope(spec *openapi.Spec, format any, codes []errcode.Code) *openapi.Schema {
+	enum := make([]any, len(codes))
+	for i, code := range codes {
+		enum[i] = code.Name()
+	}
+
+	return &openapi.Schema{AllOf: []*openapi.Schema{
+		spec.Schema(format),
+		{
+			Required: []string{"errors"},
+			Properties: map[string]*openapi.Schema{
+				"errors": {
+					Type: openapi.Types{"array"},
+					Items: &openapi.Schema{Properties: map[string]*openapi.Schema{
+						"code": {Enum: enum},
+					}},
+				},
+			},
+		},
+	}}
+}
+
+// serveOpenAPI serves the OpenAPI document of router, built on first use
+func serveOpenAPI(router chi.Routes, deprecations map[string]middleware.Deprecation) http.HandlerFunc {
+	document := sync.OnceValues(func() ([]byte, error) {
+		doc, err := OpenAPI(router, deprecations)
+		if err != nil {
+			return nil, err
+		}
+		return doc.Marshal()
+	})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := document()
+		if err != nil {
+			respondWithStandardError(r.Context(), w, errcode.SpecFailed, err.Error(), "")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}
+}
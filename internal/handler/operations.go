@@ -0,0 +1,398 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/middleware"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/logger"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/openapi"
+)
+
+// Tags grouping operations in the OpenAPI document
+const (
+	tagHealth    = "Health"
+	tagAdmin     = "Admin"
+	tagUsers     = "Users"
+	tagTransfers = "Imports and Exports"
+	tagAudit     = "Audit"
+	tagErrors    = "Errors"
+)
+
+var (
+	userIDParam     = pathParam("id", int64(0), "User ID")
+	transferIDParam = pathParam("id", "", "Import or export ID")
+
+	// Projection of user reads
+	fieldsParam = queryParam("fields", "", "Comma-separated fields to return, all when unset: "+strings.Join(domain.UserFields, ", "))
+	expandParam = queryParam("expand", "", "Comma-separated related resources to include: "+strings.Join(domain.UserExpansions, ", "))
+
+	adminScopes = []string{middleware.ScopeAdmin}
+
+	// Bodies that are not JSON
+	textSchema = &openapi.Schema{Type: openapi.Types{"string"}}
+	anySchema  = &openapi.Schema{}
+)
+
+// operations documents every route in NewRouter for the OpenAPI document,
+// keyed by method and path as in the document. The routes of the API
+// versions are documented once, in apiOperations.
+var operations = withVersions(map[string]operation{
+	// Health
+	"GET /health": {
+		id:      "getHealth",
+		summary: "Report that the process is alive",
+		tag:     tagHealth,
+		data:    openapi.Object{"status": "", "timestamp": time.Time{}, "api_id": ""},
+	},
+	"GET /readyz": {
+		id:      "getReadiness",
+		summary: "Report whether the database is reachable",
+		tag:     tagHealth,
+		data:    openapi.Object{"status": "", "timestamp": time.Time{}, "checks": map[string]string{}},
+		errors:  []errcode.Code{errcode.ServiceUnavailable},
+	},
+	"GET /openapi.json": {
+		id:      "getOpenAPI",
+		summary: "This OpenAPI document",
+		tag:     tagHealth,
+		content: map[string]any{"application/json": anySchema},
+		errors:  []errcode.Code{errcode.SpecFailed},
+	},
+
+	// Admin
+	"GET /admin/log-levels": {
+		id:      "getLogLevels",
+		summary: "Show the root and component log levels",
+		tag:     tagAdmin,
+		scopes:  adminScopes,
+		data:    openapi.Object{"log_levels": logger.LevelsSnapshot{}},
+	},
+	"PUT /admin/log-levels": {
+		id:          "setLogLevel",
+		summary:     "Change the root or a component's log level",
+		description: "With a ttl, such as 15m, the change reverts after that long.",
+		tag:         tagAdmin,
+		scopes:      adminScopes,
+		body:        domain.SetLogLevelRequest{},
+		data:        openapi.Object{"log_levels": logger.LevelsSnapshot{}},
+		errors:      []errcode.Code{errcode.InvalidRequest, errcode.InvalidComponent, errcode.InvalidLevel, errcode.InvalidTTL},
+	},
+	"DELETE /admin/log-levels/{component}": {
+		id:      "resetLogLevel",
+		summary: "Remove a component's log level override",
+		tag:     tagAdmin,
+		scopes:  adminScopes,
+		params:  []param{pathParam("component", "", "Component name, such as repository")},
+		data:    openapi.Object{"log_levels": logger.LevelsSnapshot{}},
+		errors:  []errcode.Code{errcode.InvalidComponent},
+	},
+	"GET /admin/debug/vars": {
+		id:      "getDebugVars",
+		summary: "Metrics published with expvar",
+		tag:     tagAdmin,
+		scopes:  adminScopes,
+		content: map[string]any{"application/json": anySchema},
+	},
+}, apiOperations, versionOperations)
+
+// apiOperations documents the routes every API version serves, keyed by
+// method and path within the version, as the latest version serves them
+var apiOperations = map[string]operation{
+	// Errors
+	"GET /errors": {
+		id:          "listErrorCodes",
+		summary:     "List every error code the API responds with",
+		description: "Each code has the HTTP status it is sent with, whether the request may succeed when sent again, and its documentation URL, which is also its Problem Details type.",
+		tag:         tagErrors,
+		data:        openapi.Object{"error_codes": []domain.ErrorCode{}},
+		list:        "error_codes",
+	},
+	"GET /errors/{code}": {
+		id:      "getErrorCode",
+		summary: "Describe an error code",
+		tag:     tagErrors,
+		params:  []param{pathParam("code", "", "Error code, such as NOT_FOUND")},
+		data:    openapi.Object{"error_code": domain.ErrorCode{}},
+		errors:  []errcode.Code{errcode.NotFound},
+	},
+
+	// Batch users
+	"POST /users:batchCreate": {
+		id:          "batchCreateUsers",
+		summary:     "Create many users",
+		description: "Atomic batches create every user or none; best effort batches create those that are valid. A batch where nothing was applied responds 422 with the per-item results.",
+		tag:         tagUsers,
+		body:        domain.BatchRequest[domain.CreateUserRequest]{},
+		status:      http.StatusCreated,
+		data:        openapi.Object{"batch": domain.BatchResult{}},
+		errors: []errcode.Code{
+			errcode.InvalidRequest, errcode.BodyTooLarge, errcode.InvalidMode, errcode.EmptyBatch, errcode.TooManyItems,
+			errcode.BatchFailed, errcode.CreateFailed,
+		},
+	},
+	"POST /users:batchUpdate": {
+		id:      "batchUpdateUsers",
+		summary: "Update many users",
+		tag:     tagUsers,
+		body:    domain.BatchRequest[domain.BatchUpdateItem]{},
+		data:    openapi.Object{"batch": domain.BatchResult{}},
+		errors: []errcode.Code{
+			errcode.InvalidRequest, errcode.BodyTooLarge, errcode.InvalidMode, errcode.EmptyBatch, errcode.TooManyItems,
+			errcode.BatchFailed, errcode.UpdateFailed,
+		},
+	},
+	"POST /users:batchDelete": {
+		id:      "batchDeleteUsers",
+		summary: "Delete many users",
+		tag:     tagUsers,
+		body:    domain.BatchRequest[domain.BatchDeleteItem]{},
+		data:    openapi.Object{"batch": domain.BatchResult{}},
+		errors: []errcode.Code{
+			errcode.InvalidRequest, errcode.BodyTooLarge, errcode.InvalidMode, errcode.EmptyBatch, errcode.TooManyItems,
+			errcode.BatchFailed, errcode.DeleteFailed,
+		},
+	},
+
+	// Users
+	"GET /users": {
+		id:          "listUsers",
+		summary:     "List users",
+		description: "JSON and NDJSON lists are streamed as users are read, without a time budget unless one is configured for the route. An error after the list has begun ends it with the errors: in the envelope for JSON, and as a last line with an errors field for NDJSON. With a budget, the list ends with TIMEOUT shortly before the budget runs out.",
+		tag:         tagUsers,
+		params:      []param{fieldsParam, expandParam},
+		data:        openapi.Object{"users": []domain.UserView{}},
+		list:        "users",
+		errors:      []errcode.Code{errcode.InvalidField, errcode.FetchFailed, errcode.Timeout},
+	},
+	"POST /users": {
+		id:      "createUser",
+		summary: "Create a user",
+		tag:     tagUsers,
+		body:    domain.CreateUserRequest{},
+		status:  http.StatusCreated,
+		data:    openapi.Object{"user": domain.User{}},
+		errors:  []errcode.Code{errcode.InvalidRequest, errcode.EmailTaken, errcode.CreateFailed},
+	},
+	"GET /users/events": {
+		id:          "streamUserEvents",
+		summary:     "Stream user changes as server-sent events",
+		description: "Reconnecting clients send Last-Event-ID to receive the events they missed. User details are only included for authenticated callers.",
+		tag:         tagUsers,
+		params: []param{
+			queryParam("type", "", "Comma-separated event types: user.created, user.updated, user.deleted"),
+			queryParam("user_id", int64(0), "Only events of this user"),
+			queryParam("last_event_id", int64(0), "Resume after this event, for clients that cannot set Last-Event-ID"),
+			headerParam("Last-Event-ID", int64(0), "Resume after this event"),
+		},
+		content: map[string]any{"text/event-stream": textSchema},
+		errors:  []errcode.Code{errcode.InvalidLastEventID, errcode.InvalidFilter, errcode.SubscribeFailed},
+	},
+	"GET /users/search": {
+		id:          "searchUsers",
+		summary:     "Search users by name or email",
+		description: "Results are ranked best match first and tolerate misspelled names. Pass next_cursor as cursor, with the same q, for the following page.",
+		tag:         tagUsers,
+		params: []param{
+			{in: openapi.InQuery, name: "q", description: "Text to search for, at most 255 characters", required: true, value: ""},
+			queryParam("limit", 0, "Page size, 1 to 100, 20 by default"),
+			queryParam("cursor", "", "next_cursor of the previous page"),
+		},
+		data: openapi.Object{"users": []domain.UserSearchResult{}, "next_cursor": openapi.Optional("")},
+		list: "users",
+		errors: []errcode.Code{
+			errcode.InvalidQuery, errcode.InvalidLimit, errcode.InvalidCursor, errcode.SearchFailed,
+		},
+	},
+	"GET /users/{id}": {
+		id:      "getUser",
+		summary: "Get a user",
+		tag:     tagUsers,
+		params: []param{
+			userIDParam,
+			queryParam("as_of", time.Time{}, "Return the user as it was at this time"),
+			fieldsParam,
+			expandParam,
+		},
+		data:   openapi.Object{"user": domain.UserView{}},
+		errors: []errcode.Code{errcode.InvalidID, errcode.InvalidField, errcode.InvalidTime, errcode.NotFound},
+	},
+	"PUT /users/{id}": {
+		id:      "updateUser",
+		summary: "Update a user",
+		tag:     tagUsers,
+		params:  []param{userIDParam},
+		body:    domain.UpdateUserRequest{},
+		data:    openapi.Object{"user": domain.User{}},
+		errors:  []errcode.Code{errcode.InvalidID, errcode.InvalidRequest, errcode.EmailTaken, errcode.UpdateFailed},
+	},
+	"DELETE /users/{id}": {
+		id:      "deleteUser",
+		summary: "Delete a user",
+		tag:     tagUsers,
+		params:  []param{userIDParam},
+		status:  http.StatusNoContent,
+		content: map[string]any{},
+		errors:  []errcode.Code{errcode.InvalidID, errcode.DeleteFailed},
+	},
+	"GET /users/{id}/history": {
+		id:      "getUserHistory",
+		summary: "List a user's versions, oldest first",
+		tag:     tagUsers,
+		params:  []param{userIDParam},
+		data:    openapi.Object{"history": []domain.UserVersion{}},
+		list:    "history",
+		errors:  []errcode.Code{errcode.InvalidID, errcode.NotFound, errcode.FetchFailed},
+	},
+	"POST /users/{id}/revert": {
+		id:      "revertUser",
+		summary: "Restore a user to an earlier version",
+		tag:     tagUsers,
+		params:  []param{userIDParam},
+		body:    domain.RevertUserRequest{},
+		data:    openapi.Object{"user": domain.User{}},
+		errors: []errcode.Code{
+			errcode.InvalidID, errcode.InvalidRequest, errcode.InvalidVersion, errcode.NotFound,
+			errcode.EmailTaken, errcode.RevertFailed,
+		},
+	},
+
+	// Imports and exports
+	"POST /users/imports": {
+		id:          "startUserImport",
+		summary:     "Import users from a CSV or NDJSON file",
+		description: "Upload the file in the file field of a multipart form, or as the request body. Options are form fields or query parameters. Users are matched by email: new ones are created and existing ones updated.",
+		tag:         tagTransfers,
+		scopes:      adminScopes,
+		params: []param{
+			queryParam("format", "", "csv or ndjson; guessed from the file name or content type when unset"),
+			queryParam("mapping", "", `JSON object of user field to column or key, such as {"name":"Full name"}`),
+			queryParam("delimiter", "", "CSV delimiter, a comma by default"),
+			queryParam("dry_run", false, "Validate and report rows without writing them"),
+		},
+		bodyContent: map[string]any{
+			"multipart/form-data": &openapi.Schema{
+				Type:     openapi.Types{"object"},
+				Required: []string{"file"},
+				Properties: map[string]*openapi.Schema{
+					"file":      {Type: openapi.Types{"string"}, ContentMediaType: "application/octet-stream"},
+					"format":    {Type: openapi.Types{"string"}},
+					"mapping":   {Type: openapi.Types{"string"}},
+					"delimiter": {Type: openapi.Types{"string"}},
+					"dry_run":   {Type: openapi.Types{"boolean"}},
+				},
+			},
+			"text/csv":             textSchema,
+			"application/x-ndjson": textSchema,
+		},
+		status:  http.StatusAccepted,
+		data:    openapi.Object{"import": domain.UserTransfer{}},
+		headers: map[string]string{"Location": "URL of the import"},
+		errors: []errcode.Code{
+			errcode.InvalidRequest, errcode.MissingFile, errcode.InvalidFile, errcode.InvalidFormat,
+			errcode.InvalidMapping, errcode.MissingColumn, errcode.InvalidDelimiter,
+			errcode.FileTooLarge, errcode.ImportFailed,
+		},
+	},
+	"GET /users/imports/{id}": {
+		id:      "getUserImport",
+		summary: "Get an import's status and counts",
+		tag:     tagTransfers,
+		scopes:  adminScopes,
+		params:  []param{transferIDParam},
+		data:    openapi.Object{"import": domain.UserTransfer{}},
+		errors:  []errcode.Code{errcode.NotFound, errcode.FetchFailed},
+	},
+	"GET /users/imports/{id}/errors": {
+		id:          "downloadUserImportErrors",
+		summary:     "Download the rows an import rejected",
+		description: "A CSV report with the line, field, code and message of each problem. Supports range requests.",
+		tag:         tagTransfers,
+		scopes:      adminScopes,
+		params:      []param{transferIDParam},
+		content:     map[string]any{"text/csv": textSchema},
+		errors:      []errcode.Code{errcode.NotFound, errcode.NotReady, errcode.DownloadFailed},
+	},
+	"POST /users/exports": {
+		id:           "startUserExport",
+		summary:      "Export every user to a file",
+		tag:          tagTransfers,
+		scopes:       adminScopes,
+		body:         domain.CreateExportRequest{},
+		optionalBody: true,
+		status:       http.StatusAccepted,
+		data:         openapi.Object{"export": domain.UserTransfer{}},
+		headers:      map[string]string{"Location": "URL of the export"},
+		errors:       []errcode.Code{errcode.InvalidRequest, errcode.InvalidFormat, errcode.ExportFailed},
+	},
+	"GET /users/exports/{id}": {
+		id:      "getUserExport",
+		summary: "Get an export's status and counts",
+		tag:     tagTransfers,
+		scopes:  adminScopes,
+		params:  []param{transferIDParam},
+		data:    openapi.Object{"export": domain.UserTransfer{}},
+		errors:  []errcode.Code{errcode.NotFound, errcode.FetchFailed},
+	},
+	"GET /users/exports/{id}/download": {
+		id:          "downloadUserExport",
+		summary:     "Download a completed export",
+		description: "Supports range requests, so interrupted downloads can resume.",
+		tag:         tagTransfers,
+		scopes:      adminScopes,
+		params:      []param{transferIDParam},
+		content: map[string]any{
+			"text/csv":             textSchema,
+			"application/x-ndjson": textSchema,
+			"application/json":     []domain.User{},
+		},
+		errors: []errcode.Code{errcode.NotFound, errcode.NotReady, errcode.DownloadFailed},
+	},
+
+	// Audit
+	"GET /audit-events": {
+		id:      "listAuditEvents",
+		summary: "List audit events, newest first",
+		tag:     tagAudit,
+		scopes:  adminScopes,
+		params: []param{
+			queryParam("actor", "", "Only events by this principal"),
+			queryParam("action", "", "Only events with this action, such as user.updated"),
+			queryParam("entity_type", "", "Only events on this type of entity"),
+			queryParam("entity_id", "", "Only events on this entity"),
+			queryParam("since", time.Time{}, "Only events at or after this time"),
+			queryParam("until", time.Time{}, "Only events before this time"),
+			queryParam("limit", 0, "Page size, 1 to 200"),
+			queryParam("cursor", "", "next_cursor of the previous page"),
+		},
+		data: openapi.Object{"audit_events": []domain.AuditEvent{}, "next_cursor": openapi.Optional("")},
+		list: "audit_events",
+		errors: []errcode.Code{
+			errcode.InvalidTime, errcode.InvalidLimit, errcode.InvalidCursor, errcode.FetchFailed,
+		},
+	},
+	"GET /audit-events/verify": {
+		id:      "verifyAuditChain",
+		summary: "Check the audit hash chain for tampering",
+		tag:     tagAudit,
+		scopes:  adminScopes,
+		data:    openapi.Object{"verification": domain.AuditVerification{}},
+		errors:  []errcode.Code{errcode.VerifyFailed},
+	},
+}
+
+// versionOperations document routes of older versions that differ from
+// the latest, as their transformers make them, keyed by method and path
+var versionOperations = map[string]operation{
+	"DELETE /api/v1/users/{id}": {
+		id:      "deleteUser",
+		summary: "Delete a user",
+		tag:     tagUsers,
+		params:  []param{userIDParam},
+		data:    openapi.Object{"message": ""},
+		errors:  []errcode.Code{errcode.InvalidID, errcode.DeleteFailed},
+	},
+}
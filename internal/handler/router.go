@@ -1,46 +1,164 @@
 package handler
 
 import (
+	"expvar"
 	"log/slog"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/i18n"
 	customMiddleware "github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/middleware"
 )
 
-func NewRouter(userHandler *UserHandler, healthHandler *HealthHandler, logger *slog.Logger) *chi.Mux {
+// RouterOptions carries the settings used by the router's middleware
+type RouterOptions struct {
+	APIKeys  []customMiddleware.APIKey
+	Database customMiddleware.HealthChecker
+	Timeouts customMiddleware.TimeoutBudgets
+	Problems customMiddleware.ProblemOptions
+	// Deprecated API versions, keyed by name, e.g. "v1". A version without
+	// a Successor is replaced by the next one.
+	Deprecations map[string]customMiddleware.Deprecation
+	// Counts requests to deprecated versions and routes, if set
+	DeprecatedRequests *expvar.Map
+}
+
+// streamingRoutes stay open indefinitely, move whole files or stream lists
+// of every user, so they run without a request budget, and unbuffered,
+// unless a budget is configured explicitly
+var streamingRoutes = everyVersion(
+	"GET /users",
+	"GET /users/events",
+	"POST /users/imports",
+	"GET /users/imports/{id}/errors",
+	"GET /users/exports/{id}/download",
+)
+
+// everyVersion expands routes given within an API version, such as
+// "GET /users", to the routes of every version
+func everyVersion(routes ...string) []string {
+	expanded := make([]string, 0, len(routes)*len(apiVersions))
+	for _, version := range apiVersions {
+		for _, route := range routes {
+			method, path, _ := strings.Cut(route, " ")
+			expanded = append(expanded, method+" /api/"+version.Name+path)
+		}
+	}
+	return expanded
+}
+
+func NewRouter(userHandler *UserHandler, userEventHandler *UserEventHandler, userTransferHandler *UserTransferHandler, auditHandler *AuditHandler, healthHandler *HealthHandler, adminHandler *AdminHandler, opts RouterOptions, logger *slog.Logger) *chi.Mux {
 	r := chi.NewRouter()
+	versions := versionOptions(opts.Deprecations, opts.DeprecatedRequests)
 
 	// Global middleware
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 	r.Use(customMiddleware.APIIDMiddleware) // Generate unique API ID for each request
+	r.Use(customMiddleware.SelectVersion(versions))
+	r.Use(customMiddleware.NegotiateErrors(opts.Problems))
+	r.Use(customMiddleware.Localize(i18n.Default()))
 	r.Use(customMiddleware.Logger(logger))
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "Deprecation", "Sunset"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
+	r.Use(customMiddleware.Authenticate(opts.APIKeys))
+	r.Use(customMiddleware.Actor)                                          // Who and where, for the audit trail
+	r.Use(customMiddleware.Deprecate(r, versions))                         // Deprecation and Sunset headers
+	r.Use(customMiddleware.NegotiateEncoding(r, encodingOptions()))        // Response encoding chosen by Accept
+	r.Use(customMiddleware.Timeout(r, withStreamingRoutes(opts.Timeouts))) // Per-route request budgets
 
-	// Health check endpoint
+	// Health check endpoints
 	r.Get("/health", healthHandler.Health)
+	r.Get("/readyz", healthHandler.Ready)
 
-	// API routes
-	r.Route("/api/v1", func(r chi.Router) {
-		// User routes
-		r.Route("/users", func(r chi.Router) {
-			r.Get("/", userHandler.GetAllUsers)
-			r.Post("/", userHandler.CreateUser)
-			r.Get("/{id}", userHandler.GetUser)
-			r.Put("/{id}", userHandler.UpdateUser)
-			r.Delete("/{id}", userHandler.DeleteUser)
-		})
+	// OpenAPI document of every route in this router
+	r.Get("/openapi.json", serveOpenAPI(r, opts.Deprecations))
+
+	// Admin routes
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(customMiddleware.RequireScope(customMiddleware.ScopeAdmin))
+
+		r.Get("/log-levels", adminHandler.GetLogLevels)
+		r.Put("/log-levels", adminHandler.SetLogLevel)
+		r.Delete("/log-levels/{component}", adminHandler.ResetLogLevel)
+
+		// Metrics published with expvar, such as per-statement query stats
+		r.Method(http.MethodGet, "/debug/vars", expvar.Handler())
 	})
 
+	// API routes, the same in every version
+	for _, version := range apiVersions {
+		// Catalog of error codes, which needs no database
+		r.Get("/api/"+version.Name+"/errors", serveErrorCatalog(opts.Problems.TypeBaseURL))
+		r.Get("/api/"+version.Name+"/errors/{code}", serveErrorCode(opts.Problems.TypeBaseURL))
+
+		r.Route("/api/"+version.Name, func(r chi.Router) {
+			// Every API route needs the database
+			r.Use(customMiddleware.RequireHealthy(opts.Database))
+			r.Use(customMiddleware.DBSession)
+
+			// Batch user routes, named after the collection they act on
+			r.Post("/users:batchCreate", userHandler.BatchCreateUsers)
+			r.Post("/users:batchUpdate", userHandler.BatchUpdateUsers)
+			r.Post("/users:batchDelete", userHandler.BatchDeleteUsers)
+
+			// User routes
+			r.Route("/users", func(r chi.Router) {
+				r.Get("/", userHandler.GetAllUsers)
+				r.Post("/", userHandler.CreateUser)
+				r.Get("/events", userEventHandler.Stream)
+				r.Get("/search", userHandler.SearchUsers)
+				r.Get("/{id}", userHandler.GetUser)
+				r.Put("/{id}", userHandler.UpdateUser)
+				r.Delete("/{id}", userHandler.DeleteUser)
+				r.Get("/{id}/history", userHandler.GetUserHistory)
+				r.Post("/{id}/revert", userHandler.RevertUser)
+
+				// Imports and exports read and write every user
+				r.Group(func(r chi.Router) {
+					r.Use(customMiddleware.RequireScope(customMiddleware.ScopeAdmin))
+
+					r.Post("/imports", userTransferHandler.StartImport)
+					r.Get("/imports/{id}", userTransferHandler.GetImport)
+					r.Get("/imports/{id}/errors", userTransferHandler.DownloadImportErrors)
+					r.Post("/exports", userTransferHandler.StartExport)
+					r.Get("/exports/{id}", userTransferHandler.GetExport)
+					r.Get("/exports/{id}/download", userTransferHandler.DownloadExport)
+				})
+			})
+
+			// Audit routes
+			r.Route("/audit-events", func(r chi.Router) {
+				r.Use(customMiddleware.RequireScope(customMiddleware.ScopeAdmin))
+
+				r.Get("/", auditHandler.ListEvents)
+				r.Get("/verify", auditHandler.VerifyChain)
+			})
+		})
+	}
+
 	return r
 }
+
+func withStreamingRoutes(budgets customMiddleware.TimeoutBudgets) customMiddleware.TimeoutBudgets {
+	routes := make(map[string]time.Duration, len(budgets.Routes)+len(streamingRoutes))
+	for _, route := range streamingRoutes {
+		routes[route] = 0
+	}
+	for route, budget := range budgets.Routes {
+		routes[route] = budget
+	}
+	budgets.Routes = routes
+	return budgets
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/i18n"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/middleware"
+)
+
+func (h *UserHandler) BatchCreateUsers(w http.ResponseWriter, r *http.Request) {
+	var req domain.BatchRequest[domain.CreateUserRequest]
+	if !decodeBatch(h, w, r, &req) {
+		return
+	}
+
+	items := make([]*domain.CreateUserRequest, len(req.Items))
+	for i := range req.Items {
+		items[i] = &req.Items[i]
+	}
+
+	result, err := h.service.BatchCreateUsers(r.Context(), items, req.Mode)
+	if err != nil {
+		respondWithStandardError(r.Context(), w, errcode.CreateFailed, err.Error(), "")
+		return
+	}
+
+	respondWithBatchResult(w, r, http.StatusCreated, result)
+}
+
+func (h *UserHandler) BatchUpdateUsers(w http.ResponseWriter, r *http.Request) {
+	var req domain.BatchRequest[domain.BatchUpdateItem]
+	if !decodeBatch(h, w, r, &req) {
+		return
+	}
+
+	items := make([]*domain.BatchUpdateItem, len(req.Items))
+	for i := range req.Items {
+		items[i] = &req.Items[i]
+	}
+
+	result, err := h.service.BatchUpdateUsers(r.Context(), items, req.Mode)
+	if err != nil {
+		respondWithStandardError(r.Context(), w, errcode.UpdateFailed, err.Error(), "")
+		return
+	}
+
+	respondWithBatchResult(w, r, http.StatusOK, result)
+}
+
+func (h *UserHandler) BatchDeleteUsers(w http.ResponseWriter, r *http.Request) {
+	var req domain.BatchRequest[domain.BatchDeleteItem]
+	if !decodeBatch(h, w, r, &req) {
+		return
+	}
+
+	ids := make([]int64, len(req.Items))
+	for i, item := range req.Items {
+		ids[i] = item.ID
+	}
+
+	result, err := h.service.BatchDeleteUsers(r.Context(), ids, req.Mode)
+	if err != nil {
+		respondWithStandardError(r.Context(), w, errcode.DeleteFailed, err.Error(), "")
+		return
+	}
+
+	respondWithBatchResult(w, r, http.StatusOK, result)
+}
+
+// batchItemMaxBytes bounds the encoded size of a batch item, whose fields
+// are at most 255 characters, even with every character escaped. Bodies
+// may take this much per item allowed, and one more for the rest.
+const batchItemMaxBytes = 4 << 10
+
+// decodeBatch reads a batch request and checks its mode and size, writing
+// an error response and returning false if it is invalid
+func decodeBatch[T any](h *UserHandler, w http.ResponseWriter, r *http.Request, req *domain.BatchRequest[T]) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, int64(h.batchMaxItems+1)*batchItemMaxBytes)
+	if err := middleware.DecodeBody(r, req); err != nil {
+		respondWithDecodeError(r.Context(), w, err)
+		return false
+	}
+
+	switch req.Mode {
+	case "":
+		req.Mode = domain.BatchAtomic
+	case domain.BatchAtomic, domain.BatchBestEffort:
+	default:
+		respondWithStandardError(r.Context(), w, errcode.InvalidMode, "Mode must be atomic or best_effort", "mode")
+		return false
+	}
+
+	switch {
+	case len(req.Items) == 0:
+		respondWithStandardError(r.Context(), w, errcode.EmptyBatch, "Batch must contain at least one item", "items")
+		return false
+	case len(req.Items) > h.batchMaxItems:
+		respondWithErrorDetail(r.Context(), w, errcode.TooManyItems, errcode.TooManyItems.Message("max", "items", i18n.Args{"Max": h.batchMaxItems}))
+		return false
+	}
+
+	return true
+}
+
+// respondWithBatchResult uses code when every item succeeded and 200 for a
+// partially applied best effort batch. A batch where nothing was applied
+// is a 422 with a BATCH_FAILED error alongside the per-item results.
+func respondWithBatchResult(w http.ResponseWriter, r *http.Request, code int, result *domain.BatchResult) {
+	data := map[string]interface{}{"batch": result}
+	for i := range result.Results {
+		middleware.LocalizeDetails(r.Context(), result.Results[i].Errors)
+	}
+	middleware.Vary(w.Header(), "Accept-Language")
+
+	switch {
+	case !result.Applied:
+		args := i18n.Args{"Failed": result.Failed, "Total": len(result.Results)}
+		middleware.WriteErrorDetail(r.Context(), w, errcode.BatchFailed, errcode.BatchFailed.Message("failed", "", args), data)
+		return
+	case result.Failed > 0:
+		code = http.StatusOK
+	}
+
+	respondWithStandardJSON(r.Context(), w, code, data)
+}
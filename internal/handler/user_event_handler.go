@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/middleware"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/service"
+)
+
+// retryMillis tells EventSource clients how soon to reconnect
+const retryMillis = 2000
+
+type UserEventHandler struct {
+	service   service.UserEventService
+	heartbeat time.Duration
+	logger    *slog.Logger
+
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+}
+
+func NewUserEventHandler(service service.UserEventService, heartbeat time.Duration, logger *slog.Logger) *UserEventHandler {
+	return &UserEventHandler{
+		service:   service,
+		heartbeat: heartbeat,
+		logger:    logger,
+		shutdown:  make(chan struct{}),
+	}
+}
+
+// Shutdown ends open streams so that the server can shut down gracefully.
+// Clients reconnect to another instance with their Last-Event-ID.
+func (h *UserEventHandler) Shutdown() {
+	h.shutdownOnce.Do(func() { close(h.shutdown) })
+}
+
+// Stream sends user change events as Server-Sent Events. Clients resume
+// with the Last-Event-ID header (or last_event_id query parameter) and can
+// filter with type=user.created,user.deleted and user_id=42. Anonymous
+// callers receive the event type and user id but not the user's details.
+func (h *UserEventHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	lastEventID, err := parseLastEventID(r)
+	if err != nil {
+		respondWithStandardError(r.Context(), w, errcode.InvalidLastEventID, "Last-Event-ID must be a positive integer", "last_event_id")
+		return
+	}
+
+	filter, err := newEventFilter(r)
+	if err != nil {
+		respondWithStandardError(r.Context(), w, errcode.InvalidFilter, err.Error(), "")
+		return
+	}
+
+	events, err := h.service.Subscribe(r.Context(), lastEventID)
+	if err != nil {
+		respondWithStandardError(r.Context(), w, errcode.SubscribeFailed, "Failed to subscribe to user events", "")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering
+	w.WriteHeader(http.StatusOK)
+
+	if err := h.send(w, rc, fmt.Sprintf("retry: %d\n\n", retryMillis)); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-h.shutdown:
+			return
+
+		case event, ok := <-events:
+			if !ok {
+				// Dropped for falling behind; the client resumes from its
+				// last event id
+				return
+			}
+			if !filter.allows(event) {
+				continue
+			}
+
+			data, err := json.Marshal(filter.view(event))
+			if err != nil {
+				h.logger.Error("failed to encode user event", "event_id", event.ID, "error", err)
+				continue
+			}
+			if err := h.send(w, rc, fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)); err != nil {
+				return
+			}
+
+		case <-heartbeat.C:
+			if err := h.send(w, rc, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// send writes and flushes one message. The server's WriteTimeout would
+// otherwise end the stream, so each write pushes the deadline past the next
+// heartbeat; a client that stops reading still times out.
+func (h *UserEventHandler) send(w http.ResponseWriter, rc *http.ResponseController, message string) error {
+	err := rc.SetWriteDeadline(time.Now().Add(2 * h.heartbeat))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	if _, err := fmt.Fprint(w, message); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
+
+func parseLastEventID(r *http.Request) (int64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, errors.New("invalid last event id")
+	}
+	return id, nil
+}
+
+// eventFilter limits a stream to the events the caller asked for and may see
+type eventFilter struct {
+	types       map[string]bool
+	userID      int64
+	includeUser bool
+}
+
+func newEventFilter(r *http.Request) (eventFilter, error) {
+	_, authenticated := middleware.GetPrincipal(r.Context())
+	filter := eventFilter{includeUser: authenticated}
+
+	if value := r.URL.Query().Get("type"); value != "" {
+		filter.types = make(map[string]bool)
+		for _, t := range strings.Split(value, ",") {
+			switch t {
+			case domain.UserEventCreated, domain.UserEventUpdated, domain.UserEventDeleted:
+				filter.types[t] = true
+			default:
+				return eventFilter{}, fmt.Errorf("unknown event type %q", t)
+			}
+		}
+	}
+
+	if value := r.URL.Query().Get("user_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return eventFilter{}, errors.New("user_id must be an integer")
+		}
+		filter.userID = id
+	}
+
+	return filter, nil
+}
+
+func (f eventFilter) allows(event *domain.UserEvent) bool {
+	if f.types != nil && !f.types[event.Type] {
+		return false
+	}
+	return f.userID == 0 || event.UserID == f.userID
+}
+
+func (f eventFilter) view(event *domain.UserEvent) *domain.UserEvent {
+	if f.includeUser {
+		return event
+	}
+	redacted := *event
+	redacted.User = nil
+	return &redacted
+}
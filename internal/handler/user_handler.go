@@ -2,39 +2,51 @@ package handler
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"iter"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/i18n"
 	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/middleware"
 	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/service"
 )
 
 type UserHandler struct {
-	service service.UserService
-	logger  *slog.Logger
+	service       service.UserService
+	batchMaxItems int
+	logger        *slog.Logger
 }
 
-func NewUserHandler(service service.UserService, logger *slog.Logger) *UserHandler {
+func NewUserHandler(service service.UserService, batchMaxItems int, logger *slog.Logger) *UserHandler {
 	return &UserHandler{
-		service: service,
-		logger:  logger,
+		service:       service,
+		batchMaxItems: batchMaxItems,
+		logger:        logger,
 	}
 }
 
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var req domain.CreateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithStandardError(r.Context(), w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request payload", "")
+	if err := middleware.DecodeBody(r, &req); err != nil {
+		respondWithDecodeError(r.Context(), w, err)
 		return
 	}
 
 	user, err := h.service.CreateUser(r.Context(), &req)
 	if err != nil {
-		respondWithStandardError(r.Context(), w, http.StatusInternalServerError, "CREATE_FAILED", err.Error(), "")
+		if errors.Is(err, service.ErrEmailTaken) {
+			respondWithStandardError(r.Context(), w, errcode.EmailTaken, "Email is already taken", "email")
+			return
+		}
+		respondWithStandardError(r.Context(), w, errcode.CreateFailed, err.Error(), "")
 		return
 	}
 
@@ -43,17 +55,35 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetUser returns the fields of the user selected with fields=id,name, and
+// the related resources asked for with expand=history
 func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		respondWithStandardError(r.Context(), w, http.StatusBadRequest, "INVALID_ID", "Invalid user ID", "id")
+		respondWithStandardError(r.Context(), w, errcode.InvalidID, "Invalid user ID", "id")
 		return
 	}
 
-	user, err := h.service.GetUser(r.Context(), id)
+	query, details := parseUserQuery(r)
+	if len(details) > 0 {
+		respondWithErrorDetails(r.Context(), w, errcode.InvalidField, details)
+		return
+	}
+
+	var user *domain.UserView
+	if asOf := r.URL.Query().Get("as_of"); asOf != "" {
+		t, err := time.Parse(time.RFC3339, asOf)
+		if err != nil {
+			respondWithStandardError(r.Context(), w, errcode.InvalidTime, "as_of must be in RFC 3339 format", "as_of")
+			return
+		}
+		user, err = h.service.GetUserAsOf(r.Context(), id, t, query)
+	} else {
+		user, err = h.service.GetUser(r.Context(), id, query)
+	}
 	if err != nil {
-		respondWithStandardError(r.Context(), w, http.StatusNotFound, "NOT_FOUND", err.Error(), "")
+		respondWithStandardError(r.Context(), w, errcode.NotFound, err.Error(), "")
 		return
 	}
 
@@ -62,15 +92,16 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetAllUsers takes the fields and expand parameters of GetUser
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.service.GetAllUsers(r.Context())
-	if err != nil {
-		respondWithStandardError(r.Context(), w, http.StatusInternalServerError, "FETCH_FAILED", err.Error(), "")
+	query, details := parseUserQuery(r)
+	if len(details) > 0 {
+		respondWithErrorDetails(r.Context(), w, errcode.InvalidField, details)
 		return
 	}
 
-	respondWithStandardJSON(r.Context(), w, http.StatusOK, map[string]interface{}{
-		"users": users,
+	respondWithStream(w, r, "users", errcode.FetchFailed, func(ctx context.Context) iter.Seq2[*domain.UserView, error] {
+		return h.service.GetAllUsers(ctx, query)
 	})
 }
 
@@ -78,19 +109,23 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		respondWithStandardError(r.Context(), w, http.StatusBadRequest, "INVALID_ID", "Invalid user ID", "id")
+		respondWithStandardError(r.Context(), w, errcode.InvalidID, "Invalid user ID", "id")
 		return
 	}
 
 	var req domain.UpdateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithStandardError(r.Context(), w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request payload", "")
+	if err := middleware.DecodeBody(r, &req); err != nil {
+		respondWithDecodeError(r.Context(), w, err)
 		return
 	}
 
 	user, err := h.service.UpdateUser(r.Context(), id, &req)
 	if err != nil {
-		respondWithStandardError(r.Context(), w, http.StatusInternalServerError, "UPDATE_FAILED", err.Error(), "")
+		if errors.Is(err, service.ErrEmailTaken) {
+			respondWithStandardError(r.Context(), w, errcode.EmailTaken, "Email is already taken", "email")
+			return
+		}
+		respondWithStandardError(r.Context(), w, errcode.UpdateFailed, err.Error(), "")
 		return
 	}
 
@@ -103,62 +138,150 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		respondWithStandardError(r.Context(), w, http.StatusBadRequest, "INVALID_ID", "Invalid user ID", "id")
+		respondWithStandardError(r.Context(), w, errcode.InvalidID, "Invalid user ID", "id")
 		return
 	}
 
 	if err := h.service.DeleteUser(r.Context(), id); err != nil {
-		respondWithStandardError(r.Context(), w, http.StatusInternalServerError, "DELETE_FAILED", err.Error(), "")
+		respondWithStandardError(r.Context(), w, errcode.DeleteFailed, err.Error(), "")
+		return
+	}
+
+	respondWithStandardJSON(r.Context(), w, http.StatusNoContent, nil)
+}
+
+func (h *UserHandler) GetUserHistory(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		respondWithStandardError(r.Context(), w, errcode.InvalidID, "Invalid user ID", "id")
+		return
+	}
+
+	versions, err := h.service.GetUserHistory(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			respondWithStandardError(r.Context(), w, errcode.NotFound, err.Error(), "")
+			return
+		}
+		respondWithStandardError(r.Context(), w, errcode.FetchFailed, err.Error(), "")
+		return
+	}
+
+	respondWithList(w, r, "history", map[string]interface{}{
+		"history": versions,
+	})
+}
+
+func (h *UserHandler) RevertUser(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		respondWithStandardError(r.Context(), w, errcode.InvalidID, "Invalid user ID", "id")
+		return
+	}
+
+	var req domain.RevertUserRequest
+	if err := middleware.DecodeBody(r, &req); err != nil {
+		respondWithDecodeError(r.Context(), w, err)
+		return
+	}
+	if req.Version < 1 {
+		respondWithStandardError(r.Context(), w, errcode.InvalidVersion, "Version must be a positive integer", "version")
+		return
+	}
+
+	user, err := h.service.RevertUser(r.Context(), id, req.Version)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) || errors.Is(err, service.ErrVersionNotFound) {
+			respondWithStandardError(r.Context(), w, errcode.NotFound, err.Error(), "")
+			return
+		}
+		if errors.Is(err, service.ErrEmailTaken) {
+			respondWithStandardError(r.Context(), w, errcode.EmailTaken, "Email is already taken", "email")
+			return
+		}
+		respondWithStandardError(r.Context(), w, errcode.RevertFailed, err.Error(), "")
 		return
 	}
 
 	respondWithStandardJSON(r.Context(), w, http.StatusOK, map[string]interface{}{
-		"message": "User deleted successfully",
+		"user": user,
 	})
 }
 
-// respondWithStandardJSON sends a success response using the StandardResponse format
+// parseUserQuery reads the comma-separated fields and expand parameters,
+// with a detail for each name that is not in domain.UserFields or
+// domain.UserExpansions
+func parseUserQuery(r *http.Request) (domain.UserQuery, []domain.ErrorDetail) {
+	var query domain.UserQuery
+	var details []domain.ErrorDetail
+	if fields := r.URL.Query().Get("fields"); fields != "" {
+		query.Fields = parseNames(fields, "fields", domain.UserFields, &details)
+	}
+	if expand := r.URL.Query().Get("expand"); expand != "" {
+		query.Expand = parseNames(expand, "expand", domain.UserExpansions, &details)
+	}
+	return query, details
+}
+
+// parseNames splits the value of param into names, dropping duplicates and
+// adding a detail to details for each name not in known. It returns nil
+// when the value has no names.
+func parseNames(value, param string, known []string, details *[]domain.ErrorDetail) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "" || slices.Contains(names, name):
+		case slices.Contains(known, name):
+			names = append(names, name)
+		default:
+			args := i18n.Args{"Name": name, "Known": strings.Join(known, ", ")}
+			*details = append(*details, errcode.InvalidField.Message(param, param, args))
+		}
+	}
+	return names
+}
+
+// respondWithStandardJSON sends a success response using the StandardResponse format,
+// converted to the API version of the route, in the encoding negotiated by
+// the middleware: JSON unless the client asks for another. 204 responses
+// have no body.
 func respondWithStandardJSON(ctx context.Context, w http.ResponseWriter, code int, data interface{}) {
+	code, data = transform(ctx, code, data)
+	if code == http.StatusNoContent {
+		w.WriteHeader(code)
+		return
+	}
+
 	response := domain.StandardResponse{
 		APIID:  middleware.GetAPIID(ctx),
 		Errors: []domain.ErrorDetail{}, // Empty array for successful responses
 		Data:   data,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	encoder := middleware.ResponseEncoder(ctx)
+	w.Header().Set("Content-Type", encoder.ContentType())
 	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(response)
+	encoder.Encode(w, response)
 }
 
-// respondWithStandardError sends an error response using the StandardResponse format
-func respondWithStandardError(ctx context.Context, w http.ResponseWriter, code int, errorCode, message, field string) {
-	response := domain.StandardResponse{
-		APIID: middleware.GetAPIID(ctx),
-		Errors: []domain.ErrorDetail{
-			{
-				Code:    errorCode,
-				Message: message,
-				Field:   field,
-			},
-		},
-		Data: nil,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(response)
+// respondWithStandardError sends an error response with the status of code,
+// as a StandardResponse or as Problem Details depending on what the client
+// accepts. Codes come from the errcode registry.
+func respondWithStandardError(ctx context.Context, w http.ResponseWriter, code errcode.Code, message, field string) {
+	middleware.WriteError(ctx, w, code, message, field, nil)
 }
 
-// Deprecated helper functions (kept for backward compatibility)
-func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(payload)
+// respondWithErrorDetail sends an error response with a detail made by
+// code, such as a message of the i18n catalog with arguments
+func respondWithErrorDetail(ctx context.Context, w http.ResponseWriter, code errcode.Code, detail domain.ErrorDetail) {
+	middleware.WriteErrorDetail(ctx, w, code, detail, nil)
 }
 
-func respondWithError(w http.ResponseWriter, code int, message string) {
-	respondWithJSON(w, code, domain.Response{
-		Success: false,
-		Error:   message,
-	})
+// respondWithErrorDetails sends an error response with several details of
+// code, such as one per invalid field
+func respondWithErrorDetails(ctx context.Context, w http.ResponseWriter, code errcode.Code, details []domain.ErrorDetail) {
+	middleware.WriteErrorDetails(ctx, w, code, details, nil)
 }
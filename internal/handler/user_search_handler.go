@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/i18n"
+)
+
+const (
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = 100
+	maxSearchQueryLength  = 255
+)
+
+// SearchUsers finds users by name or email, tolerating misspelled names,
+// best match first. Pages are limit results long; pass next_cursor as
+// cursor, with the same q, to get the following page.
+func (h *UserHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	search := domain.UserSearch{
+		Query: strings.TrimSpace(query.Get("q")),
+		Limit: defaultSearchPageSize,
+	}
+
+	if search.Query == "" {
+		respondWithErrorDetail(r.Context(), w, errcode.InvalidQuery, errcode.InvalidQuery.Message("required", "q", nil))
+		return
+	}
+	if utf8.RuneCountInString(search.Query) > maxSearchQueryLength {
+		respondWithErrorDetail(r.Context(), w, errcode.InvalidQuery, errcode.InvalidQuery.Message("max", "q", i18n.Args{"Max": 255}))
+		return
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxSearchPageSize {
+			respondWithErrorDetail(r.Context(), w, errcode.InvalidLimit, errcode.InvalidLimit.Message("range", "limit", i18n.Args{"Min": 1, "Max": 100}))
+			return
+		}
+		search.Limit = limit
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := decodeSearchCursor(value)
+		if err != nil {
+			respondWithStandardError(r.Context(), w, errcode.InvalidCursor, "Invalid cursor", "cursor")
+			return
+		}
+		search.After = cursor
+	}
+
+	// Fetch one extra result to know whether there is another page
+	pageSize := search.Limit
+	search.Limit++
+
+	results, err := h.service.SearchUsers(r.Context(), search)
+	if err != nil {
+		respondWithStandardError(r.Context(), w, errcode.SearchFailed, err.Error(), "")
+		return
+	}
+
+	data := map[string]interface{}{}
+	if len(results) > pageSize {
+		results = results[:pageSize]
+		last := results[pageSize-1]
+		data["next_cursor"] = encodeSearchCursor(domain.UserSearchCursor{Score: last.Score, ID: last.ID})
+	}
+	data["users"] = results
+
+	respondWithList(w, r, "users", data)
+}
+
+// Search cursors hold the last result's score, exactly, and id
+func encodeSearchCursor(cursor domain.UserSearchCursor) string {
+	raw := strconv.FormatFloat(float64(cursor.Score), 'g', -1, 32) + ":" + strconv.FormatInt(cursor.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeSearchCursor(cursor string) (*domain.UserSearchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	score, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, errors.New("malformed search cursor")
+	}
+
+	s, err := strconv.ParseFloat(score, 32)
+	if err != nil {
+		return nil, err
+	}
+	i, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.UserSearchCursor{Score: float32(s), ID: i}, nil
+}
@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/middleware"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/service"
+)
+
+// Multipart uploads larger than this are spooled to temporary files
+const multipartMemory = 32 << 20
+
+var transferContentTypes = map[string]string{
+	domain.FormatCSV:    "text/csv; charset=utf-8",
+	domain.FormatNDJSON: "application/x-ndjson",
+	domain.FormatJSON:   "application/json",
+}
+
+type UserTransferHandler struct {
+	service        service.UserTransferService
+	maxUploadBytes int64
+	fileTimeout    time.Duration
+	logger         *slog.Logger
+}
+
+// NewUserTransferHandler serves imports and exports. Uploads and downloads
+// may take up to fileTimeout, beyond the server's read and write timeouts.
+func NewUserTransferHandler(service service.UserTransferService, maxUploadBytes int64, fileTimeout time.Duration, logger *slog.Logger) *UserTransferHandler {
+	return &UserTransferHandler{
+		service:        service,
+		maxUploadBytes: maxUploadBytes,
+		fileTimeout:    fileTimeout,
+		logger:         logger,
+	}
+}
+
+// StartImport accepts a multipart/form-data upload with the file in the
+// "file" field, or the raw file as the request body with a text/csv or
+// application/x-ndjson content type. Options are form fields or query
+// parameters: format, mapping (a JSON object such as {"name":"Full name"}),
+// delimiter and dry_run.
+func (h *UserTransferHandler) StartImport(w http.ResponseWriter, r *http.Request) {
+	http.NewResponseController(w).SetReadDeadline(time.Now().Add(h.fileTimeout))
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadBytes)
+
+	src, format, ok := h.importFile(w, r)
+	if !ok {
+		return
+	}
+
+	opts := domain.TransferOptions{Delimiter: r.FormValue("delimiter")}
+	if mapping := r.FormValue("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &opts.Mapping); err != nil {
+			respondWithStandardError(r.Context(), w, errcode.InvalidMapping, "Mapping must be a JSON object of field to column", "mapping")
+			return
+		}
+	}
+	if dryRun := r.FormValue("dry_run"); dryRun != "" {
+		var err error
+		if opts.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			respondWithStandardError(r.Context(), w, errcode.InvalidRequest, "dry_run must be true or false", "dry_run")
+			return
+		}
+	}
+
+	t, err := h.service.StartImport(r.Context(), src, format, opts)
+	if err != nil {
+		var invalid *service.InvalidTransferError
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &invalid):
+			respondWithErrorDetail(r.Context(), w, invalid.Code, invalid.Detail)
+		case errors.As(err, &tooLarge):
+			h.respondTooLarge(w, r)
+		default:
+			respondWithStandardError(r.Context(), w, errcode.ImportFailed, err.Error(), "")
+		}
+		return
+	}
+
+	w.Header().Set("Location", versionPath(r, "/users/imports/"+t.ID))
+	respondWithStandardJSON(r.Context(), w, http.StatusAccepted, map[string]interface{}{
+		"import": t,
+	})
+}
+
+func (h *UserTransferHandler) GetImport(w http.ResponseWriter, r *http.Request) {
+	h.getTransfer(w, r, domain.TransferImport)
+}
+
+// DownloadImportErrors sends the CSV report of rejected rows, with the
+// line, field, code and message of each problem.
+func (h *UserTransferHandler) DownloadImportErrors(w http.ResponseWriter, r *http.Request) {
+	t, f, err := h.service.OpenImportErrors(r.Context(), chi.URLParam(r, "id"))
+	if !h.checkOpen(w, r, err) {
+		return
+	}
+
+	h.serveFile(w, r, "users-import-"+t.ID+"-errors.csv", transferContentTypes[domain.FormatCSV], t, f)
+}
+
+func (h *UserTransferHandler) StartExport(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateExportRequest
+	if err := middleware.DecodeBody(r, &req); err != nil && err != io.EOF {
+		respondWithDecodeError(r.Context(), w, err)
+		return
+	}
+	if req.Format == "" {
+		req.Format = domain.FormatCSV
+	}
+
+	t, err := h.service.StartExport(r.Context(), req.Format)
+	if err != nil {
+		var invalid *service.InvalidTransferError
+		if errors.As(err, &invalid) {
+			respondWithErrorDetail(r.Context(), w, invalid.Code, invalid.Detail)
+			return
+		}
+		respondWithStandardError(r.Context(), w, errcode.ExportFailed, err.Error(), "")
+		return
+	}
+
+	w.Header().Set("Location", versionPath(r, "/users/exports/"+t.ID))
+	respondWithStandardJSON(r.Context(), w, http.StatusAccepted, map[string]interface{}{
+		"export": t,
+	})
+}
+
+func (h *UserTransferHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	h.getTransfer(w, r, domain.TransferExport)
+}
+
+func (h *UserTransferHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	t, f, err := h.service.OpenExport(r.Context(), chi.URLParam(r, "id"))
+	if !h.checkOpen(w, r, err) {
+		return
+	}
+
+	h.serveFile(w, r, "users-"+t.ID+"."+t.Format, transferContentTypes[t.Format], t, f)
+}
+
+func (h *UserTransferHandler) getTransfer(w http.ResponseWriter, r *http.Request, kind string) {
+	t, err := h.service.GetTransfer(r.Context(), kind, chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, service.ErrTransferNotFound) {
+			respondWithStandardError(r.Context(), w, errcode.NotFound, "The "+kind+" was not found", "id")
+			return
+		}
+		respondWithStandardError(r.Context(), w, errcode.FetchFailed, err.Error(), "")
+		return
+	}
+
+	respondWithStandardJSON(r.Context(), w, http.StatusOK, map[string]interface{}{
+		kind: t,
+	})
+}
+
+// importFile returns the uploaded file and its format, from the format
+// option, the file name or the content type, in that order
+func (h *UserTransferHandler) importFile(w http.ResponseWriter, r *http.Request) (io.Reader, string, bool) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = formatOf("", mediaType)
+		}
+		return r.Body, format, true
+	}
+
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.respondTooLarge(w, r)
+			return nil, "", false
+		}
+		respondWithStandardError(r.Context(), w, errcode.InvalidRequest, "Invalid multipart form", "")
+		return nil, "", false
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		respondWithStandardError(r.Context(), w, errcode.MissingFile, "Upload the file in the file field", "file")
+		return nil, "", false
+	}
+
+	format := r.FormValue("format")
+	if format == "" {
+		partType, _, _ := mime.ParseMediaType(header.Header.Get("Content-Type"))
+		format = formatOf(header.Filename, partType)
+	}
+	return file, format, true
+}
+
+// formatOf guesses a file format from its name or media type
+func formatOf(filename, mediaType string) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return domain.FormatCSV
+	case ".ndjson", ".jsonl":
+		return domain.FormatNDJSON
+	}
+
+	switch mediaType {
+	case "text/csv":
+		return domain.FormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return domain.FormatNDJSON
+	}
+	return ""
+}
+
+func (h *UserTransferHandler) checkOpen(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, service.ErrTransferNotFound):
+		respondWithStandardError(r.Context(), w, errcode.NotFound, err.Error(), "id")
+	case errors.Is(err, service.ErrTransferNotReady):
+		respondWithStandardError(r.Context(), w, errcode.NotReady, "The file is available once the transfer has completed", "")
+	default:
+		respondWithStandardError(r.Context(), w, errcode.DownloadFailed, err.Error(), "")
+	}
+	return false
+}
+
+// serveFile supports range requests, so interrupted downloads can resume
+func (h *UserTransferHandler) serveFile(w http.ResponseWriter, r *http.Request, filename, contentType string, t *domain.UserTransfer, f io.ReadSeekCloser) {
+	defer f.Close()
+
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(h.fileTimeout))
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	var modified time.Time
+	if t.FinishedAt != nil {
+		modified = *t.FinishedAt
+	}
+	http.ServeContent(w, r, filename, modified, f)
+}
+
+func (h *UserTransferHandler) respondTooLarge(w http.ResponseWriter, r *http.Request) {
+	respondWithStandardError(r.Context(), w, errcode.FileTooLarge,
+		"The upload is larger than "+strconv.FormatInt(h.maxUploadBytes, 10)+" bytes", "file")
+}
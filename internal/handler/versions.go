@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"expvar"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/middleware"
+)
+
+// apiVersions are the versions of the API, oldest first. Every version
+// serves the same handlers, which respond in the shape of the latest
+// version; transformers convert their responses for older versions.
+// Which versions are deprecated, and until when they are served, is
+// configured with RouterOptions.Deprecations.
+var apiVersions = []middleware.APIVersion{
+	{Name: "v1"},
+	{Name: "v2"},
+}
+
+// latestVersion serves /api paths without a version by default
+var latestVersion = apiVersions[len(apiVersions)-1].Name
+
+// deprecatedRoutes are routes deprecated ahead of their version, keyed by
+// method and pattern, e.g. "GET /api/v2/users/search"
+var deprecatedRoutes = map[string]middleware.Deprecation{}
+
+// versionOptions configures the version middleware for apiVersions,
+// deprecating those of deprecations, whose successor defaults to the next
+// version, and counting deprecated requests in usage when not nil
+func versionOptions(deprecations map[string]middleware.Deprecation, usage *expvar.Map) middleware.VersionOptions {
+	versions := slices.Clone(apiVersions)
+	for i := range versions {
+		d, ok := deprecations[versions[i].Name]
+		if !ok {
+			continue
+		}
+		if d.Successor == "" && i+1 < len(versions) {
+			d.Successor = versions[i+1].Name
+		}
+		versions[i].Deprecation = &d
+	}
+
+	return middleware.VersionOptions{
+		Versions: versions,
+		Default:  latestVersion,
+		Routes:   deprecatedRoutes,
+		Usage:    usage,
+	}
+}
+
+// responseTransformer converts the status and data of a success response
+// to the shape of the previous version
+type responseTransformer func(status int, data interface{}) (int, interface{})
+
+// transformers convert responses of the next version to the shape of the
+// version they are keyed by, then by method and pattern within the
+// version. A response to v1 goes through every version's transformers
+// from the latest down to v1's.
+var transformers = map[string]map[string]responseTransformer{
+	"v1": {
+		// v2 answers deletes with 204 No Content
+		"DELETE /users/{id}": func(int, interface{}) (int, interface{}) {
+			return http.StatusOK, map[string]interface{}{"message": "User deleted successfully"}
+		},
+	},
+}
+
+// transform converts a response of the latest version to the version of
+// the request's route
+func transform(ctx context.Context, status int, data interface{}) (int, interface{}) {
+	rctx := chi.RouteContext(ctx)
+	if rctx == nil {
+		return status, data
+	}
+	version, pattern := splitVersion(rctx.RoutePattern())
+	route := rctx.RouteMethod + " " + pattern
+
+	target := slices.IndexFunc(apiVersions, func(v middleware.APIVersion) bool { return v.Name == version })
+	if target < 0 {
+		return status, data
+	}
+	for i := len(apiVersions) - 2; i >= target; i-- {
+		if t, ok := transformers[apiVersions[i].Name][route]; ok {
+			status, data = t(status, data)
+		}
+	}
+	return status, data
+}
+
+// splitVersion splits an /api/{version} pattern into the version and the
+// pattern within it. Other patterns have no version.
+func splitVersion(pattern string) (string, string) {
+	rest, ok := strings.CutPrefix(pattern, "/api/")
+	if !ok {
+		return "", pattern
+	}
+	version, rest, _ := strings.Cut(rest, "/")
+	return version, "/" + rest
+}
+
+// versionPath returns path, given within an API version, under the version
+// of the request's route
+func versionPath(r *http.Request, path string) string {
+	version, _ := splitVersion(chi.RouteContext(r.Context()).RoutePattern())
+	return "/api/" + version + path
+}
@@ -0,0 +1,189 @@
+// Package i18n translates the messages of error responses. Messages live in
+// JSON bundles, one per language, embedded in the binary from locales/.
+//
+// A bundle maps keys to messages. A key is an error code, such as
+// NOT_FOUND, or an error code and a rule, such as VALIDATION_ERROR.max.
+// Messages are text/template strings over the arguments they are rendered
+// with, e.g. "{{.Label}} must be at most {{.Max}} characters". A message can
+// instead be an object of plural forms, chosen by the argument it names:
+//
+//	{"plural": "Max", "one": "... {{.Max}} item", "other": "... {{.Max}} items"}
+//
+// English is the fallback language. English messages of plain codes are
+// written where the error is raised, so en.json only holds the messages of
+// rules, while the other bundles translate every code.
+package i18n
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Fallback is the language of messages no bundle of the client's languages
+// translates
+const Fallback = "en"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// Args are the arguments a message is rendered with
+type Args map[string]any
+
+// Bundle holds the messages of every language
+type Bundle struct {
+	languages map[string]catalog
+}
+
+type catalog map[string]*message
+
+// message is a message, with its plural forms when it has some. forms
+// always has "other".
+type message struct {
+	plural string
+	forms  map[string]*template.Template
+}
+
+// Load reads the bundles of fsys, one file per language named after it,
+// such as de.json or pt-br.json
+func Load(fsys fs.FS) (*Bundle, error) {
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{languages: make(map[string]catalog, len(files))}
+	for _, file := range files {
+		language := strings.ToLower(strings.TrimSuffix(path.Base(file), ".json"))
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		messages, err := parseCatalog(language, data)
+		if err != nil {
+			return nil, fmt.Errorf("i18n: %s: %w", file, err)
+		}
+		b.languages[language] = messages
+	}
+	if _, ok := b.languages[Fallback]; !ok {
+		return nil, fmt.Errorf("i18n: no bundle for the fallback language %s", Fallback)
+	}
+	return b, nil
+}
+
+// Default returns the embedded bundles. It panics when they are invalid,
+// which fails at startup since the error code registry checks them.
+var Default = sync.OnceValue(func() *Bundle {
+	fsys, err := fs.Sub(locales, "locales")
+	if err != nil {
+		panic(err)
+	}
+	b, err := Load(fsys)
+	if err != nil {
+		panic(err)
+	}
+	return b
+})
+
+func parseCatalog(language string, data []byte) (catalog, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	messages := make(catalog, len(raw))
+	for key, value := range raw {
+		forms := map[string]string{}
+		var text string
+		if err := json.Unmarshal(value, &text); err == nil {
+			forms["other"] = text
+		} else if err := json.Unmarshal(value, &forms); err != nil {
+			return nil, fmt.Errorf("%s: a message is a string or an object of plural forms", key)
+		}
+
+		m := &message{plural: forms["plural"], forms: make(map[string]*template.Template, len(forms))}
+		delete(forms, "plural")
+		if _, ok := forms["other"]; !ok {
+			return nil, fmt.Errorf("%s: plural forms must include other", key)
+		}
+		if len(forms) > 1 && m.plural == "" {
+			return nil, fmt.Errorf("%s: plural forms must name their argument in plural", key)
+		}
+		for form, text := range forms {
+			if !slices.Contains(pluralForms, form) {
+				return nil, fmt.Errorf("%s: unknown plural form %q", key, form)
+			}
+			tmpl, err := template.New(language + ":" + key).Option("missingkey=error").Parse(text)
+			if err != nil {
+				return nil, err
+			}
+			m.forms[form] = tmpl
+		}
+		messages[key] = m
+	}
+	return messages, nil
+}
+
+// Languages returns the languages with a bundle, sorted
+func (b *Bundle) Languages() []string {
+	languages := make([]string, 0, len(b.languages))
+	for language := range b.languages {
+		languages = append(languages, language)
+	}
+	slices.Sort(languages)
+	return languages
+}
+
+// Keys returns the keys of every bundle, sorted and without duplicates
+func (b *Bundle) Keys() []string {
+	var keys []string
+	for _, messages := range b.languages {
+		for key := range messages {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// Message renders the message of key in language with args. It reports
+// false when the bundle has no such message, or args lack one it uses.
+func (b *Bundle) Message(language, key string, args Args) (string, bool) {
+	m, ok := b.languages[language][key]
+	if !ok {
+		return "", false
+	}
+
+	tmpl := m.forms["other"]
+	if m.plural != "" {
+		if n, ok := toInt(args[m.plural]); ok {
+			if form, ok := m.forms[pluralForm(language, n)]; ok {
+				tmpl = form
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, args); err != nil {
+		return "", false
+	}
+	return buf.String(), true
+}
+
+func toInt(v any) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,71 @@
+package i18n
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Negotiate returns the languages to try for a request with the
+// Accept-Language header values, most preferred first. Each language range
+// is followed by its truncations, so de-CH-1996 tries de-ch-1996, de-ch and
+// de, and only languages with a bundle are kept. The chain always ends with
+// the fallback language; ranges with q=0 and the wildcard are ignored.
+func (b *Bundle) Negotiate(acceptLanguage []string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var ranges []weighted
+	for _, header := range acceptLanguage {
+		for _, part := range strings.Split(header, ",") {
+			tag, params, _ := strings.Cut(part, ";")
+			tag = strings.ToLower(strings.TrimSpace(tag))
+			if tag == "" || tag == "*" {
+				continue
+			}
+			q := 1.0
+			if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil {
+					continue
+				}
+				q = parsed
+			}
+			if q > 0 {
+				ranges = append(ranges, weighted{tag: tag, q: q})
+			}
+		}
+	}
+	slices.SortStableFunc(ranges, func(a, b weighted) int {
+		return cmp.Compare(b.q, a.q)
+	})
+
+	var chain []string
+	for _, r := range ranges {
+		for tag := r.tag; tag != ""; tag = truncate(tag) {
+			if _, ok := b.languages[tag]; ok && !slices.Contains(chain, tag) {
+				chain = append(chain, tag)
+			}
+		}
+	}
+	if !slices.Contains(chain, Fallback) {
+		chain = append(chain, Fallback)
+	}
+	return chain
+}
+
+// truncate drops the last subtag of a language tag, and a single letter
+// subtag left before it, as RFC 4647 lookup does
+func truncate(tag string) string {
+	i := strings.LastIndexByte(tag, '-')
+	if i < 0 {
+		return ""
+	}
+	tag = tag[:i]
+	if j := strings.LastIndexByte(tag, '-'); j >= 0 && len(tag)-j == 2 {
+		tag = tag[:j]
+	}
+	return tag
+}
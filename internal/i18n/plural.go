@@ -0,0 +1,36 @@
+package i18n
+
+import "strings"
+
+// pluralForms are the CLDR plural categories a message may define
+var pluralForms = []string{"zero", "one", "two", "few", "many", "other"}
+
+// pluralRules give the plural form of an integer in a language. Languages
+// without a rule use English's.
+var pluralRules = map[string]func(n int) string{
+	"en": oneIfOne,
+	"de": oneIfOne,
+	"fr": func(n int) string {
+		if n == 0 || n == 1 {
+			return "one"
+		}
+		return "other"
+	},
+}
+
+func oneIfOne(n int) string {
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+// pluralForm returns the plural form of n in language, by the rule of its
+// base language, so de-at uses de's
+func pluralForm(language string, n int) string {
+	base, _, _ := strings.Cut(language, "-")
+	if rule, ok := pluralRules[base]; ok {
+		return rule(n)
+	}
+	return oneIfOne(n)
+}
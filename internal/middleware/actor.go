@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+)
+
+// Actor records the principal, API ID and client IP of the request in its
+// context for the audit trail. It must run after RealIP and Authenticate.
+func Actor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := domain.Actor{
+			ID:    domain.AnonymousActor,
+			APIID: GetAPIID(r.Context()),
+			IP:    clientIP(r),
+		}
+		if principal, ok := GetPrincipal(r.Context()); ok {
+			actor.ID = principal.ID
+		}
+
+		next.ServeHTTP(w, r.WithContext(domain.WithActor(r.Context(), actor)))
+	})
+}
+
+// clientIP strips the port RemoteAddr has unless RealIP replaced it
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
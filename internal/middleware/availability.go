@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+)
+
+// HealthChecker reports whether a dependency is currently usable
+type HealthChecker interface {
+	Healthy() bool
+	RetryAfter() time.Duration
+}
+
+// RequireHealthy short-circuits requests with 503 and a Retry-After header
+// while checker reports the dependency as unavailable
+func RequireHealthy(checker HealthChecker) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !checker.Healthy() {
+				retryAfter := max(int(checker.RetryAfter().Seconds()), 1)
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				writeError(r.Context(), w, errcode.ServiceUnavailable, "Database is unavailable, please retry later")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
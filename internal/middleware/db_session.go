@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/database"
+)
+
+// DBSession enables read-your-writes for each request: once the request
+// writes to the primary, its later reads skip the replicas
+func DBSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(database.WithSession(r.Context())))
+	})
+}
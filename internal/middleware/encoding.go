@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/i18n"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/codec"
+)
+
+const encodingKey contextKey = "encoding"
+
+// EncodingOptions configures the encodings of responses and requests
+type EncodingOptions struct {
+	Codecs *codec.Registry
+	// Routes responding with a list, which may be sent with a list encoder
+	// such as NDJSON, keyed by method and pattern
+	ListRoutes []string
+	// Routes responding in media types of their own, such as event streams
+	// and file downloads, which are not negotiated
+	RawRoutes []string
+}
+
+// encoding is what NegotiateEncoding chose for a request
+type encoding struct {
+	codecs *codec.Registry
+	// Encoder of success responses, nil on raw routes
+	response codec.Encoder
+	// Encoder of errors, which are never sent with a list encoder
+	errors codec.Encoder
+}
+
+// UnsupportedMediaTypeError is returned by DecodeBody for a Content-Type
+// that no decoder reads
+type UnsupportedMediaTypeError struct {
+	ContentType string
+	Supported   []string
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("Content-Type %s is not supported", e.ContentType)
+}
+
+// NegotiateEncoding chooses the encoder of the request's responses from its
+// Accept header, responding with NOT_ACCEPTABLE when it accepts none of
+// them. Errors are sent in the best encoding Accept allows that is not a
+// list encoder, or else in the default one. It must run after
+// SelectVersion.
+func NegotiateEncoding(routes chi.Routes, opts EncodingOptions) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accept := r.Header.Values("Accept")
+			e := &encoding{codecs: opts.Codecs}
+			var ok bool
+			if e.errors, ok = opts.Codecs.Negotiate(accept, false); !ok {
+				e.errors = opts.Codecs.Default()
+			}
+			ctx := context.WithValue(r.Context(), encodingKey, e)
+
+			pattern := routes.Find(chi.NewRouteContext(), r.Method, r.URL.Path)
+			route := r.Method + " " + pattern
+			if pattern == "" || slices.Contains(opts.RawRoutes, route) {
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			list := slices.Contains(opts.ListRoutes, route)
+			if e.response, ok = opts.Codecs.Negotiate(accept, list); !ok {
+				args := i18n.Args{"Types": strings.Join(opts.Codecs.ContentTypes(list), ", ")}
+				WriteErrorDetail(ctx, w, errcode.NotAcceptable, errcode.NotAcceptable.Message("types", "", args), nil)
+				return
+			}
+
+			Vary(w.Header(), "Accept")
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ResponseEncoder returns the encoder negotiated for the response of ctx's
+// request, JSON when there is none
+func ResponseEncoder(ctx context.Context) codec.Encoder {
+	if e, ok := ctx.Value(encodingKey).(*encoding); ok && e.response != nil {
+		return e.response
+	}
+	return codec.JSON{}
+}
+
+// errorEncoder returns the encoder negotiated for errors of ctx's request,
+// JSON when there is none
+func errorEncoder(ctx context.Context) codec.Encoder {
+	if e, ok := ctx.Value(encodingKey).(*encoding); ok {
+		return e.errors
+	}
+	return codec.JSON{}
+}
+
+// DecodeBody reads the body of r into v in the media type of its
+// Content-Type, JSON when it has none. A Content-Type without a decoder is
+// an *UnsupportedMediaTypeError.
+func DecodeBody(r *http.Request, v any) error {
+	codecs := defaultCodecs
+	if e, ok := r.Context().Value(encodingKey).(*encoding); ok {
+		codecs = e.codecs
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	decoder, ok := codecs.Decoder(contentType)
+	if !ok {
+		return &UnsupportedMediaTypeError{ContentType: contentType, Supported: codecs.DecoderTypes()}
+	}
+	return decoder.Decode(r.Body, v)
+}
+
+// defaultCodecs read the bodies of requests that were not negotiated
+var defaultCodecs = func() *codec.Registry {
+	codecs := codec.NewRegistry()
+	codecs.Register(codec.JSON{})
+	return codecs
+}()
+
+// Vary adds field to the Vary header unless it is already there
+func Vary(h http.Header, field string) {
+	for _, value := range h.Values("Vary") {
+		for _, f := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(f), field) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", field)
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/i18n"
+)
+
+const localeKey contextKey = "locale"
+
+// locale is the bundle and the languages negotiated for a request
+type locale struct {
+	bundle    *i18n.Bundle
+	languages []string
+}
+
+// Localize negotiates the language of the request's error messages from
+// its Accept-Language header. Only messages are translated; error codes
+// stay the same in every language.
+func Localize(bundle *i18n.Bundle) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := &locale{bundle: bundle, languages: bundle.Negotiate(r.Header.Values("Accept-Language"))}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), localeKey, l)))
+		})
+	}
+}
+
+// LocalizeDetails translates the messages of details in place to the
+// language negotiated for ctx, trying each language of the fallback chain.
+// Messages are left as they are once the chain reaches English, which
+// they are written in. It returns the language of the first detail.
+func LocalizeDetails(ctx context.Context, details []domain.ErrorDetail) string {
+	l, ok := ctx.Value(localeKey).(*locale)
+	if !ok {
+		return i18n.Fallback
+	}
+
+	language := ""
+	for i := range details {
+		lang := l.translate(&details[i])
+		if i == 0 {
+			language = lang
+		}
+	}
+	return language
+}
+
+func (l *locale) translate(d *domain.ErrorDetail) string {
+	for _, language := range l.languages {
+		if language == i18n.Fallback {
+			break
+		}
+		if message, ok := l.bundle.Message(language, d.Key, d.Args); ok {
+			d.Message = message
+			return language
+		}
+	}
+	return i18n.Fallback
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+)
+
+// ProblemContentType is the media type of RFC 9457 Problem Details
+const ProblemContentType = "application/problem+json"
+
+const problemKey contextKey = "problem_format"
+
+// ProblemOptions configures the negotiation of error formats
+type ProblemOptions struct {
+	// API versions, such as "v1", whose errors are Problem Details unless
+	// the client prefers application/json. Other versions and routes
+	// outside /api default to the StandardResponse.
+	Versions []string
+	// TypeBaseURL is the base of the documentation URLs of error codes,
+	// which are the type URIs of problems, e.g. /api/v1/errors/ gives
+	// /api/v1/errors/NOT_FOUND
+	TypeBaseURL string
+}
+
+// problemFormat is stored in the context of requests whose errors are
+// sent as Problem Details
+type problemFormat struct {
+	typeBaseURL string
+}
+
+// problem converts an error to Problem Details, whose type is the
+// documentation URL of its code
+func (f *problemFormat) problem(ctx context.Context, code errcode.Code, details []domain.ErrorDetail, data interface{}) domain.ProblemDetails {
+	return domain.ProblemDetails{
+		Type:     code.DocURL(f.typeBaseURL),
+		Title:    http.StatusText(code.Status()),
+		Status:   code.Status(),
+		Detail:   details[0].Message,
+		Instance: GetAPIID(ctx),
+		Errors:   details,
+		Data:     data,
+	}
+}
+
+// NegotiateErrors chooses the format of the request's error responses from
+// its Accept header: Problem Details when application/problem+json ranks
+// above application/json, the StandardResponse when it ranks below, and
+// the default of the request's API version on a tie, such as */*.
+func NegotiateErrors(opts ProblemOptions) func(next http.Handler) http.Handler {
+	format := &problemFormat{typeBaseURL: opts.TypeBaseURL}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if prefersProblem(r.Header.Values("Accept"), slices.Contains(opts.Versions, apiVersion(r.URL.Path))) {
+				r = r.WithContext(context.WithValue(r.Context(), problemKey, format))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// apiVersion returns the version of an /api/{version}/ path, or ""
+func apiVersion(path string) string {
+	rest, ok := strings.CutPrefix(path, "/api/")
+	if !ok {
+		return ""
+	}
+	version, _, _ := strings.Cut(rest, "/")
+	return version
+}
+
+// prefersProblem compares the quality Accept gives Problem Details and
+// JSON, each taken from the most specific range matching it, and 0 when
+// none does. Without an Accept header, or on a tie, it returns fallback,
+// unless Problem Details were explicitly refused with q=0.
+func prefersProblem(accept []string, fallback bool) bool {
+	problemQ, jsonQ := 0.0, 0.0
+	problemRank, jsonRank := -1, -1
+
+	for _, header := range accept {
+		for _, part := range strings.Split(header, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if value, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(value, 64); err != nil {
+					continue
+				}
+			}
+			if rank := matchRank(mediaType, ProblemContentType); rank > problemRank {
+				problemQ, problemRank = q, rank
+			}
+			if rank := matchRank(mediaType, "application/json"); rank > jsonRank {
+				jsonQ, jsonRank = q, rank
+			}
+		}
+	}
+
+	switch {
+	case problemQ > jsonQ:
+		return true
+	case problemQ < jsonQ, problemRank >= 0 && problemQ == 0:
+		return false
+	default:
+		return fallback
+	}
+}
+
+// matchRank returns how specifically the media range matches mediaType:
+// 2 exactly, 1 by type/*, 0 by */*, -1 not at all
+func matchRank(mediaRange, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// QueryTags returns the request attributes attached to database statements
+// for slow query logs and SQL comments. The route is read when the statement
+// runs, by which time routing has resolved the full pattern.
+func QueryTags(ctx context.Context) map[string]string {
+	tags := make(map[string]string, 2)
+	if apiID, ok := ctx.Value(APIIDKey).(string); ok {
+		tags["api_id"] = apiID
+	}
+	if rctx := chi.RouteContext(ctx); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			tags["route"] = pattern
+		}
+	}
+	return tags
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+)
+
+// writeError sends an error response with the status of code.
+// Middleware cannot use the handler package helpers, so it keeps its own.
+func writeError(ctx context.Context, w http.ResponseWriter, code errcode.Code, message string) {
+	WriteError(ctx, w, code, message, "", nil)
+}
+
+// WriteError sends an error response with the status of code, in the
+// format negotiated by NegotiateErrors: a StandardResponse, in the encoding
+// negotiated by NegotiateEncoding, or Problem Details. data is sent
+// alongside the error when not nil.
+func WriteError(ctx context.Context, w http.ResponseWriter, code errcode.Code, message, field string, data interface{}) {
+	WriteErrorDetail(ctx, w, code, code.Detail(message, field), data)
+}
+
+// WriteErrorDetail is WriteError for a detail made by code, such as one
+// of code.Message. Its message is translated to the language negotiated
+// by Localize.
+func WriteErrorDetail(ctx context.Context, w http.ResponseWriter, code errcode.Code, detail domain.ErrorDetail, data interface{}) {
+	WriteErrorDetails(ctx, w, code, []domain.ErrorDetail{detail}, data)
+}
+
+// WriteErrorDetails is WriteErrorDetail for several details of code, such
+// as one per invalid field. Problem Details take their detail from the
+// first.
+func WriteErrorDetails(ctx context.Context, w http.ResponseWriter, code errcode.Code, details []domain.ErrorDetail, data interface{}) {
+	status := code.Status()
+	w.Header().Set("Content-Language", LocalizeDetails(ctx, details))
+	Vary(w.Header(), "Accept")
+	Vary(w.Header(), "Accept-Language")
+
+	if format, ok := ctx.Value(problemKey).(*problemFormat); ok {
+		w.Header().Set("Content-Type", ProblemContentType)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(format.problem(ctx, code, details, data))
+		return
+	}
+
+	response := domain.StandardResponse{
+		APIID:  GetAPIID(ctx),
+		Errors: details,
+		Data:   data,
+	}
+
+	encoder := errorEncoder(ctx)
+	w.Header().Set("Content-Type", encoder.ContentType())
+	w.WriteHeader(status)
+	encoder.Encode(w, response)
+}
@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+)
+
+// TimeoutBudgets sets how long each route may take. Routes are keyed by
+// method and pattern, e.g. "GET /api/v1/users". A budget of zero or less
+// disables the timeout for that route.
+type TimeoutBudgets struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+func (b TimeoutBudgets) budget(method, pattern string) time.Duration {
+	if d, ok := b.Routes[method+" "+pattern]; ok {
+		return d
+	}
+	return b.Default
+}
+
+// Timeout bounds each request by its route's budget. The handler runs with
+// a context deadline and its response is buffered; if the deadline passes
+// first, a TIMEOUT error, or CANCELLED if the request was cancelled, is
+// sent instead of a partial body. Handlers that stream can call Flush to
+// commit the response, after which a timeout can no longer replace it.
+func Timeout(routes chi.Routes, budgets TimeoutBudgets) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pattern := routes.Find(chi.NewRouteContext(), r.Method, r.URL.Path)
+			budget := budgets.budget(r.Method, pattern)
+			if budget <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), budget)
+			defer cancel()
+
+			tw := &timeoutWriter{w: w, header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				// Re-panic on the server goroutine so Recoverer handles it
+				panic(p)
+
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				// A handler that gave up because of the deadline usually
+				// reports it as a server error; surface it as a timeout
+				if ctx.Err() != nil && tw.status >= http.StatusInternalServerError && !tw.committed {
+					tw.timedOut = true
+					writeTimeout(ctx, w)
+					return
+				}
+				tw.commit()
+
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				tw.timedOut = true
+				if !tw.committed {
+					writeTimeout(ctx, w)
+				}
+			}
+		})
+	}
+}
+
+func writeTimeout(ctx context.Context, w http.ResponseWriter) {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		writeError(ctx, w, errcode.Cancelled, "Request was cancelled")
+		return
+	}
+	writeError(ctx, w, errcode.Timeout, "Request exceeded its time budget")
+}
+
+// timeoutWriter buffers the response until the handler finishes or flushes.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+	buf    bytes.Buffer
+
+	mu          sync.Mutex
+	status      int
+	wroteHeader bool
+	committed   bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeaderLocked(code)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.status = code
+	tw.wroteHeader = true
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	if tw.committed {
+		return tw.w.Write(p)
+	}
+	return tw.buf.Write(p)
+}
+
+// Flush commits the response so far and passes later writes straight through.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	tw.commit()
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.w
+}
+
+// commit sends the buffered header and body. The caller must hold tw.mu.
+func (tw *timeoutWriter) commit() {
+	if !tw.committed {
+		tw.committed = true
+
+		dst := tw.w.Header()
+		for k, v := range tw.header {
+			dst[k] = v
+		}
+		if !tw.wroteHeader {
+			tw.status = http.StatusOK
+		}
+		tw.w.WriteHeader(tw.status)
+	}
+
+	if tw.buf.Len() > 0 {
+		tw.w.Write(tw.buf.Bytes())
+		tw.buf.Reset()
+	}
+}
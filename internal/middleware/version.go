@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"expvar"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+)
+
+// VersionContentType is the media type clients accept, with a version
+// parameter, to choose the API version of a path without one
+const VersionContentType = "application/vnd.api+json"
+
+// APIVersion is a version of the API, served under /api/{Name}
+type APIVersion struct {
+	Name string
+	// Set once the version is deprecated. Its Successor is the name of the
+	// version replacing it.
+	Deprecation *Deprecation
+}
+
+// Deprecation announces that a version or route is going away, with the
+// Deprecation (RFC 9745) and Sunset (RFC 8594) headers
+type Deprecation struct {
+	// When it was, or will be, deprecated
+	Since time.Time
+	// When it stops being served, zero until decided. Requests after it
+	// fail with SUNSET.
+	Sunset time.Time
+	// What replaces it, sent as a successor-version link: a version name
+	// for versions, a path for routes
+	Successor string
+}
+
+// VersionOptions configures version selection and deprecations
+type VersionOptions struct {
+	// Versions of the API, oldest first
+	Versions []APIVersion
+	// Version of /api paths without one, unless the client asks for
+	// another with Accept
+	Default string
+	// Routes deprecated ahead of their version, keyed by method and
+	// pattern, e.g. "GET /api/v2/users/search"
+	Routes map[string]Deprecation
+	// Requests to deprecated versions and routes, counted per route and
+	// principal. Nil disables counting.
+	Usage *expvar.Map
+}
+
+func (o VersionOptions) version(name string) (APIVersion, bool) {
+	i := slices.IndexFunc(o.Versions, func(v APIVersion) bool { return v.Name == name })
+	if i < 0 {
+		return APIVersion{}, false
+	}
+	return o.Versions[i], true
+}
+
+// SelectVersion routes /api paths without a version, such as /api/users,
+// to the version the client accepts, e.g. with
+// Accept: application/vnd.api+json;version=2, or else to the default one.
+// Paths with a version keep it whatever Accept says. It must run before
+// anything that looks at the version of the path.
+func SelectVersion(opts VersionOptions) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rest, ok := strings.CutPrefix(r.URL.Path, "/api/")
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if _, ok := opts.version(apiVersion(r.URL.Path)); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			name, requested := acceptedVersion(r.Header.Values("Accept"))
+			if !requested {
+				name = opts.Default
+			}
+			if _, ok := opts.version(name); !ok {
+				writeError(r.Context(), w, errcode.UnsupportedVersion, fmt.Sprintf("API version %s does not exist", name))
+				return
+			}
+
+			Vary(w.Header(), "Accept")
+			r.URL.Path = "/api/" + name + "/" + rest
+			r.URL.RawPath = ""
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// acceptedVersion returns the version of the first application/vnd.api+json
+// range of Accept with a version parameter, as v1 for either 1 or v1
+func acceptedVersion(accept []string) (string, bool) {
+	for _, header := range accept {
+		for _, part := range strings.Split(header, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil || mediaType != VersionContentType || params["q"] == "0" {
+				continue
+			}
+			if version := params["version"]; version != "" {
+				return "v" + strings.TrimPrefix(version, "v"), true
+			}
+		}
+	}
+	return "", false
+}
+
+// Deprecate sends the Deprecation, Sunset and successor-version Link
+// headers on routes of deprecated versions and on deprecated routes,
+// counting their requests in opts.Usage. Once the sunset has passed it
+// responds with SUNSET instead. It must run after Authenticate.
+func Deprecate(routes chi.Routes, opts VersionOptions) func(next http.Handler) http.Handler {
+	var mu sync.Mutex
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pattern := routes.Find(chi.NewRouteContext(), r.Method, r.URL.Path)
+			if pattern == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			route := r.Method + " " + pattern
+			deprecation, ok := opts.Deprecation(route)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if opts.Usage != nil {
+				principal := domain.AnonymousActor
+				if p, ok := GetPrincipal(r.Context()); ok {
+					principal = p.ID
+				}
+				countUsage(&mu, opts.Usage, route, principal)
+			}
+
+			h := w.Header()
+			h.Set("Deprecation", fmt.Sprintf("@%d", deprecation.Since.Unix()))
+			if !deprecation.Sunset.IsZero() {
+				h.Set("Sunset", deprecation.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if successor := opts.successor(route, r.URL.Path, deprecation); successor != "" {
+				h.Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+			}
+
+			if !deprecation.Sunset.IsZero() && !time.Now().Before(deprecation.Sunset) {
+				writeError(r.Context(), w, errcode.Sunset, fmt.Sprintf("%s was sunset on %s", route, deprecation.Sunset.UTC().Format(time.DateOnly)))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Deprecation returns the deprecation of route, keyed by method and
+// pattern: its own, or else its version's
+func (o VersionOptions) Deprecation(route string) (Deprecation, bool) {
+	if d, ok := o.Routes[route]; ok {
+		return d, true
+	}
+	_, pattern, _ := strings.Cut(route, " ")
+	version, ok := o.version(apiVersion(pattern))
+	if !ok || version.Deprecation == nil {
+		return Deprecation{}, false
+	}
+	return *version.Deprecation, true
+}
+
+// successor returns the path replacing path, of route, under deprecation d
+func (o VersionOptions) successor(route, path string, d Deprecation) string {
+	if _, ok := o.Routes[route]; ok || d.Successor == "" {
+		return d.Successor
+	}
+	return "/api/" + d.Successor + strings.TrimPrefix(path, "/api/"+apiVersion(path))
+}
+
+// countUsage adds a request of principal to route in usage, a map of
+// routes to maps of principals
+func countUsage(mu *sync.Mutex, usage *expvar.Map, route, principal string) {
+	counts, ok := usage.Get(route).(*expvar.Map)
+	if !ok {
+		mu.Lock()
+		if counts, ok = usage.Get(route).(*expvar.Map); !ok {
+			counts = new(expvar.Map)
+			usage.Set(route, counts)
+		}
+		mu.Unlock()
+	}
+	counts.Add(principal, 1)
+}
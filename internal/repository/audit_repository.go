@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/database"
+)
+
+// auditChainLock is the advisory lock key serializing hash chain appends
+const auditChainLock = 7_265_621_001
+
+type AuditRepository interface {
+	// Append records event and sets its ID, and its hashes when the chain
+	// is enabled.
+	Append(ctx context.Context, event *domain.AuditEvent) error
+	List(ctx context.Context, filter domain.AuditFilter) ([]*domain.AuditEvent, error)
+	// Verify recomputes the hash chain over every chained event.
+	Verify(ctx context.Context) (*domain.AuditVerification, error)
+}
+
+type auditRepository struct {
+	db        *database.DB
+	hashChain bool
+}
+
+func NewAuditRepository(db *database.DB, hashChain bool) AuditRepository {
+	return &auditRepository{db: db, hashChain: hashChain}
+}
+
+func (r *auditRepository) Append(ctx context.Context, event *domain.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (occurred_at, actor, api_id, ip, action, entity_type, entity_id, diff, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''))
+		RETURNING id
+	`
+
+	diff, err := json.Marshal(event.Diff)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit diff: %w", err)
+	}
+
+	// Postgres keeps microseconds; hash exactly what is stored
+	event.OccurredAt = event.OccurredAt.UTC().Truncate(time.Microsecond)
+
+	return r.db.InTx(ctx, func(ctx context.Context) error {
+		return r.db.Write(ctx, func(ctx context.Context, q database.Querier) error {
+			if r.hashChain {
+				if err := r.chain(ctx, q, event, diff); err != nil {
+					return err
+				}
+			}
+
+			return q.QueryRowContext(
+				ctx,
+				query,
+				event.OccurredAt,
+				event.Actor,
+				event.APIID,
+				event.IP,
+				event.Action,
+				event.EntityType,
+				event.EntityID,
+				diff,
+				event.PrevHash,
+				event.Hash,
+			).Scan(&event.ID)
+		})
+	})
+}
+
+// chain links event to the latest chained event. The advisory lock is held
+// until the transaction ends, so appends are chained in commit order.
+func (r *auditRepository) chain(ctx context.Context, q database.Querier, event *domain.AuditEvent, diff []byte) error {
+	if _, err := q.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLock); err != nil {
+		return err
+	}
+
+	var prev string
+	err := q.QueryRowContext(ctx, `SELECT hash FROM audit_events WHERE hash IS NOT NULL ORDER BY id DESC LIMIT 1`).Scan(&prev)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	event.PrevHash = prev
+	event.Hash = auditHash(prev, event, diff)
+	return nil
+}
+
+func (r *auditRepository) List(ctx context.Context, filter domain.AuditFilter) ([]*domain.AuditEvent, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Actor != "" {
+		where("actor = $%d", filter.Actor)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if filter.EntityType != "" {
+		where("entity_type = $%d", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		where("entity_id = $%d", filter.EntityID)
+	}
+	if !filter.Since.IsZero() {
+		where("occurred_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		where("occurred_at < $%d", filter.Until)
+	}
+	if filter.BeforeID > 0 {
+		where("id < $%d", filter.BeforeID)
+	}
+
+	query := `
+		SELECT id, occurred_at, actor, api_id, ip, action, entity_type, entity_id, diff,
+		       COALESCE(prev_hash, ''), COALESCE(hash, '')
+		FROM audit_events
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	events := []*domain.AuditEvent{}
+	err := r.db.Read(ctx, func(ctx context.Context, q database.Querier) error {
+		rows, err := q.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			event, err := scanAuditEvent(rows)
+			if err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+
+		return rows.Err()
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (r *auditRepository) Verify(ctx context.Context) (*domain.AuditVerification, error) {
+	query := `
+		SELECT id, occurred_at, actor, api_id, ip, action, entity_type, entity_id, diff,
+		       COALESCE(prev_hash, ''), COALESCE(hash, '')
+		FROM audit_events
+		WHERE hash IS NOT NULL
+		ORDER BY id
+	`
+
+	result := &domain.AuditVerification{Valid: true}
+	err := r.db.Read(database.UsePrimary(ctx), func(ctx context.Context, q database.Querier) error {
+		rows, err := q.QueryContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		var prev string
+		for rows.Next() {
+			event, err := scanAuditEvent(rows)
+			if err != nil {
+				return err
+			}
+			result.Checked++
+
+			diff, err := json.Marshal(event.Diff)
+			if err != nil {
+				return err
+			}
+			if event.PrevHash != prev || event.Hash != auditHash(prev, event, diff) {
+				result.Valid = false
+				result.FirstInvalidID = event.ID
+				return nil
+			}
+			prev = event.Hash
+		}
+
+		return rows.Err()
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func scanAuditEvent(rows *sql.Rows) (*domain.AuditEvent, error) {
+	event := &domain.AuditEvent{}
+	var diff []byte
+	err := rows.Scan(
+		&event.ID,
+		&event.OccurredAt,
+		&event.Actor,
+		&event.APIID,
+		&event.IP,
+		&event.Action,
+		&event.EntityType,
+		&event.EntityID,
+		&diff,
+		&event.PrevHash,
+		&event.Hash,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(diff, &event.Diff); err != nil {
+		return nil, fmt.Errorf("failed to decode audit diff: %w", err)
+	}
+	return event, nil
+}
+
+// auditHash covers every recorded field except the id. diff must be the
+// JSON encoding of event.Diff, which is stable because map keys are sorted.
+func auditHash(prev string, event *domain.AuditEvent, diff []byte) string {
+	h := sha256.New()
+	for _, field := range []string{
+		prev,
+		event.OccurredAt.UTC().Format(time.RFC3339Nano),
+		event.Actor,
+		event.APIID,
+		event.IP,
+		event.Action,
+		event.EntityType,
+		event.EntityID,
+		string(diff),
+	} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/cache"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/database"
+	"golang.org/x/sync/singleflight"
+)
+
+// UserCacheChannel is the Postgres NOTIFY channel used to invalidate cached
+// users across instances. The payload is the user id, or empty to purge.
+const UserCacheChannel = "user_cache_invalidation"
+
+type CacheConfig struct {
+	Size        int
+	TTL         time.Duration
+	NegativeTTL time.Duration // how long a missing user is remembered; zero disables
+	Stats       *CacheStats
+}
+
+// CacheStats counts cache outcomes. It is safe for concurrent use.
+type CacheStats struct {
+	hits          atomic.Int64
+	negativeHits  atomic.Int64
+	misses        atomic.Int64
+	evictions     atomic.Int64
+	invalidations atomic.Int64
+}
+
+func NewCacheStats() *CacheStats {
+	return &CacheStats{}
+}
+
+// Snapshot returns the current counters.
+func (s *CacheStats) Snapshot() map[string]int64 {
+	return map[string]int64{
+		"hits":          s.hits.Load(),
+		"negative_hits": s.negativeHits.Load(),
+		"misses":        s.misses.Load(),
+		"evictions":     s.evictions.Load(),
+		"invalidations": s.invalidations.Load(),
+	}
+}
+
+// cachedUser is a cache entry; a nil user records that the id does not exist.
+type cachedUser struct {
+	user *domain.User
+}
+
+type cachedUserRepository struct {
+	UserRepository
+	db     *database.DB
+	cfg    CacheConfig
+	cache  *cache.LRU[int64, cachedUser]
+	group  singleflight.Group
+	logger *slog.Logger
+
+	// generation is bumped on every invalidation so that a load which
+	// overlaps one does not store what may be a stale row
+	generation atomic.Uint64
+}
+
+// NewCachedUserRepository caches GetByID results of next. Writes invalidate
+// the local entry immediately and notify other instances through listener,
+// which must be started after this call.
+func NewCachedUserRepository(next UserRepository, db *database.DB, listener *database.Listener, cfg CacheConfig, logger *slog.Logger) UserRepository {
+	if cfg.Stats == nil {
+		cfg.Stats = NewCacheStats()
+	}
+
+	r := &cachedUserRepository{
+		UserRepository: next,
+		db:             db,
+		cfg:            cfg,
+		cache:          cache.NewLRU[int64, cachedUser](cfg.Size),
+		logger:         logger,
+	}
+
+	if listener != nil {
+		listener.Subscribe(UserCacheChannel, r.handleNotification)
+		// Notifications sent while disconnected are lost
+		listener.OnReconnect(r.purge)
+	}
+
+	return r
+}
+
+// GetByID caches whole users, so reads that select fields, and reads inside
+// a transaction, which may see uncommitted rows, go to the database
+func (r *cachedUserRepository) GetByID(ctx context.Context, id int64, fields []string) (*domain.User, error) {
+	if fields != nil || database.InTransaction(ctx) {
+		return r.UserRepository.GetByID(ctx, id, fields)
+	}
+
+	if entry, ok := r.cache.Get(id); ok {
+		if entry.user == nil {
+			r.cfg.Stats.negativeHits.Add(1)
+			return nil, sql.ErrNoRows
+		}
+		r.cfg.Stats.hits.Add(1)
+		return copyUser(entry.user), nil
+	}
+	r.cfg.Stats.misses.Add(1)
+
+	// Concurrent misses for the same id share one query, which must not be
+	// cut short by the first caller's request being cancelled. It reads the
+	// primary, since a lagging replica could return the row a write has
+	// just invalidated.
+	v, err, _ := r.group.Do(strconv.FormatInt(id, 10), func() (any, error) {
+		generation := r.generation.Load()
+
+		user, err := r.UserRepository.GetByID(database.UsePrimary(context.WithoutCancel(ctx)), id, nil)
+		switch {
+		case err == nil:
+			r.store(generation, id, cachedUser{user: user}, r.cfg.TTL)
+		case errors.Is(err, sql.ErrNoRows) && r.cfg.NegativeTTL > 0:
+			r.store(generation, id, cachedUser{}, r.cfg.NegativeTTL)
+		}
+		return user, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyUser(v.(*domain.User)), nil
+}
+
+func (r *cachedUserRepository) Create(ctx context.Context, req *domain.CreateUserRequest) (*domain.User, error) {
+	user, err := r.UserRepository.Create(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The id may have been cached as missing
+	r.invalidate(ctx, user.ID)
+	return user, nil
+}
+
+func (r *cachedUserRepository) Update(ctx context.Context, id int64, req *domain.UpdateUserRequest) (*domain.User, *domain.User, error) {
+	before, after, err := r.UserRepository.Update(ctx, id, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r.invalidate(ctx, id)
+	return before, after, nil
+}
+
+func (r *cachedUserRepository) Delete(ctx context.Context, id int64) (*domain.User, error) {
+	user, err := r.UserRepository.Delete(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx, id)
+	return user, nil
+}
+
+func (r *cachedUserRepository) CreateMany(ctx context.Context, reqs []*domain.CreateUserRequest) ([]*domain.User, error) {
+	users, err := r.UserRepository.CreateMany(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		if user != nil {
+			r.invalidate(ctx, user.ID)
+		}
+	}
+	return users, nil
+}
+
+func (r *cachedUserRepository) UpdateMany(ctx context.Context, items []*domain.BatchUpdateItem) ([]*domain.User, []*domain.User, error) {
+	before, after, err := r.UserRepository.UpdateMany(ctx, items)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, user := range after {
+		if user != nil {
+			r.invalidate(ctx, user.ID)
+		}
+	}
+	return before, after, nil
+}
+
+func (r *cachedUserRepository) DeleteMany(ctx context.Context, ids []int64) ([]*domain.User, error) {
+	users, err := r.UserRepository.DeleteMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		if user != nil {
+			r.invalidate(ctx, user.ID)
+		}
+	}
+	return users, nil
+}
+
+func (r *cachedUserRepository) UpsertMany(ctx context.Context, reqs []*domain.CreateUserRequest) ([]*domain.User, []*domain.User, error) {
+	before, after, err := r.UserRepository.UpsertMany(ctx, reqs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, user := range after {
+		if user != nil {
+			r.invalidate(ctx, user.ID)
+		}
+	}
+	return before, after, nil
+}
+
+func (r *cachedUserRepository) store(generation uint64, id int64, entry cachedUser, ttl time.Duration) {
+	if r.generation.Load() != generation {
+		return
+	}
+	if r.cache.Set(id, entry, ttl) {
+		r.cfg.Stats.evictions.Add(1)
+	}
+}
+
+// invalidate drops id locally and notifies every instance. Inside a
+// transaction the notification is only sent on commit, which also clears
+// anything cached locally before the transaction became visible.
+func (r *cachedUserRepository) invalidate(ctx context.Context, id int64) {
+	r.evict(id)
+
+	if err := r.db.Notify(ctx, UserCacheChannel, strconv.FormatInt(id, 10)); err != nil {
+		// Other instances fall back to the TTL
+		r.logger.Warn("Failed to publish cache invalidation", "user_id", id, "error", err)
+	}
+}
+
+func (r *cachedUserRepository) handleNotification(payload string) {
+	if payload == "" {
+		r.purge()
+		return
+	}
+
+	id, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		r.logger.Warn("Ignoring malformed cache invalidation", "payload", payload)
+		return
+	}
+	r.evict(id)
+}
+
+func (r *cachedUserRepository) evict(id int64) {
+	r.generation.Add(1)
+	r.cache.Delete(id)
+	r.cfg.Stats.invalidations.Add(1)
+}
+
+func (r *cachedUserRepository) purge() {
+	r.generation.Add(1)
+	r.cache.Purge()
+	r.cfg.Stats.invalidations.Add(1)
+	r.logger.Info("User cache purged")
+}
+
+// copyUser keeps callers from mutating cached values.
+func copyUser(user *domain.User) *domain.User {
+	c := *user
+	return &c
+}
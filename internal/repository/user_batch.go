@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/database"
+)
+
+// EmailTakenError reports that a write would give a user an email that
+// another user already has.
+type EmailTakenError struct {
+	Email string
+}
+
+func (e *EmailTakenError) Error() string {
+	return fmt.Sprintf("email %s is already taken", e.Email)
+}
+
+var emailConflictDetail = regexp.MustCompile(`^Key \(email\)=\((.*)\) already exists`)
+
+// emailTaken converts a unique violation on users.email into an
+// *EmailTakenError and returns other errors unchanged.
+func emailTaken(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return err
+	}
+	if m := emailConflictDetail.FindStringSubmatch(pqErr.Detail); m != nil {
+		return &EmailTakenError{Email: m[1]}
+	}
+	return err
+}
+
+func (r *userRepository) CreateMany(ctx context.Context, reqs []*domain.CreateUserRequest) ([]*domain.User, error) {
+	// Arrays keep this a single statement with three parameters whatever
+	// the batch size; unlike COPY it can skip conflicting rows
+	query := `
+		INSERT INTO users (email, name, created_at, updated_at)
+		SELECT item.email, item.name, $3, $3
+		FROM unnest($1::text[], $2::text[]) WITH ORDINALITY AS item(email, name, ord)
+		ORDER BY item.ord
+		ON CONFLICT (email) DO NOTHING
+		RETURNING id, email, name, created_at, updated_at
+	`
+
+	emails := make([]string, len(reqs))
+	names := make([]string, len(reqs))
+	for i, req := range reqs {
+		emails[i], names[i] = req.Email, req.Name
+	}
+
+	users := make([]*domain.User, len(reqs))
+	err := r.db.Write(ctx, func(ctx context.Context, q database.Querier) error {
+		rows, err := q.QueryContext(ctx, query, pq.Array(emails), pq.Array(names), time.Now())
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		// Emails are unique, so each returned row belongs to the first item
+		// with its email; later duplicates were skipped
+		index := make(map[string]int, len(reqs))
+		for i := len(reqs) - 1; i >= 0; i-- {
+			index[reqs[i].Email] = i
+		}
+
+		for rows.Next() {
+			user := &domain.User{}
+			if err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.CreatedAt, &user.UpdatedAt); err != nil {
+				return err
+			}
+			users[index[user.Email]] = user
+		}
+
+		return rows.Err()
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *userRepository) UpdateMany(ctx context.Context, items []*domain.BatchUpdateItem) ([]*domain.User, []*domain.User, error) {
+	// Rows are locked in id order so concurrent batches cannot deadlock
+	query := `
+		UPDATE users u
+		SET email = COALESCE(NULLIF(item.email, ''), u.email),
+		    name = COALESCE(NULLIF(item.name, ''), u.name),
+		    updated_at = $4
+		FROM unnest($1::bigint[], $2::text[], $3::text[]) AS item(id, email, name)
+		JOIN (
+			SELECT id, email, name, created_at, updated_at FROM users
+			WHERE id = ANY($1) ORDER BY id FOR UPDATE
+		) old ON old.id = item.id
+		WHERE u.id = item.id
+		RETURNING old.id, old.email, old.name, old.created_at, old.updated_at,
+		          u.id, u.email, u.name, u.created_at, u.updated_at
+	`
+
+	ids := make([]int64, len(items))
+	emails := make([]string, len(items))
+	names := make([]string, len(items))
+	index := make(map[int64]int, len(items))
+	for i, item := range items {
+		ids[i], emails[i], names[i] = item.ID, item.Email, item.Name
+		index[item.ID] = i
+	}
+
+	before := make([]*domain.User, len(items))
+	after := make([]*domain.User, len(items))
+	err := r.db.Write(ctx, func(ctx context.Context, q database.Querier) error {
+		rows, err := q.QueryContext(ctx, query, pq.Array(ids), pq.Array(emails), pq.Array(names), time.Now())
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			b, a := &domain.User{}, &domain.User{}
+			err := rows.Scan(
+				&b.ID, &b.Email, &b.Name, &b.CreatedAt, &b.UpdatedAt,
+				&a.ID, &a.Email, &a.Name, &a.CreatedAt, &a.UpdatedAt,
+			)
+			if err != nil {
+				return err
+			}
+			before[index[a.ID]], after[index[a.ID]] = b, a
+		}
+
+		return rows.Err()
+	})
+
+	if err != nil {
+		return nil, nil, emailTaken(err)
+	}
+
+	return before, after, nil
+}
+
+func (r *userRepository) DeleteMany(ctx context.Context, ids []int64) ([]*domain.User, error) {
+	query := `DELETE FROM users WHERE id = ANY($1) RETURNING id, email, name, created_at, updated_at`
+
+	index := make(map[int64]int, len(ids))
+	for i, id := range ids {
+		index[id] = i
+	}
+
+	users := make([]*domain.User, len(ids))
+	err := r.db.Write(ctx, func(ctx context.Context, q database.Querier) error {
+		rows, err := q.QueryContext(ctx, query, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			user := &domain.User{}
+			if err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.CreatedAt, &user.UpdatedAt); err != nil {
+				return err
+			}
+			users[index[user.ID]] = user
+		}
+
+		return rows.Err()
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *userRepository) UpsertMany(ctx context.Context, reqs []*domain.CreateUserRequest) ([]*domain.User, []*domain.User, error) {
+	// Lock the existing rows first, in id order, so the previous values are
+	// exact and concurrent imports cannot deadlock
+	lockQuery := `
+		SELECT id, email, name, created_at, updated_at FROM users
+		WHERE email = ANY($1) ORDER BY id FOR UPDATE
+	`
+	// Unchanged rows are left alone and not returned
+	upsertQuery := `
+		INSERT INTO users (email, name, created_at, updated_at)
+		SELECT item.email, item.name, $3, $3
+		FROM unnest($1::text[], $2::text[]) WITH ORDINALITY AS item(email, name, ord)
+		ORDER BY item.ord
+		ON CONFLICT (email) DO UPDATE
+		SET name = EXCLUDED.name, updated_at = EXCLUDED.updated_at
+		WHERE users.name IS DISTINCT FROM EXCLUDED.name
+		RETURNING id, email, name, created_at, updated_at
+	`
+
+	emails := make([]string, len(reqs))
+	names := make([]string, len(reqs))
+	index := make(map[string]int, len(reqs))
+	for i, req := range reqs {
+		emails[i], names[i] = req.Email, req.Name
+		index[req.Email] = i
+	}
+
+	before := make([]*domain.User, len(reqs))
+	after := make([]*domain.User, len(reqs))
+	err := r.db.InTx(ctx, func(ctx context.Context) error {
+		return r.db.Write(ctx, func(ctx context.Context, q database.Querier) error {
+			existing, err := queryUsers(ctx, q, lockQuery, pq.Array(emails))
+			if err != nil {
+				return err
+			}
+			for _, user := range existing {
+				before[index[user.Email]] = user
+			}
+
+			changed, err := queryUsers(ctx, q, upsertQuery, pq.Array(emails), pq.Array(names), time.Now())
+			if err != nil {
+				return err
+			}
+			for _, user := range changed {
+				after[index[user.Email]] = user
+			}
+
+			// Only report the previous values of users that changed
+			for i := range before {
+				if after[i] == nil {
+					before[i] = nil
+				}
+			}
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return before, after, nil
+}
+
+// queryUsers runs a query selecting id, email, name, created_at and
+// updated_at
+func queryUsers(ctx context.Context, q database.Querier, query string, args ...any) ([]*domain.User, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*domain.User
+	for rows.Next() {
+		user := &domain.User{}
+		if err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.CreatedAt, &user.UpdatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
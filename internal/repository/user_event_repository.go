@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/database"
+)
+
+// UserEventChannel is the Postgres NOTIFY channel the users table trigger
+// publishes every recorded event on, as JSON.
+const UserEventChannel = "user_events"
+
+type UserEventRepository interface {
+	// ListAfter returns up to limit events with an id greater than afterID,
+	// oldest first.
+	ListAfter(ctx context.Context, afterID int64, limit int) ([]*domain.UserEvent, error)
+	// DeleteBefore removes events recorded before t.
+	DeleteBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+type userEventRepository struct {
+	db *database.DB
+}
+
+func NewUserEventRepository(db *database.DB) UserEventRepository {
+	return &userEventRepository{db: db}
+}
+
+func (r *userEventRepository) ListAfter(ctx context.Context, afterID int64, limit int) ([]*domain.UserEvent, error) {
+	query := `
+		SELECT id, type, user_id, data, created_at
+		FROM user_events
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+
+	var events []*domain.UserEvent
+	// Replicas may not have the latest events yet
+	err := r.db.Read(database.UsePrimary(ctx), func(ctx context.Context, q database.Querier) error {
+		rows, err := q.QueryContext(ctx, query, afterID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			event := &domain.UserEvent{}
+			var data []byte
+			if err := rows.Scan(&event.ID, &event.Type, &event.UserID, &data, &event.CreatedAt); err != nil {
+				return err
+			}
+			if err := json.Unmarshal(data, &event.User); err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+		return rows.Err()
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (r *userEventRepository) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	query := `DELETE FROM user_events WHERE created_at < $1`
+
+	var deleted int64
+	err := r.db.Write(ctx, func(ctx context.Context, q database.Querier) error {
+		result, err := q.ExecContext(ctx, query, t)
+		if err != nil {
+			return err
+		}
+		deleted, err = result.RowsAffected()
+		return err
+	})
+
+	return deleted, err
+}
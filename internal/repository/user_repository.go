@@ -2,25 +2,71 @@ package repository
 
 import (
 	"context"
-	"database/sql"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/database"
 )
 
 type UserRepository interface {
+	// Create and Update return an *EmailTakenError when another user has
+	// the email.
 	Create(ctx context.Context, user *domain.CreateUserRequest) (*domain.User, error)
-	GetByID(ctx context.Context, id int64) (*domain.User, error)
-	GetAll(ctx context.Context) ([]*domain.User, error)
-	Update(ctx context.Context, id int64, user *domain.UpdateUserRequest) (*domain.User, error)
-	Delete(ctx context.Context, id int64) error
+	// GetByID and All select only the columns of fields, and every column
+	// for nil fields. The id is always selected.
+	GetByID(ctx context.Context, id int64, fields []string) (*domain.User, error)
+	// All yields every user, newest first, reading a page of them per
+	// query so that callers need not hold them all. No query is open while
+	// the loop body runs, so it may use the database too. An error ends
+	// the sequence.
+	All(ctx context.Context, fields []string) iter.Seq2[*domain.User, error]
+	// Search returns up to search.Limit users matching search.Query, best
+	// match first, ties broken by id.
+	Search(ctx context.Context, search domain.UserSearch) ([]*domain.UserSearchResult, error)
+	// ListAfter returns up to limit users with an id greater than afterID,
+	// in id order.
+	ListAfter(ctx context.Context, afterID int64, limit int) ([]*domain.User, error)
+	// Update returns the user before and after the change.
+	Update(ctx context.Context, id int64, user *domain.UpdateUserRequest) (before, after *domain.User, err error)
+	// Delete returns the user as it was before deletion.
+	Delete(ctx context.Context, id int64) (*domain.User, error)
+
+	// GetHistory returns every version of a user, oldest first.
+	GetHistory(ctx context.Context, id int64) ([]*domain.UserVersion, error)
+	// GetHistories returns the history of each of ids in one query, keyed
+	// by user id.
+	GetHistories(ctx context.Context, ids []int64) (map[int64][]*domain.UserVersion, error)
+	GetVersion(ctx context.Context, id int64, version int) (*domain.UserVersion, error)
+	// GetAsOf returns the user as it was at t.
+	GetAsOf(ctx context.Context, id int64, t time.Time) (*domain.User, error)
+
+	// CreateMany inserts users in one statement. Items whose email is taken
+	// are skipped and have a nil user at their index.
+	CreateMany(ctx context.Context, reqs []*domain.CreateUserRequest) ([]*domain.User, error)
+	// UpdateMany updates users in one statement and returns them before and
+	// after, nil at the index of ids that don't exist. A duplicate email
+	// fails the whole statement with an *EmailTakenError.
+	UpdateMany(ctx context.Context, items []*domain.BatchUpdateItem) (before, after []*domain.User, err error)
+	// DeleteMany deletes users in one statement and returns them, nil at the
+	// index of ids that don't exist.
+	DeleteMany(ctx context.Context, ids []int64) ([]*domain.User, error)
+	// UpsertMany creates users whose email is new and renames the others,
+	// in one transaction. Emails must be unique within reqs. It returns the
+	// users before and after, with a nil before for created users and nil
+	// for both when nothing changed.
+	UpsertMany(ctx context.Context, reqs []*domain.CreateUserRequest) (before, after []*domain.User, err error)
 }
 
 type userRepository struct {
-	db *sql.DB
+	db *database.DB
 }
 
-func NewUserRepository(db *sql.DB) UserRepository {
+func NewUserRepository(db *database.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
@@ -34,33 +80,74 @@ func (r *userRepository) Create(ctx context.Context, req *domain.CreateUserReque
 	user := &domain.User{}
 	now := time.Now()
 
-	err := r.db.QueryRowContext(
-		ctx,
-		query,
-		req.Email,
-		req.Name,
-		now,
-		now,
-	).Scan(&user.ID, &user.Email, &user.Name, &user.CreatedAt, &user.UpdatedAt)
+	err := r.db.Write(ctx, func(ctx context.Context, q database.Querier) error {
+		return q.QueryRowContext(
+			ctx,
+			query,
+			req.Email,
+			req.Name,
+			now,
+			now,
+		).Scan(&user.ID, &user.Email, &user.Name, &user.CreatedAt, &user.UpdatedAt)
+	})
 
 	if err != nil {
-		return nil, err
+		return nil, emailTaken(err)
 	}
 
 	return user, nil
 }
 
-func (r *userRepository) GetByID(ctx context.Context, id int64) (*domain.User, error) {
-	query := `SELECT id, email, name, created_at, updated_at FROM users WHERE id = $1`
+// userColumns are the columns of the users table, keyed by the field of
+// domain.User they are scanned into
+var userColumns = map[string]func(user *domain.User) any{
+	"id":         func(user *domain.User) any { return &user.ID },
+	"email":      func(user *domain.User) any { return &user.Email },
+	"name":       func(user *domain.User) any { return &user.Name },
+	"created_at": func(user *domain.User) any { return &user.CreatedAt },
+	"updated_at": func(user *domain.User) any { return &user.UpdatedAt },
+}
+
+// userProjection returns the select list of fields, id first and every
+// column for nil fields, and the scan targets of a user in the same order.
+// Fields are checked against userColumns, so they never reach the query
+// unless they name a column.
+func userProjection(fields []string) (string, func(user *domain.User) []any, error) {
+	if fields == nil {
+		fields = domain.UserFields
+	}
+
+	columns := []string{"id"}
+	for _, field := range fields {
+		if _, ok := userColumns[field]; !ok {
+			return "", nil, fmt.Errorf("unknown user field %q", field)
+		}
+		if !slices.Contains(columns, field) {
+			columns = append(columns, field)
+		}
+	}
+
+	dest := func(user *domain.User) []any {
+		targets := make([]any, len(columns))
+		for i, column := range columns {
+			targets[i] = userColumns[column](user)
+		}
+		return targets
+	}
+	return strings.Join(columns, ", "), dest, nil
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int64, fields []string) (*domain.User, error) {
+	columns, dest, err := userProjection(fields)
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT ` + columns + ` FROM users WHERE id = $1`
 
 	user := &domain.User{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Name,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	err = r.db.Read(ctx, func(ctx context.Context, q database.Querier) error {
+		return q.QueryRowContext(ctx, query, id).Scan(dest(user)...)
+	})
 
 	if err != nil {
 		return nil, err
@@ -69,53 +156,145 @@ func (r *userRepository) GetByID(ctx context.Context, id int64) (*domain.User, e
 	return user, nil
 }
 
-func (r *userRepository) GetAll(ctx context.Context) ([]*domain.User, error) {
-	query := `SELECT id, email, name, created_at, updated_at FROM users ORDER BY created_at DESC`
+// AllPageSize is how many users All reads per query
+const AllPageSize = 100
 
-	rows, err := r.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
+func (r *userRepository) All(ctx context.Context, fields []string) iter.Seq2[*domain.User, error] {
+	return func(yield func(*domain.User, error) bool) {
+		// Each page continues after the created_at and id of the last user
+		// of the previous one
+		if fields != nil {
+			fields = append(slices.Clone(fields), "created_at")
+		}
+		columns, dest, err := userProjection(fields)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		first := `SELECT ` + columns + ` FROM users ORDER BY created_at DESC, id DESC LIMIT $1`
+		next := `SELECT ` + columns + ` FROM users WHERE (created_at, id) < ($2, $3) ORDER BY created_at DESC, id DESC LIMIT $1`
+
+		var last *domain.User
+		for {
+			query, args := first, []any{AllPageSize}
+			if last != nil {
+				query, args = next, []any{AllPageSize, last.CreatedAt, last.ID}
+			}
+
+			page := make([]*domain.User, 0, AllPageSize)
+			err := r.db.Read(ctx, func(ctx context.Context, q database.Querier) error {
+				rows, err := q.QueryContext(ctx, query, args...)
+				if err != nil {
+					return err
+				}
+				defer rows.Close()
+
+				for rows.Next() {
+					user := &domain.User{}
+					if err := rows.Scan(dest(user)...); err != nil {
+						return err
+					}
+					page = append(page, user)
+				}
+
+				return rows.Err()
+			})
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, user := range page {
+				if !yield(user, nil) {
+					return
+				}
+			}
+			if len(page) < AllPageSize {
+				return
+			}
+			last = page[len(page)-1]
+		}
 	}
-	defer rows.Close()
+}
+
+func (r *userRepository) ListAfter(ctx context.Context, afterID int64, limit int) ([]*domain.User, error) {
+	query := `SELECT id, email, name, created_at, updated_at FROM users WHERE id > $1 ORDER BY id LIMIT $2`
 
 	users := []*domain.User{}
-	for rows.Next() {
-		user := &domain.User{}
-		err := rows.Scan(
-			&user.ID,
-			&user.Email,
-			&user.Name,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-		)
+	err := r.db.Read(ctx, func(ctx context.Context, q database.Querier) error {
+		rows, err := q.QueryContext(ctx, query, afterID, limit)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		users = append(users, user)
+		defer rows.Close()
+
+		for rows.Next() {
+			user := &domain.User{}
+			if err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.CreatedAt, &user.UpdatedAt); err != nil {
+				return err
+			}
+			users = append(users, user)
+		}
+
+		return rows.Err()
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
 	return users, nil
 }
 
-func (r *userRepository) Update(ctx context.Context, id int64, req *domain.UpdateUserRequest) (*domain.User, error) {
+func (r *userRepository) Update(ctx context.Context, id int64, req *domain.UpdateUserRequest) (*domain.User, *domain.User, error) {
+	// Lock the row in the same statement so the previous values are exact
 	query := `
-		UPDATE users
-		SET email = COALESCE(NULLIF($1, ''), email),
-		    name = COALESCE(NULLIF($2, ''), name),
+		UPDATE users u
+		SET email = COALESCE(NULLIF($1, ''), u.email),
+		    name = COALESCE(NULLIF($2, ''), u.name),
 		    updated_at = $3
-		WHERE id = $4
-		RETURNING id, email, name, created_at, updated_at
+		FROM (SELECT id, email, name, created_at, updated_at FROM users WHERE id = $4 FOR UPDATE) old
+		WHERE u.id = old.id
+		RETURNING old.id, old.email, old.name, old.created_at, old.updated_at,
+		          u.id, u.email, u.name, u.created_at, u.updated_at
 	`
 
+	before := &domain.User{}
+	after := &domain.User{}
+	err := r.db.Write(ctx, func(ctx context.Context, q database.Querier) error {
+		return q.QueryRowContext(
+			ctx,
+			query,
+			req.Email,
+			req.Name,
+			time.Now(),
+			id,
+		).Scan(
+			&before.ID, &before.Email, &before.Name, &before.CreatedAt, &before.UpdatedAt,
+			&after.ID, &after.Email, &after.Name, &after.CreatedAt, &after.UpdatedAt,
+		)
+	})
+
+	if err != nil {
+		return nil, nil, emailTaken(err)
+	}
+
+	return before, after, nil
+}
+
+func (r *userRepository) Delete(ctx context.Context, id int64) (*domain.User, error) {
+	query := `DELETE FROM users WHERE id = $1 RETURNING id, email, name, created_at, updated_at`
+
 	user := &domain.User{}
-	err := r.db.QueryRowContext(
-		ctx,
-		query,
-		req.Email,
-		req.Name,
-		time.Now(),
-		id,
-	).Scan(&user.ID, &user.Email, &user.Name, &user.CreatedAt, &user.UpdatedAt)
+	err := r.db.Write(ctx, func(ctx context.Context, q database.Querier) error {
+		return q.QueryRowContext(ctx, query, id).Scan(
+			&user.ID,
+			&user.Email,
+			&user.Name,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		)
+	})
 
 	if err != nil {
 		return nil, err
@@ -124,22 +303,122 @@ func (r *userRepository) Update(ctx context.Context, id int64, req *domain.Updat
 	return user, nil
 }
 
-func (r *userRepository) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM users WHERE id = $1`
+const userVersionColumns = `version, user_id, email, name, created_at, updated_at, valid_from, valid_to`
+
+func (r *userRepository) GetHistory(ctx context.Context, id int64) ([]*domain.UserVersion, error) {
+	query := `SELECT ` + userVersionColumns + ` FROM users_history WHERE user_id = $1 ORDER BY version`
+
+	versions := []*domain.UserVersion{}
+	err := r.db.Read(ctx, func(ctx context.Context, q database.Querier) error {
+		rows, err := q.QueryContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			version, err := scanUserVersion(rows)
+			if err != nil {
+				return err
+			}
+			versions = append(versions, version)
+		}
+
+		return rows.Err()
+	})
 
-	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	return versions, nil
+}
+
+func (r *userRepository) GetHistories(ctx context.Context, ids []int64) (map[int64][]*domain.UserVersion, error) {
+	query := `SELECT ` + userVersionColumns + ` FROM users_history WHERE user_id = ANY($1) ORDER BY user_id, version`
+
+	histories := make(map[int64][]*domain.UserVersion, len(ids))
+	err := r.db.Read(ctx, func(ctx context.Context, q database.Querier) error {
+		rows, err := q.QueryContext(ctx, query, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			version, err := scanUserVersion(rows)
+			if err != nil {
+				return err
+			}
+			histories[version.User.ID] = append(histories[version.User.ID], version)
+		}
+
+		return rows.Err()
+	})
+
 	if err != nil {
+		return nil, err
+	}
+
+	return histories, nil
+}
+
+func (r *userRepository) GetVersion(ctx context.Context, id int64, version int) (*domain.UserVersion, error) {
+	query := `SELECT ` + userVersionColumns + ` FROM users_history WHERE user_id = $1 AND version = $2`
+
+	var v *domain.UserVersion
+	err := r.db.Read(ctx, func(ctx context.Context, q database.Querier) error {
+		var err error
+		v, err = scanUserVersion(q.QueryRowContext(ctx, query, id, version))
 		return err
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	return v, nil
+}
+
+func (r *userRepository) GetAsOf(ctx context.Context, id int64, t time.Time) (*domain.User, error) {
+	query := `
+		SELECT ` + userVersionColumns + `
+		FROM users_history
+		WHERE user_id = $1 AND valid_from <= $2 AND (valid_to IS NULL OR valid_to > $2)
+		ORDER BY version DESC
+		LIMIT 1
+	`
+
+	var v *domain.UserVersion
+	err := r.db.Read(ctx, func(ctx context.Context, q database.Querier) error {
+		var err error
+		v, err = scanUserVersion(q.QueryRowContext(ctx, query, id, t))
+		return err
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return v.User, nil
+}
+
+// scanUserVersion reads a row selected with userVersionColumns from
+// *sql.Row or *sql.Rows
+func scanUserVersion(row interface{ Scan(dest ...any) error }) (*domain.UserVersion, error) {
+	v := &domain.UserVersion{User: &domain.User{}}
+	err := row.Scan(
+		&v.Version,
+		&v.User.ID,
+		&v.User.Email,
+		&v.User.Name,
+		&v.User.CreatedAt,
+		&v.User.UpdatedAt,
+		&v.ValidFrom,
+		&v.ValidTo,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
 }
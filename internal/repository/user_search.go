@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/database"
+)
+
+func (r *userRepository) Search(ctx context.Context, search domain.UserSearch) ([]*domain.UserSearchResult, error) {
+	// A user matches on full-text (every word of the query as a prefix of a
+	// name or email word), on trigram similarity of the name, or on an
+	// email prefix. Its score is the best of the three. Highlights are only
+	// computed for the page.
+	query := `
+		WITH matches AS (
+			SELECT id, email, name, created_at, updated_at,
+			       GREATEST(
+			           CASE WHEN $2 <> '' THEN ts_rank(search_vector, to_tsquery('simple', $2)) END,
+			           similarity(name, $1),
+			           CASE WHEN email ILIKE $3 THEN 1 END
+			       )::real AS score
+			FROM users
+			WHERE ($2 <> '' AND search_vector @@ to_tsquery('simple', $2))
+			   OR name % $1
+			   OR email ILIKE $3
+		)
+		SELECT id, email, name, created_at, updated_at, score,
+		       CASE WHEN $2 <> '' THEN
+		           ts_headline('simple', ` + escapeHTML("name") + `, to_tsquery('simple', $2),
+		                       'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')
+		       ELSE '' END,
+		       CASE WHEN email ILIKE $3 THEN
+		           '<mark>' || ` + escapeHTML("left(email, char_length($1))") + ` || '</mark>' ||
+		           ` + escapeHTML("substr(email, char_length($1) + 1)") + `
+		       ELSE '' END
+		FROM matches
+		WHERE $4::real IS NULL OR score < $4 OR (score = $4 AND id > $5)
+		ORDER BY score DESC, id
+		LIMIT $6
+	`
+
+	var afterScore *float32
+	var afterID int64
+	if search.After != nil {
+		afterScore, afterID = &search.After.Score, search.After.ID
+	}
+
+	results := []*domain.UserSearchResult{}
+	err := r.db.Read(ctx, func(ctx context.Context, q database.Querier) error {
+		rows, err := q.QueryContext(
+			ctx,
+			query,
+			search.Query,
+			prefixTSQuery(search.Query),
+			likePrefix(search.Query),
+			afterScore,
+			afterID,
+			search.Limit,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			result := &domain.UserSearchResult{}
+			var name, email string
+			err := rows.Scan(
+				&result.ID,
+				&result.Email,
+				&result.Name,
+				&result.CreatedAt,
+				&result.UpdatedAt,
+				&result.Score,
+				&name,
+				&email,
+			)
+			if err != nil {
+				return err
+			}
+
+			for field, highlight := range map[string]string{"name": name, "email": email} {
+				if strings.Contains(highlight, "<mark>") {
+					if result.Highlights == nil {
+						result.Highlights = make(map[string]string, 2)
+					}
+					result.Highlights[field] = highlight
+				}
+			}
+			results = append(results, result)
+		}
+
+		return rows.Err()
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// prefixTSQuery turns free text into a to_tsquery expression that matches
+// every word as a prefix, e.g. "Jon Sm" becomes "jon:* & sm:*". Only
+// letters and digits are kept, so the text cannot inject operators.
+func prefixTSQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// likePrefix returns a LIKE pattern matching values that start with text
+func likePrefix(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text) + "%"
+}
+
+// escapeHTML wraps a SQL text expression so that its value is escaped like
+// html.EscapeString, making highlights safe to render
+func escapeHTML(expr string) string {
+	for _, r := range [][2]string{{"&", "&amp;"}, {"<", "&lt;"}, {">", "&gt;"}, {`"`, "&#34;"}, {"'", "&#39;"}} {
+		expr = "replace(" + expr + ", '" + strings.ReplaceAll(r[0], "'", "''") + "', '" + r[1] + "')"
+	}
+	return expr
+}
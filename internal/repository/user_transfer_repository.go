@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/database"
+)
+
+type UserTransferRepository interface {
+	Create(ctx context.Context, t *domain.UserTransfer) error
+	GetByID(ctx context.Context, id string) (*domain.UserTransfer, error)
+	// Start marks a transfer as running and clears the counts and error of
+	// any earlier attempt.
+	Start(ctx context.Context, id string) (*domain.UserTransfer, error)
+	UpdateCounts(ctx context.Context, id string, counts domain.TransferCounts) error
+	// Finish records the final status, counts and error of a transfer.
+	Finish(ctx context.Context, id string, status string, counts domain.TransferCounts, errMsg string) error
+	// DeleteFinishedBefore removes transfers that finished before t and
+	// returns them, so that their files can be deleted.
+	DeleteFinishedBefore(ctx context.Context, t time.Time) ([]*domain.UserTransfer, error)
+}
+
+type userTransferRepository struct {
+	db *database.DB
+}
+
+func NewUserTransferRepository(db *database.DB) UserTransferRepository {
+	return &userTransferRepository{db: db}
+}
+
+const userTransferColumns = `id, kind, status, format, options, processed, created, updated, unchanged, failed,
+	error, actor, api_id, ip, created_at, started_at, finished_at`
+
+func (r *userTransferRepository) Create(ctx context.Context, t *domain.UserTransfer) error {
+	query := `
+		INSERT INTO user_transfers (id, kind, status, format, options, actor, api_id, ip, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	options, err := json.Marshal(t.Options)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Write(ctx, func(ctx context.Context, q database.Querier) error {
+		_, err := q.ExecContext(
+			ctx,
+			query,
+			t.ID,
+			t.Kind,
+			t.Status,
+			t.Format,
+			options,
+			t.RequestedBy.ID,
+			t.RequestedBy.APIID,
+			t.RequestedBy.IP,
+			t.CreatedAt,
+		)
+		return err
+	})
+}
+
+func (r *userTransferRepository) GetByID(ctx context.Context, id string) (*domain.UserTransfer, error) {
+	query := `SELECT ` + userTransferColumns + ` FROM user_transfers WHERE id = $1`
+
+	var t *domain.UserTransfer
+	// Progress is polled; replicas may lag behind the job
+	err := r.db.Read(database.UsePrimary(ctx), func(ctx context.Context, q database.Querier) error {
+		var err error
+		t, err = scanUserTransfer(q.QueryRowContext(ctx, query, id))
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (r *userTransferRepository) Start(ctx context.Context, id string) (*domain.UserTransfer, error) {
+	query := `
+		UPDATE user_transfers
+		SET status = $1, started_at = $2, finished_at = NULL, error = '',
+		    processed = 0, created = 0, updated = 0, unchanged = 0, failed = 0
+		WHERE id = $3
+		RETURNING ` + userTransferColumns
+
+	var t *domain.UserTransfer
+	err := r.db.Write(ctx, func(ctx context.Context, q database.Querier) error {
+		var err error
+		t, err = scanUserTransfer(q.QueryRowContext(ctx, query, domain.TransferRunning, time.Now(), id))
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (r *userTransferRepository) UpdateCounts(ctx context.Context, id string, counts domain.TransferCounts) error {
+	query := `
+		UPDATE user_transfers
+		SET processed = $1, created = $2, updated = $3, unchanged = $4, failed = $5
+		WHERE id = $6
+	`
+
+	return r.db.Write(ctx, func(ctx context.Context, q database.Querier) error {
+		_, err := q.ExecContext(ctx, query, counts.Processed, counts.Created, counts.Updated, counts.Unchanged, counts.Failed, id)
+		return err
+	})
+}
+
+func (r *userTransferRepository) Finish(ctx context.Context, id string, status string, counts domain.TransferCounts, errMsg string) error {
+	query := `
+		UPDATE user_transfers
+		SET status = $1, error = $2, finished_at = $3,
+		    processed = $4, created = $5, updated = $6, unchanged = $7, failed = $8
+		WHERE id = $9
+	`
+
+	return r.db.Write(ctx, func(ctx context.Context, q database.Querier) error {
+		_, err := q.ExecContext(
+			ctx,
+			query,
+			status,
+			errMsg,
+			time.Now(),
+			counts.Processed,
+			counts.Created,
+			counts.Updated,
+			counts.Unchanged,
+			counts.Failed,
+			id,
+		)
+		return err
+	})
+}
+
+func (r *userTransferRepository) DeleteFinishedBefore(ctx context.Context, t time.Time) ([]*domain.UserTransfer, error) {
+	query := `DELETE FROM user_transfers WHERE finished_at < $1 RETURNING ` + userTransferColumns
+
+	var transfers []*domain.UserTransfer
+	err := r.db.Write(ctx, func(ctx context.Context, q database.Querier) error {
+		rows, err := q.QueryContext(ctx, query, t)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			transfer, err := scanUserTransfer(rows)
+			if err != nil {
+				return err
+			}
+			transfers = append(transfers, transfer)
+		}
+		return rows.Err()
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+// scanUserTransfer reads a row selected with userTransferColumns from
+// *sql.Row or *sql.Rows
+func scanUserTransfer(row interface{ Scan(dest ...any) error }) (*domain.UserTransfer, error) {
+	t := &domain.UserTransfer{}
+	var options []byte
+	err := row.Scan(
+		&t.ID,
+		&t.Kind,
+		&t.Status,
+		&t.Format,
+		&options,
+		&t.Counts.Processed,
+		&t.Counts.Created,
+		&t.Counts.Updated,
+		&t.Counts.Unchanged,
+		&t.Counts.Failed,
+		&t.Error,
+		&t.RequestedBy.ID,
+		&t.RequestedBy.APIID,
+		&t.RequestedBy.IP,
+		&t.CreatedAt,
+		&t.StartedAt,
+		&t.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(options, &t.Options); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/repository"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/eventbus"
+)
+
+type AuditService interface {
+	ListEvents(ctx context.Context, filter domain.AuditFilter) ([]*domain.AuditEvent, error)
+	VerifyChain(ctx context.Context) (*domain.AuditVerification, error)
+}
+
+type auditService struct {
+	repo   repository.AuditRepository
+	logger *slog.Logger
+}
+
+// NewAuditService records an audit event for every user change published on
+// bus. It subscribes synchronously, so the audit event is written in the
+// same transaction as the change and a failure to record it rolls the
+// change back.
+func NewAuditService(repo repository.AuditRepository, bus *eventbus.Bus, logger *slog.Logger) AuditService {
+	s := &auditService{
+		repo:   repo,
+		logger: logger,
+	}
+
+	eventbus.On(bus, func(ctx context.Context, e domain.UserCreated) error {
+		return s.record(ctx, e.EventName(), e.User.ID, userDiff(nil, e.User))
+	})
+	eventbus.On(bus, func(ctx context.Context, e domain.UserUpdated) error {
+		return s.record(ctx, e.EventName(), e.User.ID, e.Changes)
+	})
+	eventbus.On(bus, func(ctx context.Context, e domain.UserDeleted) error {
+		return s.record(ctx, e.EventName(), e.User.ID, userDiff(e.User, nil))
+	})
+
+	return s
+}
+
+func (s *auditService) ListEvents(ctx context.Context, filter domain.AuditFilter) ([]*domain.AuditEvent, error) {
+	events, err := s.repo.List(ctx, filter)
+	if err != nil {
+		s.logger.Error("failed to list audit events", "error", err)
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (s *auditService) VerifyChain(ctx context.Context) (*domain.AuditVerification, error) {
+	result, err := s.repo.Verify(ctx)
+	if err != nil {
+		s.logger.Error("failed to verify audit chain", "error", err)
+		return nil, err
+	}
+
+	if !result.Valid {
+		s.logger.Warn("audit chain is broken", "first_invalid_id", result.FirstInvalidID)
+	}
+	return result, nil
+}
+
+func (s *auditService) record(ctx context.Context, action string, userID int64, diff map[string]domain.FieldChange) error {
+	actor := domain.ActorFrom(ctx)
+	return s.repo.Append(ctx, &domain.AuditEvent{
+		OccurredAt: time.Now(),
+		Actor:      actor.ID,
+		APIID:      actor.APIID,
+		IP:         actor.IP,
+		Action:     action,
+		EntityType: domain.EntityUser,
+		EntityID:   strconv.FormatInt(userID, 10),
+		Diff:       diff,
+	})
+}
+
+// userDiff lists every user-editable field of a created or deleted user
+func userDiff(before, after *domain.User) map[string]domain.FieldChange {
+	fields := func(u *domain.User) map[string]any {
+		if u == nil {
+			return map[string]any{"email": nil, "name": nil}
+		}
+		return map[string]any{"email": u.Email, "name": u.Name}
+	}
+
+	from, to := fields(before), fields(after)
+	diff := make(map[string]domain.FieldChange, len(from))
+	for field := range from {
+		diff[field] = domain.FieldChange{From: from[field], To: to[field]}
+	}
+	return diff
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/i18n"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/repository"
+)
+
+// maxFieldLength matches the VARCHAR(255) user columns
+const maxFieldLength = 255
+
+// errBatchRolledBack rolls back an atomic batch in which an item failed
+var errBatchRolledBack = errors.New("batch rolled back")
+
+// batch collects per-item results
+type batch struct {
+	atomic  bool
+	results []domain.BatchItemResult
+}
+
+func newBatch(size int, mode string) *batch {
+	b := &batch{
+		atomic:  mode != domain.BatchBestEffort,
+		results: make([]domain.BatchItemResult, size),
+	}
+	for i := range b.results {
+		b.results[i] = domain.BatchItemResult{Index: i, Errors: []domain.ErrorDetail{}}
+	}
+	return b
+}
+
+func (b *batch) fail(i int, code errcode.Code, message, field string) {
+	b.results[i].Errors = append(b.results[i].Errors, code.Detail(message, field))
+}
+
+func (b *batch) failed(i int) bool {
+	return len(b.results[i].Errors) > 0
+}
+
+// pending returns the indexes of the items without errors
+func (b *batch) pending() []int {
+	var indexes []int
+	for i := range b.results {
+		if !b.failed(i) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// abort marks every item that would have succeeded as not applied
+func (b *batch) abort() {
+	for i := range b.results {
+		b.results[i].User = nil
+		if !b.failed(i) {
+			b.fail(i, errcode.NotApplied, "Not applied because other items in the atomic batch failed", "")
+		}
+	}
+}
+
+// stop reports whether an atomic batch has to be abandoned
+func (b *batch) stop() bool {
+	return b.atomic && len(b.pending()) < len(b.results)
+}
+
+func (b *batch) result() *domain.BatchResult {
+	result := &domain.BatchResult{Results: b.results}
+	for i := range b.results {
+		if b.failed(i) {
+			result.Failed++
+		} else {
+			result.Succeeded++
+		}
+	}
+	result.Applied = result.Succeeded > 0
+	return result
+}
+
+func (s *userService) BatchCreateUsers(ctx context.Context, reqs []*domain.CreateUserRequest, mode string) (*domain.BatchResult, error) {
+	b := newBatch(len(reqs), mode)
+	for i, req := range reqs {
+		validateUserFields(b, i, req.Email, req.Name, true)
+	}
+	if b.stop() {
+		b.abort()
+		return b.result(), nil
+	}
+
+	indexes := b.pending()
+	pending := make([]*domain.CreateUserRequest, len(indexes))
+	for j, i := range indexes {
+		pending[j] = reqs[i]
+	}
+
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		users, err := s.repo.CreateMany(ctx, pending)
+		if err != nil {
+			return err
+		}
+
+		for j, user := range users {
+			i := indexes[j]
+			if user == nil {
+				b.fail(i, errcode.EmailTaken, "Email is already taken", "email")
+				continue
+			}
+			b.results[i].User = user
+			if err := s.events.Publish(ctx, domain.UserCreated{User: user}); err != nil {
+				return err
+			}
+		}
+
+		if b.stop() {
+			return errBatchRolledBack
+		}
+		return nil
+	})
+
+	return s.finishBatch(b, "created", err)
+}
+
+func (s *userService) BatchUpdateUsers(ctx context.Context, items []*domain.BatchUpdateItem, mode string) (*domain.BatchResult, error) {
+	b := newBatch(len(items), mode)
+	seen := make(map[int64]bool, len(items))
+	for i, item := range items {
+		validateID(b, i, item.ID, seen)
+		validateUserFields(b, i, item.Email, item.Name, false)
+	}
+	if b.stop() {
+		b.abort()
+		return b.result(), nil
+	}
+
+	indexes := b.pending()
+	pending := make([]*domain.BatchUpdateItem, len(indexes))
+	for j, i := range indexes {
+		pending[j] = items[i]
+	}
+
+	var taken *repository.EmailTakenError
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		before, after, err := s.repo.UpdateMany(ctx, pending)
+		if err != nil {
+			return err
+		}
+
+		for j, user := range after {
+			i := indexes[j]
+			if user == nil {
+				b.fail(i, errcode.NotFound, ErrUserNotFound.Error(), "id")
+				continue
+			}
+			b.results[i].User = user
+			if err := s.events.Publish(ctx, domain.UserUpdated{User: user, Changes: domain.DiffUsers(before[j], user)}); err != nil {
+				return err
+			}
+		}
+
+		if b.stop() {
+			return errBatchRolledBack
+		}
+		return nil
+	})
+
+	if errors.As(err, &taken) {
+		// The statement failed as a whole; find the culprit, or in best
+		// effort mode apply the items one at a time
+		if b.atomic {
+			for j, i := range indexes {
+				if pending[j].Email == taken.Email {
+					b.fail(i, errcode.EmailTaken, "Email is already taken", "email")
+				}
+			}
+			err = errBatchRolledBack
+		} else {
+			s.updateEach(ctx, b, indexes, pending)
+			err = nil
+		}
+	}
+
+	return s.finishBatch(b, "updated", err)
+}
+
+func (s *userService) updateEach(ctx context.Context, b *batch, indexes []int, items []*domain.BatchUpdateItem) {
+	for j, i := range indexes {
+		user, err := s.UpdateUser(ctx, items[j].ID, &items[j].UpdateUserRequest)
+
+		switch {
+		case err == nil:
+			b.results[i].User = user
+		case errors.Is(err, ErrUserNotFound):
+			b.fail(i, errcode.NotFound, err.Error(), "id")
+		case errors.Is(err, ErrEmailTaken):
+			b.fail(i, errcode.EmailTaken, "Email is already taken", "email")
+		default:
+			b.fail(i, errcode.UpdateFailed, err.Error(), "")
+		}
+	}
+}
+
+func (s *userService) BatchDeleteUsers(ctx context.Context, ids []int64, mode string) (*domain.BatchResult, error) {
+	b := newBatch(len(ids), mode)
+	seen := make(map[int64]bool, len(ids))
+	for i, id := range ids {
+		validateID(b, i, id, seen)
+	}
+	if b.stop() {
+		b.abort()
+		return b.result(), nil
+	}
+
+	indexes := b.pending()
+	pending := make([]int64, len(indexes))
+	for j, i := range indexes {
+		pending[j] = ids[i]
+	}
+
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		users, err := s.repo.DeleteMany(ctx, pending)
+		if err != nil {
+			return err
+		}
+
+		for j, user := range users {
+			i := indexes[j]
+			if user == nil {
+				b.fail(i, errcode.NotFound, ErrUserNotFound.Error(), "id")
+				continue
+			}
+			b.results[i].User = user
+			if err := s.events.Publish(ctx, domain.UserDeleted{User: user}); err != nil {
+				return err
+			}
+		}
+
+		if b.stop() {
+			return errBatchRolledBack
+		}
+		return nil
+	})
+
+	return s.finishBatch(b, "deleted", err)
+}
+
+func (s *userService) finishBatch(b *batch, action string, err error) (*domain.BatchResult, error) {
+	if err != nil && !errors.Is(err, errBatchRolledBack) {
+		s.logger.Error("failed to apply user batch", "action", action, "items", len(b.results), "error", err)
+		return nil, err
+	}
+	if err != nil {
+		b.abort()
+	}
+
+	result := b.result()
+	s.logger.Info("user batch "+action, "succeeded", result.Succeeded, "failed", result.Failed, "applied", result.Applied)
+	return result, nil
+}
+
+func validateUserFields(b *batch, i int, email, name string, required bool) {
+	b.results[i].Errors = append(b.results[i].Errors, userFieldErrors(email, name, required)...)
+}
+
+// userFieldErrors checks email and name against the users table columns
+func userFieldErrors(email, name string, required bool) []domain.ErrorDetail {
+	var errs []domain.ErrorDetail
+	for _, f := range [...]struct{ field, label, value string }{{"email", "Email", email}, {"name", "Name", name}} {
+		if required && f.value == "" {
+			errs = append(errs, errcode.ValidationError.Message("required", f.field, i18n.Args{"Field": f.field, "Label": f.label}))
+		}
+		if len(f.value) > maxFieldLength {
+			errs = append(errs, errcode.ValidationError.Message("max", f.field, i18n.Args{"Field": f.field, "Label": f.label, "Max": 255}))
+		}
+	}
+	return errs
+}
+
+func validateID(b *batch, i int, id int64, seen map[int64]bool) {
+	switch {
+	case id <= 0:
+		b.fail(i, errcode.InvalidID, "Invalid user ID", "id")
+	case seen[id]:
+		b.fail(i, errcode.DuplicateID, "User ID appears more than once in the batch", "id")
+	}
+	seen[id] = true
+}
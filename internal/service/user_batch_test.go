@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"testing"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/repository"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/eventbus"
+)
+
+// takenEmailRepository fails writes that give a user the email taken by
+// another user, and UpdateMany as a whole when any item does
+type takenEmailRepository struct {
+	repository.UserRepository
+	taken string
+}
+
+func (r *takenEmailRepository) UpdateMany(ctx context.Context, items []*domain.BatchUpdateItem) ([]*domain.User, []*domain.User, error) {
+	for _, item := range items {
+		if item.Email == r.taken {
+			return nil, nil, &repository.EmailTakenError{Email: r.taken}
+		}
+	}
+	return nil, nil, nil
+}
+
+func (r *takenEmailRepository) Update(ctx context.Context, id int64, req *domain.UpdateUserRequest) (*domain.User, *domain.User, error) {
+	switch {
+	case req.Email == r.taken:
+		return nil, nil, &repository.EmailTakenError{Email: r.taken}
+	case id > 100:
+		return nil, nil, sql.ErrNoRows
+	}
+	user := &domain.User{ID: id, Email: req.Email, Name: req.Name}
+	return user, user, nil
+}
+
+type inlineTx struct{}
+
+func (inlineTx) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type discardPublisher struct{}
+
+func (discardPublisher) Publish(context.Context, eventbus.Event) error {
+	return nil
+}
+
+func TestBestEffortBatchUpdateReportsTakenEmail(t *testing.T) {
+	repo := &takenEmailRepository{taken: "ann@example.com"}
+	s := NewUserService(repo, inlineTx{}, discardPublisher{}, slog.New(slog.DiscardHandler))
+
+	result, err := s.BatchUpdateUsers(context.Background(), []*domain.BatchUpdateItem{
+		{ID: 1, UpdateUserRequest: domain.UpdateUserRequest{Email: "jon@example.com", Name: "Jon"}},
+		{ID: 2, UpdateUserRequest: domain.UpdateUserRequest{Email: "ann@example.com", Name: "Ann"}},
+		{ID: 101, UpdateUserRequest: domain.UpdateUserRequest{Email: "bob@example.com", Name: "Bob"}},
+	}, domain.BatchBestEffort)
+	if err != nil {
+		t.Fatalf("BatchUpdateUsers: %v", err)
+	}
+
+	want := []string{"", "EMAIL_TAKEN", "NOT_FOUND"}
+	for i, code := range want {
+		var got string
+		if errs := result.Results[i].Errors; len(errs) > 0 {
+			got = errs[0].Code
+		}
+		if got != code {
+			t.Errorf("item %d: code = %q, want %q", i, got, code)
+		}
+	}
+	if result.Succeeded != 1 || result.Failed != 2 {
+		t.Errorf("got %d succeeded and %d failed, want 1 and 2", result.Succeeded, result.Failed)
+	}
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/repository"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/database"
+)
+
+const (
+	eventPageSize      = 500
+	maxPruneInterval   = time.Hour
+	catchUpTimeout     = 30 * time.Second
+	defaultEventBuffer = 64
+	// seenEventWindow is how many recent event ids are remembered to skip
+	// events seen before. Ids are assigned before their transactions
+	// commit, so events may arrive out of id order, by up to this many.
+	seenEventWindow = 1000
+)
+
+// UserEventService fans user change events out to in-process subscribers.
+// Live events arrive through Postgres NOTIFY; the durable event log is used
+// to replay events a client missed.
+type UserEventService interface {
+	// Subscribe streams events until ctx is done. With a lastEventID, events
+	// after it that are still in the log are replayed first. The channel is
+	// closed early if the subscriber falls too far behind, in which case it
+	// should resubscribe from the last event it received. Events are sent
+	// in the order they commit, which need not be id order, and none of
+	// the last seenEventWindow is sent twice.
+	Subscribe(ctx context.Context, lastEventID int64) (<-chan *domain.UserEvent, error)
+	// Run prunes events older than the retention until ctx is cancelled.
+	Run(ctx context.Context)
+}
+
+type UserEventConfig struct {
+	Retention  time.Duration
+	BufferSize int // events queued per subscriber before it is dropped
+}
+
+type userEventService struct {
+	repo   repository.UserEventRepository
+	cfg    UserEventConfig
+	logger *slog.Logger
+
+	mu          sync.Mutex
+	subscribers map[*eventSubscriber]struct{}
+	dispatched  *idWindow // recent events, to catch up after a reconnect
+}
+
+type eventSubscriber struct {
+	live chan *domain.UserEvent
+}
+
+// NewUserEventService subscribes to listener, which must be started after
+// this call.
+func NewUserEventService(repo repository.UserEventRepository, listener *database.Listener, cfg UserEventConfig, logger *slog.Logger) UserEventService {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = defaultEventBuffer
+	}
+
+	s := &userEventService{
+		repo:        repo,
+		cfg:         cfg,
+		logger:      logger,
+		subscribers: make(map[*eventSubscriber]struct{}),
+		dispatched:  newIDWindow(seenEventWindow),
+	}
+
+	listener.Subscribe(repository.UserEventChannel, s.handleNotification)
+	listener.OnReconnect(s.catchUp)
+
+	return s
+}
+
+func (s *userEventService) Subscribe(ctx context.Context, lastEventID int64) (<-chan *domain.UserEvent, error) {
+	sub := &eventSubscriber{live: make(chan *domain.UserEvent, s.cfg.BufferSize)}
+
+	// Register before reading the log so no event falls between the two
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+
+	var backlog []*domain.UserEvent
+	if lastEventID > 0 {
+		var err error
+		if backlog, err = s.backlog(ctx, lastEventID); err != nil {
+			s.unsubscribe(sub)
+			s.logger.Error("failed to replay user events", "last_event_id", lastEventID, "error", err)
+			return nil, err
+		}
+	}
+
+	out := make(chan *domain.UserEvent)
+	go func() {
+		defer close(out)
+		defer s.unsubscribe(sub)
+
+		// Events dispatched while the backlog was read are in both
+		seen := newIDWindow(seenEventWindow)
+		for _, event := range backlog {
+			select {
+			case out <- event:
+				seen.add(event.ID)
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-sub.live:
+				if !ok {
+					return
+				}
+				if !seen.add(event.ID) {
+					continue
+				}
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (s *userEventService) Run(ctx context.Context) {
+	interval := min(s.cfg.Retention, maxPruneInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.prune(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *userEventService) prune(ctx context.Context) {
+	deleted, err := s.repo.DeleteBefore(ctx, time.Now().Add(-s.cfg.Retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Warn("failed to prune user events", "error", err)
+		}
+		return
+	}
+	if deleted > 0 {
+		s.logger.Debug("pruned user events", "deleted", deleted)
+	}
+}
+
+func (s *userEventService) backlog(ctx context.Context, afterID int64) ([]*domain.UserEvent, error) {
+	var events []*domain.UserEvent
+	for {
+		page, err := s.repo.ListAfter(ctx, afterID, eventPageSize)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, page...)
+		if len(page) < eventPageSize {
+			return events, nil
+		}
+		afterID = page[len(page)-1].ID
+	}
+}
+
+func (s *userEventService) handleNotification(payload string) {
+	event := &domain.UserEvent{}
+	if err := json.Unmarshal([]byte(payload), event); err != nil {
+		s.logger.Warn("ignoring malformed user event notification", "error", err)
+		return
+	}
+	s.dispatch(event)
+}
+
+// catchUp dispatches events recorded while the listener was disconnected.
+// It reads the log from the oldest recent event dispatched, so that events
+// which committed after ones with a higher id are not missed; dispatch
+// skips those it has seen.
+func (s *userEventService) catchUp() {
+	s.mu.Lock()
+	afterID, ok := s.dispatched.min()
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), catchUpTimeout)
+		defer cancel()
+
+		events, err := s.backlog(ctx, afterID)
+		if err != nil {
+			s.logger.Error("failed to catch up on user events", "after_id", afterID, "error", err)
+			return
+		}
+		for _, event := range events {
+			s.dispatch(event)
+		}
+	}()
+}
+
+func (s *userEventService) dispatch(event *domain.UserEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dispatched.add(event.ID) {
+		return
+	}
+	for sub := range s.subscribers {
+		select {
+		case sub.live <- event:
+		default:
+			// A slow client must not hold up the others; it resumes with
+			// Last-Event-ID once its stream ends
+			delete(s.subscribers, sub)
+			close(sub.live)
+			s.logger.Warn("dropped slow user event subscriber", "event_id", event.ID)
+		}
+	}
+}
+
+func (s *userEventService) unsubscribe(sub *eventSubscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscribers[sub]; ok {
+		delete(s.subscribers, sub)
+		close(sub.live)
+	}
+}
+
+// idWindow remembers the last ids added to it, up to a fixed number
+type idWindow struct {
+	ids   map[int64]struct{}
+	order []int64 // ring of the ids, oldest at next once full
+	next  int
+}
+
+func newIDWindow(size int) *idWindow {
+	return &idWindow{
+		ids:   make(map[int64]struct{}, size),
+		order: make([]int64, 0, size),
+	}
+}
+
+// add remembers id, forgetting the oldest id if the window is full, and
+// reports whether id was new
+func (w *idWindow) add(id int64) bool {
+	if _, ok := w.ids[id]; ok {
+		return false
+	}
+	if len(w.order) < cap(w.order) {
+		w.order = append(w.order, id)
+	} else {
+		delete(w.ids, w.order[w.next])
+		w.order[w.next] = id
+		w.next = (w.next + 1) % len(w.order)
+	}
+	w.ids[id] = struct{}{}
+	return true
+}
+
+// min returns the lowest id remembered, false when there is none
+func (w *idWindow) min() (int64, bool) {
+	if len(w.order) == 0 {
+		return 0, false
+	}
+	return slices.Min(w.order), true
+}
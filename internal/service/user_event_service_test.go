@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/repository"
+)
+
+// eventLog is a UserEventRepository of events, in id order
+type eventLog struct {
+	repository.UserEventRepository
+	events []*domain.UserEvent
+}
+
+func (l *eventLog) ListAfter(ctx context.Context, afterID int64, limit int) ([]*domain.UserEvent, error) {
+	var page []*domain.UserEvent
+	for _, event := range l.events {
+		if event.ID > afterID && len(page) < limit {
+			page = append(page, event)
+		}
+	}
+	return page, nil
+}
+
+func newTestEventService(repo repository.UserEventRepository) *userEventService {
+	return &userEventService{
+		repo:        repo,
+		cfg:         UserEventConfig{BufferSize: defaultEventBuffer},
+		logger:      slog.New(slog.DiscardHandler),
+		subscribers: make(map[*eventSubscriber]struct{}),
+		dispatched:  newIDWindow(seenEventWindow),
+	}
+}
+
+// receive returns the ids of the next n events of events
+func receive(t *testing.T, events <-chan *domain.UserEvent, n int) []int64 {
+	t.Helper()
+	ids := make([]int64, 0, n)
+	for range n {
+		select {
+		case event := <-events:
+			ids = append(ids, event.ID)
+		case <-time.After(time.Second):
+			t.Fatalf("got events %v, want %d", ids, n)
+		}
+	}
+	return ids
+}
+
+func assertNoEvent(t *testing.T, events <-chan *domain.UserEvent) {
+	t.Helper()
+	select {
+	case event := <-events:
+		t.Errorf("got event %d again", event.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDispatchesEventsCommittedOutOfIDOrder(t *testing.T) {
+	s := newTestEventService(&eventLog{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := s.Subscribe(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []int64{11, 10, 11} {
+		s.dispatch(&domain.UserEvent{ID: id})
+	}
+
+	if ids := receive(t, events, 2); ids[0] != 11 || ids[1] != 10 {
+		t.Errorf("ids = %v, want [11 10]", ids)
+	}
+	assertNoEvent(t, events)
+}
+
+func TestCatchUpSendsOnlyMissedEvents(t *testing.T) {
+	log := &eventLog{}
+	for id := range int64(5) {
+		log.events = append(log.events, &domain.UserEvent{ID: 10 + id})
+	}
+	s := newTestEventService(log)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := s.Subscribe(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 10 and 13 were missed while disconnected, 10 below an event sent
+	s.dispatch(log.events[1])
+	s.dispatch(log.events[2])
+	receive(t, events, 2)
+	s.dispatch(log.events[0])
+	receive(t, events, 1)
+	s.catchUp()
+
+	if ids := receive(t, events, 2); ids[0] != 13 || ids[1] != 14 {
+		t.Errorf("ids = %v, want [13 14]", ids)
+	}
+	assertNoEvent(t, events)
+}
+
+func TestSubscribeSkipsLiveEventsOfBacklog(t *testing.T) {
+	log := &eventLog{events: []*domain.UserEvent{{ID: 10}, {ID: 12}}}
+	s := newTestEventService(log)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := s.Subscribe(ctx, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 12 was dispatched while the backlog was read; 11 commits after it
+	s.dispatch(&domain.UserEvent{ID: 12})
+	s.dispatch(&domain.UserEvent{ID: 11})
+
+	if ids := receive(t, events, 3); ids[0] != 10 || ids[1] != 12 || ids[2] != 11 {
+		t.Errorf("ids = %v, want [10 12 11]", ids)
+	}
+	assertNoEvent(t, events)
+}
+
+func TestIDWindowForgetsOldestIDs(t *testing.T) {
+	w := newIDWindow(2)
+	for _, id := range []int64{5, 3, 5} {
+		w.add(id)
+	}
+	if got, _ := w.min(); got != 3 {
+		t.Errorf("min = %d, want 3", got)
+	}
+	if !w.add(7) || !w.add(5) {
+		t.Error("ids beyond the window were remembered")
+	}
+	if w.add(7) {
+		t.Error("id in the window was added again")
+	}
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riverqueue/river"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+)
+
+type userExportArgs struct {
+	TransferID string `json:"transfer_id"`
+}
+
+func (userExportArgs) Kind() string { return "user_export" }
+
+func (userExportArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{MaxAttempts: 3}
+}
+
+type userExportWorker struct {
+	river.WorkerDefaults[userExportArgs]
+	s *userTransferService
+}
+
+func (w *userExportWorker) Work(ctx context.Context, job *river.Job[userExportArgs]) error {
+	return w.s.work(ctx, job.Args.TransferID, job.Attempt >= job.MaxAttempts, w.s.runExport)
+}
+
+func (w *userExportWorker) Timeout(*river.Job[userExportArgs]) time.Duration {
+	return w.s.cfg.JobTimeout
+}
+
+// runExport writes every user, in id order, a page at a time. Pages are
+// separate queries, so users changed during the export may appear as they
+// were before or after the change.
+func (s *userTransferService) runExport(ctx context.Context, t *domain.UserTransfer) (domain.TransferCounts, error) {
+	var counts domain.TransferCounts
+
+	err := s.writeFile(ctx, exportName(t), func(w io.Writer) error {
+		enc := newUserEncoder(w, t.Format)
+
+		var afterID int64
+		for {
+			users, err := s.users.ListAfter(ctx, afterID, s.cfg.ChunkSize)
+			if err != nil {
+				return err
+			}
+
+			for _, user := range users {
+				if err := enc.encode(user); err != nil {
+					return err
+				}
+			}
+			counts.Processed += len(users)
+
+			if len(users) < s.cfg.ChunkSize {
+				return enc.close()
+			}
+			afterID = users[len(users)-1].ID
+
+			if err := s.repo.UpdateCounts(ctx, t.ID, counts); err != nil {
+				return err
+			}
+		}
+	})
+
+	return counts, err
+}
+
+type userEncoder interface {
+	encode(user *domain.User) error
+	// close ends the file
+	close() error
+}
+
+func newUserEncoder(w io.Writer, format string) userEncoder {
+	switch format {
+	case domain.FormatNDJSON:
+		return &ndjsonEncoder{enc: json.NewEncoder(w)}
+	case domain.FormatJSON:
+		return &jsonArrayEncoder{w: w}
+	default:
+		return &csvEncoder{w: csv.NewWriter(w)}
+	}
+}
+
+var userCSVHeader = []string{"id", "email", "name", "created_at", "updated_at"}
+
+type csvEncoder struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (e *csvEncoder) encode(user *domain.User) error {
+	if !e.wroteHeader {
+		e.wroteHeader = true
+		if err := e.w.Write(userCSVHeader); err != nil {
+			return err
+		}
+	}
+	return e.w.Write([]string{
+		strconv.FormatInt(user.ID, 10),
+		escapeCell(user.Email),
+		escapeCell(user.Name),
+		user.CreatedAt.Format(time.RFC3339),
+		user.UpdatedAt.Format(time.RFC3339),
+	})
+}
+
+func (e *csvEncoder) close() error {
+	if !e.wroteHeader {
+		if err := e.w.Write(userCSVHeader); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonEncoder) encode(user *domain.User) error {
+	return e.enc.Encode(user)
+}
+
+func (e *ndjsonEncoder) close() error {
+	return nil
+}
+
+type jsonArrayEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonArrayEncoder) encode(user *domain.User) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n"
+	if e.count == 0 {
+		sep = "[\n"
+	}
+	e.count++
+
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonArrayEncoder) close() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// escapeCell keeps spreadsheet applications from evaluating a cell as a
+// formula by prefixing it with a quote; unescapeCell reverses it on import.
+func escapeCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func unescapeCell(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riverqueue/river"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/i18n"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/eventbus"
+)
+
+// maxNDJSONLine bounds the memory used for one NDJSON row
+const maxNDJSONLine = 1 << 20
+
+type userImportArgs struct {
+	TransferID string `json:"transfer_id"`
+}
+
+func (userImportArgs) Kind() string { return "user_import" }
+
+func (userImportArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{MaxAttempts: 3}
+}
+
+type userImportWorker struct {
+	river.WorkerDefaults[userImportArgs]
+	s *userTransferService
+}
+
+func (w *userImportWorker) Work(ctx context.Context, job *river.Job[userImportArgs]) error {
+	return w.s.work(ctx, job.Args.TransferID, job.Attempt >= job.MaxAttempts, w.s.runImport)
+}
+
+func (w *userImportWorker) Timeout(*river.Job[userImportArgs]) time.Duration {
+	return w.s.cfg.JobTimeout
+}
+
+// importRow is one row of an import file. Line is the line the row starts
+// on, for the error report.
+type importRow struct {
+	line   int
+	user   domain.CreateUserRequest
+	errors []domain.ErrorDetail
+}
+
+// rowReader returns io.EOF after the last row
+type rowReader interface {
+	next() (*importRow, error)
+}
+
+// runImport upserts the rows of the file in chunks, each in its own
+// transaction, and writes the rows it rejected to the error report. A chunk
+// is cut short when an email repeats, so rows apply in file order.
+func (s *userTransferService) runImport(ctx context.Context, t *domain.UserTransfer) (domain.TransferCounts, error) {
+	var counts domain.TransferCounts
+
+	src, err := s.store.Open(ctx, importSourceName(t))
+	if err != nil {
+		return counts, err
+	}
+	defer src.Close()
+
+	rows, err := newRowReader(src, t.Format, t.Options)
+	if err != nil {
+		return counts, err
+	}
+
+	// Changes are audited as the caller who started the import
+	ctx = domain.WithActor(ctx, t.RequestedBy)
+
+	err = s.writeFile(ctx, importErrorsName(t), func(w io.Writer) error {
+		report := csv.NewWriter(w)
+		if err := report.Write([]string{"line", "field", "code", "message"}); err != nil {
+			return err
+		}
+
+		chunk := make([]*importRow, 0, s.cfg.ChunkSize)
+		emails := make(map[string]bool, s.cfg.ChunkSize)
+		flush := func() error {
+			if err := s.applyChunk(ctx, t, chunk, &counts); err != nil {
+				return err
+			}
+			chunk, emails = chunk[:0], make(map[string]bool, s.cfg.ChunkSize)
+			return s.repo.UpdateCounts(ctx, t.ID, counts)
+		}
+
+		for {
+			row, err := rows.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+
+			counts.Processed++
+			if len(row.errors) == 0 {
+				row.errors = userFieldErrors(row.user.Email, row.user.Name, true)
+			}
+			if len(row.errors) > 0 {
+				counts.Failed++
+				for _, e := range row.errors {
+					if err := report.Write([]string{strconv.Itoa(row.line), e.Field, e.Code, e.Message}); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			if emails[row.user.Email] || len(chunk) == s.cfg.ChunkSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			chunk = append(chunk, row)
+			emails[row.user.Email] = true
+		}
+
+		if err := flush(); err != nil {
+			return err
+		}
+		report.Flush()
+		return report.Error()
+	})
+
+	return counts, err
+}
+
+// applyChunk upserts rows in one transaction and publishes an event for
+// every user created or changed. A dry run only counts the rows as valid.
+func (s *userTransferService) applyChunk(ctx context.Context, t *domain.UserTransfer, rows []*importRow, counts *domain.TransferCounts) error {
+	if len(rows) == 0 || t.Options.DryRun {
+		return nil
+	}
+
+	reqs := make([]*domain.CreateUserRequest, len(rows))
+	for i, row := range rows {
+		reqs[i] = &row.user
+	}
+
+	var created, updated, unchanged int
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		created, updated, unchanged = 0, 0, 0
+
+		before, after, err := s.users.UpsertMany(ctx, reqs)
+		if err != nil {
+			return err
+		}
+
+		for i := range reqs {
+			var event eventbus.Event
+			switch {
+			case after[i] == nil:
+				unchanged++
+				continue
+			case before[i] == nil:
+				created++
+				event = domain.UserCreated{User: after[i]}
+			default:
+				updated++
+				event = domain.UserUpdated{User: after[i], Changes: domain.DiffUsers(before[i], after[i])}
+			}
+			if err := s.events.Publish(ctx, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import rows from line %d: %w", rows[0].line, err)
+	}
+
+	counts.Created += created
+	counts.Updated += updated
+	counts.Unchanged += unchanged
+	return nil
+}
+
+// checkImportHeader makes sure a CSV upload has the columns the mapping
+// needs.
+func (s *userTransferService) checkImportHeader(ctx context.Context, t *domain.UserTransfer) error {
+	if t.Format != domain.FormatCSV {
+		return nil
+	}
+
+	f, err := s.store.Open(ctx, importSourceName(t))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = newRowReader(f, t.Format, t.Options)
+	return err
+}
+
+func newRowReader(r io.Reader, format string, opts domain.TransferOptions) (rowReader, error) {
+	if format == domain.FormatNDJSON {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+		return &ndjsonRows{scanner: scanner, keys: fieldSources(opts.Mapping)}, nil
+	}
+	return newCSVRows(r, opts)
+}
+
+// fieldSources returns the column or key each user field is read from
+func fieldSources(mapping map[string]string) [2]string {
+	sources := [2]string{"email", "name"}
+	for i, field := range sources {
+		if source, ok := mapping[field]; ok {
+			sources[i] = source
+		}
+	}
+	return sources
+}
+
+type csvRows struct {
+	reader  *csv.Reader
+	columns [2]int // email, name
+}
+
+func newCSVRows(r io.Reader, opts domain.TransferOptions) (*csvRows, error) {
+	delimiter, err := csvDelimiter(opts.Delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1 // short rows are reported per row
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, invalidTransfer(errcode.InvalidFile, "The file is empty", "file")
+	}
+	if err != nil {
+		return nil, invalidTransfer(errcode.InvalidFile, "Invalid CSV header: "+err.Error(), "file")
+	}
+	// Spreadsheet applications often start UTF-8 files with a byte order mark
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	rows := &csvRows{reader: reader}
+	for i, source := range fieldSources(opts.Mapping) {
+		rows.columns[i] = -1
+		for j, column := range header {
+			if strings.EqualFold(strings.TrimSpace(column), source) {
+				rows.columns[i] = j
+				break
+			}
+		}
+		if rows.columns[i] == -1 {
+			return nil, &InvalidTransferError{
+				Code:   errcode.MissingColumn,
+				Detail: errcode.MissingColumn.Message("required", "mapping", i18n.Args{"Column": source}),
+			}
+		}
+	}
+	return rows, nil
+}
+
+func (c *csvRows) next() (*importRow, error) {
+	record, err := c.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &importRow{line: parseErr.StartLine, errors: []domain.ErrorDetail{
+			errcode.InvalidRow.Detail(parseErr.Err.Error(), ""),
+		}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	line, _ := c.reader.FieldPos(0)
+	row := &importRow{line: line}
+	values := [2]string{}
+	for i, column := range c.columns {
+		if column < len(record) {
+			values[i] = unescapeCell(strings.TrimSpace(record[column]))
+		}
+	}
+	row.user = domain.CreateUserRequest{Email: values[0], Name: values[1]}
+	return row, nil
+}
+
+type ndjsonRows struct {
+	scanner *bufio.Scanner
+	keys    [2]string // email, name
+	line    int
+}
+
+func (n *ndjsonRows) next() (*importRow, error) {
+	for n.scanner.Scan() {
+		n.line++
+		data := n.scanner.Bytes()
+		if len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+
+		row := &importRow{line: n.line}
+		var object map[string]any
+		if err := json.Unmarshal(data, &object); err != nil {
+			row.errors = append(row.errors, errcode.InvalidRow.Detail("Invalid JSON object: "+err.Error(), ""))
+			return row, nil
+		}
+
+		values := [2]string{}
+		for i, key := range n.keys {
+			switch v := object[key].(type) {
+			case nil:
+			case string:
+				values[i] = strings.TrimSpace(v)
+			default:
+				field := [2]string{"email", "name"}[i]
+				row.errors = append(row.errors, errcode.InvalidValue.Detail(key+" must be a string", field))
+			}
+		}
+		row.user = domain.CreateUserRequest{Email: values[0], Name: values[1]}
+		return row, nil
+	}
+
+	if err := n.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", n.line+1, err)
+	}
+	return nil, io.EOF
+}
@@ -4,28 +4,74 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"iter"
 	"log/slog"
+	"time"
 
 	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
 	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/repository"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/eventbus"
+)
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrVersionNotFound = errors.New("version not found")
+	ErrEmailTaken      = errors.New("email is already taken")
 )
 
 type UserService interface {
 	CreateUser(ctx context.Context, req *domain.CreateUserRequest) (*domain.User, error)
-	GetUser(ctx context.Context, id int64) (*domain.User, error)
-	GetAllUsers(ctx context.Context) ([]*domain.User, error)
+	// GetUser and GetAllUsers read the fields of query, and load the
+	// related resources it expands for many users at once. GetAllUsers
+	// yields users as they are read, expanding them in batches of
+	// viewBatchSize.
+	GetUser(ctx context.Context, id int64, query domain.UserQuery) (*domain.UserView, error)
+	GetAllUsers(ctx context.Context, query domain.UserQuery) iter.Seq2[*domain.UserView, error]
+	SearchUsers(ctx context.Context, search domain.UserSearch) ([]*domain.UserSearchResult, error)
 	UpdateUser(ctx context.Context, id int64, req *domain.UpdateUserRequest) (*domain.User, error)
 	DeleteUser(ctx context.Context, id int64) error
+
+	// GetUserAsOf returns the user as it was at t. Related resources are
+	// expanded as they are now.
+	GetUserAsOf(ctx context.Context, id int64, t time.Time, query domain.UserQuery) (*domain.UserView, error)
+	GetUserHistory(ctx context.Context, id int64) ([]*domain.UserVersion, error)
+	// RevertUser restores the fields of an earlier version as a regular
+	// update, so the revert is itself versioned and audited.
+	RevertUser(ctx context.Context, id int64, version int) (*domain.User, error)
+
+	// Batch operations report a result for every item. In atomic mode a
+	// failing item rolls back the whole batch.
+	BatchCreateUsers(ctx context.Context, reqs []*domain.CreateUserRequest, mode string) (*domain.BatchResult, error)
+	BatchUpdateUsers(ctx context.Context, items []*domain.BatchUpdateItem, mode string) (*domain.BatchResult, error)
+	BatchDeleteUsers(ctx context.Context, ids []int64, mode string) (*domain.BatchResult, error)
+}
+
+// Transactor runs fn in a database transaction; *database.DB implements it
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Publisher delivers domain events; *eventbus.Bus implements it
+type Publisher interface {
+	Publish(ctx context.Context, event eventbus.Event) error
 }
 
 type userService struct {
 	repo   repository.UserRepository
+	tx     Transactor
+	events Publisher
 	logger *slog.Logger
 }
 
-func NewUserService(repo repository.UserRepository, logger *slog.Logger) UserService {
+// NewUserService publishes a domain event for every change, in the same
+// transaction as the change so that a failing synchronous subscriber rolls
+// it back.
+func NewUserService(repo repository.UserRepository, tx Transactor, events Publisher, logger *slog.Logger) UserService {
 	return &userService{
 		repo:   repo,
+		tx:     tx,
+		events: events,
 		logger: logger,
 	}
 }
@@ -35,7 +81,18 @@ func (s *userService) CreateUser(ctx context.Context, req *domain.CreateUserRequ
 		return nil, errors.New("email and name are required")
 	}
 
-	user, err := s.repo.Create(ctx, req)
+	var user *domain.User
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if user, err = s.repo.Create(ctx, req); err != nil {
+			return err
+		}
+		return s.events.Publish(ctx, domain.UserCreated{User: user})
+	})
+	var taken *repository.EmailTakenError
+	if errors.As(err, &taken) {
+		return nil, ErrEmailTaken
+	}
 	if err != nil {
 		s.logger.Error("failed to create user", "error", err)
 		return nil, err
@@ -45,34 +102,88 @@ func (s *userService) CreateUser(ctx context.Context, req *domain.CreateUserRequ
 	return user, nil
 }
 
-func (s *userService) GetUser(ctx context.Context, id int64) (*domain.User, error) {
-	user, err := s.repo.GetByID(ctx, id)
+func (s *userService) GetUser(ctx context.Context, id int64, query domain.UserQuery) (*domain.UserView, error) {
+	user, err := s.repo.GetByID(ctx, id, query.Fields)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		s.logger.Error("failed to get user", "user_id", id, "error", err)
 		return nil, err
 	}
 
-	return user, nil
+	views, err := s.view(ctx, []*domain.User{user}, query)
+	if err != nil {
+		s.logger.Error("failed to expand user", "user_id", id, "error", err)
+		return nil, err
+	}
+
+	return views[0], nil
+}
+
+func (s *userService) GetAllUsers(ctx context.Context, query domain.UserQuery) iter.Seq2[*domain.UserView, error] {
+	return func(yield func(*domain.UserView, error) bool) {
+		batch := make([]*domain.User, 0, viewBatchSize)
+
+		// yieldBatch yields the views of batch, and false once the loop
+		// has stopped
+		yieldBatch := func() bool {
+			views, err := s.view(ctx, batch, query)
+			batch = batch[:0]
+			if err != nil {
+				s.logger.Error("failed to expand users", "error", err)
+				yield(nil, err)
+				return false
+			}
+			for _, view := range views {
+				if !yield(view, nil) {
+					return false
+				}
+			}
+			return true
+		}
+
+		for user, err := range s.repo.All(ctx, query.Fields) {
+			if err != nil {
+				s.logger.Error("failed to get all users", "error", err)
+				yield(nil, err)
+				return
+			}
+			if batch = append(batch, user); len(batch) == viewBatchSize && !yieldBatch() {
+				return
+			}
+		}
+		yieldBatch()
+	}
 }
 
-func (s *userService) GetAllUsers(ctx context.Context) ([]*domain.User, error) {
-	users, err := s.repo.GetAll(ctx)
+func (s *userService) SearchUsers(ctx context.Context, search domain.UserSearch) ([]*domain.UserSearchResult, error) {
+	results, err := s.repo.Search(ctx, search)
 	if err != nil {
-		s.logger.Error("failed to get all users", "error", err)
+		s.logger.Error("failed to search users", "error", err)
 		return nil, err
 	}
 
-	return users, nil
+	return results, nil
 }
 
 func (s *userService) UpdateUser(ctx context.Context, id int64, req *domain.UpdateUserRequest) (*domain.User, error) {
-	user, err := s.repo.Update(ctx, id, req)
+	var user *domain.User
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		before, after, err := s.repo.Update(ctx, id, req)
+		if err != nil {
+			return err
+		}
+		user = after
+		return s.events.Publish(ctx, domain.UserUpdated{User: after, Changes: domain.DiffUsers(before, after)})
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
+		}
+		var taken *repository.EmailTakenError
+		if errors.As(err, &taken) {
+			return nil, ErrEmailTaken
 		}
 		s.logger.Error("failed to update user", "user_id", id, "error", err)
 		return nil, err
@@ -83,10 +194,16 @@ func (s *userService) UpdateUser(ctx context.Context, id int64, req *domain.Upda
 }
 
 func (s *userService) DeleteUser(ctx context.Context, id int64) error {
-	err := s.repo.Delete(ctx, id)
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		user, err := s.repo.Delete(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.events.Publish(ctx, domain.UserDeleted{User: user})
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return errors.New("user not found")
+			return ErrUserNotFound
 		}
 		s.logger.Error("failed to delete user", "user_id", id, "error", err)
 		return err
@@ -95,3 +212,102 @@ func (s *userService) DeleteUser(ctx context.Context, id int64) error {
 	s.logger.Info("user deleted successfully", "user_id", id)
 	return nil
 }
+
+func (s *userService) GetUserAsOf(ctx context.Context, id int64, t time.Time, query domain.UserQuery) (*domain.UserView, error) {
+	user, err := s.repo.GetAsOf(ctx, id, t)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		s.logger.Error("failed to get user as of time", "user_id", id, "as_of", t, "error", err)
+		return nil, err
+	}
+
+	views, err := s.view(ctx, []*domain.User{user}, query)
+	if err != nil {
+		s.logger.Error("failed to expand user", "user_id", id, "error", err)
+		return nil, err
+	}
+
+	return views[0], nil
+}
+
+func (s *userService) GetUserHistory(ctx context.Context, id int64) ([]*domain.UserVersion, error) {
+	versions, err := s.repo.GetHistory(ctx, id)
+	if err != nil {
+		s.logger.Error("failed to get user history", "user_id", id, "error", err)
+		return nil, err
+	}
+
+	if len(versions) == 0 {
+		return nil, ErrUserNotFound
+	}
+	return versions, nil
+}
+
+func (s *userService) RevertUser(ctx context.Context, id int64, version int) (*domain.User, error) {
+	v, err := s.repo.GetVersion(ctx, id, version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrVersionNotFound
+		}
+		s.logger.Error("failed to get user version", "user_id", id, "version", version, "error", err)
+		return nil, err
+	}
+
+	user, err := s.UpdateUser(ctx, id, &domain.UpdateUserRequest{Email: v.User.Email, Name: v.User.Name})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("user reverted successfully", "user_id", id, "version", version)
+	return user, nil
+}
+
+// viewBatchSize is how many users GetAllUsers expands at once, bounding
+// both the users it holds and the queries its expansions make. It is a
+// page of All, so that each batch is expanded once its page is read and
+// closed, rather than while the next page is open.
+const viewBatchSize = repository.AllPageSize
+
+// userExpanders load a resource of domain.UserExpansions for the users of
+// ids into the views at the same index, in one query for all of them
+var userExpanders = map[string]func(s *userService, ctx context.Context, ids []int64, views []*domain.UserView) error{
+	"history": (*userService).expandHistory,
+}
+
+// view selects the fields of query from users, which may have been read
+// with only those fields, and expands its related resources
+func (s *userService) view(ctx context.Context, users []*domain.User, query domain.UserQuery) ([]*domain.UserView, error) {
+	views := make([]*domain.UserView, len(users))
+	ids := make([]int64, len(users))
+	for i, user := range users {
+		views[i] = domain.NewUserView(user, query.Fields)
+		ids[i] = user.ID
+	}
+	if len(users) == 0 {
+		return views, nil
+	}
+
+	for _, name := range query.Expand {
+		expand, ok := userExpanders[name]
+		if !ok {
+			return nil, fmt.Errorf("users cannot expand %q", name)
+		}
+		if err := expand(s, ctx, ids, views); err != nil {
+			return nil, err
+		}
+	}
+	return views, nil
+}
+
+func (s *userService) expandHistory(ctx context.Context, ids []int64, views []*domain.UserView) error {
+	histories, err := s.repo.GetHistories(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i, id := range ids {
+		views[i].History = histories[id]
+	}
+	return nil
+}
@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/riverqueue/river"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/errcode"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/repository"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/filestore"
+)
+
+var (
+	ErrTransferNotFound = errors.New("transfer not found")
+	ErrTransferNotReady = errors.New("transfer has not completed")
+)
+
+// InvalidTransferError reports an import or export request that cannot be
+// started, such as an unknown format or a file without an email column.
+// Detail is made by Code, and names the request field at fault.
+type InvalidTransferError struct {
+	Code   errcode.Code
+	Detail domain.ErrorDetail
+}
+
+func (e *InvalidTransferError) Error() string {
+	return e.Detail.Message
+}
+
+func invalidTransfer(code errcode.Code, message, field string) error {
+	return &InvalidTransferError{Code: code, Detail: code.Detail(message, field)}
+}
+
+// UserTransferService imports and exports users as files, in background
+// jobs. Imported rows are upserted by email and go through the same domain
+// events as any other change, so they are audited and versioned.
+type UserTransferService interface {
+	// StartImport stores src and queues a job to import it. CSV files must
+	// have a header row naming the columns.
+	StartImport(ctx context.Context, src io.Reader, format string, opts domain.TransferOptions) (*domain.UserTransfer, error)
+	// StartExport queues a job that writes every user to a file.
+	StartExport(ctx context.Context, format string) (*domain.UserTransfer, error)
+	GetTransfer(ctx context.Context, kind, id string) (*domain.UserTransfer, error)
+	// OpenImportErrors returns the CSV report of the rows a completed
+	// import rejected.
+	OpenImportErrors(ctx context.Context, id string) (*domain.UserTransfer, io.ReadSeekCloser, error)
+	// OpenExport returns the file written by a completed export.
+	OpenExport(ctx context.Context, id string) (*domain.UserTransfer, io.ReadSeekCloser, error)
+	// Run deletes transfers and their files once they are older than the
+	// retention, until ctx is cancelled.
+	Run(ctx context.Context)
+}
+
+// JobEnqueuer inserts background jobs, joining the transaction in ctx;
+// *riverenqueuer.Enqueuer implements it
+type JobEnqueuer interface {
+	Enqueue(ctx context.Context, args river.JobArgs, opts *river.InsertOpts) error
+}
+
+type UserTransferConfig struct {
+	ChunkSize  int           // rows per transaction on import, per query on export
+	JobTimeout time.Duration // how long one attempt of a job may run
+	Retention  time.Duration // how long finished transfers and their files are kept
+}
+
+type userTransferService struct {
+	repo   repository.UserTransferRepository
+	users  repository.UserRepository
+	tx     Transactor
+	events Publisher
+	jobs   JobEnqueuer
+	store  filestore.Store
+	cfg    UserTransferConfig
+	logger *slog.Logger
+}
+
+// NewUserTransferService registers the import and export workers with
+// workers, which must then be used by the client that works jobs.
+func NewUserTransferService(
+	repo repository.UserTransferRepository,
+	users repository.UserRepository,
+	tx Transactor,
+	events Publisher,
+	jobs JobEnqueuer,
+	workers *river.Workers,
+	store filestore.Store,
+	cfg UserTransferConfig,
+	logger *slog.Logger,
+) UserTransferService {
+	s := &userTransferService{
+		repo:   repo,
+		users:  users,
+		tx:     tx,
+		events: events,
+		jobs:   jobs,
+		store:  store,
+		cfg:    cfg,
+		logger: logger,
+	}
+
+	river.AddWorker(workers, &userImportWorker{s: s})
+	river.AddWorker(workers, &userExportWorker{s: s})
+
+	return s
+}
+
+func (s *userTransferService) StartImport(ctx context.Context, src io.Reader, format string, opts domain.TransferOptions) (*domain.UserTransfer, error) {
+	if err := validateImportOptions(format, opts); err != nil {
+		return nil, err
+	}
+
+	t := newTransfer(ctx, domain.TransferImport, format, opts)
+	if err := s.store.Put(ctx, importSourceName(t), src); err != nil {
+		return nil, fmt.Errorf("failed to store upload: %w", err)
+	}
+
+	// Reject a file the job could not read before queuing it
+	if err := s.checkImportHeader(ctx, t); err != nil {
+		s.deleteFiles(ctx, t)
+		return nil, err
+	}
+
+	if err := s.enqueue(ctx, t, userImportArgs{TransferID: t.ID}); err != nil {
+		s.deleteFiles(ctx, t)
+		return nil, err
+	}
+
+	s.logger.Info("user import queued", "transfer_id", t.ID, "format", format, "dry_run", opts.DryRun)
+	return t, nil
+}
+
+func (s *userTransferService) StartExport(ctx context.Context, format string) (*domain.UserTransfer, error) {
+	switch format {
+	case domain.FormatCSV, domain.FormatNDJSON, domain.FormatJSON:
+	default:
+		return nil, invalidTransfer(errcode.InvalidFormat, "Format must be one of csv, ndjson or json", "format")
+	}
+
+	t := newTransfer(ctx, domain.TransferExport, format, domain.TransferOptions{})
+	if err := s.enqueue(ctx, t, userExportArgs{TransferID: t.ID}); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("user export queued", "transfer_id", t.ID, "format", format)
+	return t, nil
+}
+
+func (s *userTransferService) GetTransfer(ctx context.Context, kind, id string) (*domain.UserTransfer, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrTransferNotFound
+	}
+
+	t, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTransferNotFound
+		}
+		s.logger.Error("failed to get transfer", "transfer_id", id, "error", err)
+		return nil, err
+	}
+
+	if t.Kind != kind {
+		return nil, ErrTransferNotFound
+	}
+	return t, nil
+}
+
+func (s *userTransferService) OpenImportErrors(ctx context.Context, id string) (*domain.UserTransfer, io.ReadSeekCloser, error) {
+	return s.openResult(ctx, domain.TransferImport, id, importErrorsName)
+}
+
+func (s *userTransferService) OpenExport(ctx context.Context, id string) (*domain.UserTransfer, io.ReadSeekCloser, error) {
+	return s.openResult(ctx, domain.TransferExport, id, exportName)
+}
+
+func (s *userTransferService) openResult(ctx context.Context, kind, id string, name func(*domain.UserTransfer) string) (*domain.UserTransfer, io.ReadSeekCloser, error) {
+	t, err := s.GetTransfer(ctx, kind, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t.Status != domain.TransferCompleted {
+		return nil, nil, ErrTransferNotReady
+	}
+
+	f, err := s.store.Open(ctx, name(t))
+	if err != nil {
+		s.logger.Error("failed to open transfer file", "transfer_id", id, "error", err)
+		return nil, nil, err
+	}
+	return t, f, nil
+}
+
+func (s *userTransferService) Run(ctx context.Context) {
+	ticker := time.NewTicker(min(s.cfg.Retention, maxPruneInterval))
+	defer ticker.Stop()
+
+	for {
+		s.prune(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *userTransferService) prune(ctx context.Context) {
+	transfers, err := s.repo.DeleteFinishedBefore(ctx, time.Now().Add(-s.cfg.Retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Warn("failed to prune transfers", "error", err)
+		}
+		return
+	}
+
+	for _, t := range transfers {
+		s.deleteFiles(ctx, t)
+	}
+	if len(transfers) > 0 {
+		s.logger.Debug("pruned transfers", "deleted", len(transfers))
+	}
+}
+
+func (s *userTransferService) enqueue(ctx context.Context, t *domain.UserTransfer, args river.JobArgs) error {
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.Create(ctx, t); err != nil {
+			return err
+		}
+		return s.jobs.Enqueue(ctx, args, nil)
+	})
+	if err != nil {
+		s.logger.Error("failed to queue transfer", "kind", t.Kind, "error", err)
+		return err
+	}
+	return nil
+}
+
+// work runs one attempt of a transfer job. The transfer is only marked as
+// failed once River has no attempts left; a retry starts over.
+func (s *userTransferService) work(ctx context.Context, id string, lastAttempt bool, run func(ctx context.Context, t *domain.UserTransfer) (domain.TransferCounts, error)) error {
+	t, err := s.repo.Start(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Pruned before it ran; retrying will not help
+			return river.JobCancel(ErrTransferNotFound)
+		}
+		return err
+	}
+
+	logger := s.logger.With("transfer_id", t.ID, "kind", t.Kind)
+	counts, err := run(ctx, t)
+
+	// Record the outcome even if the attempt ran out of time
+	ctx = context.WithoutCancel(ctx)
+	if err != nil {
+		logger.Error("transfer failed", "final", lastAttempt, "error", err)
+		if !lastAttempt {
+			return err
+		}
+		if finishErr := s.repo.Finish(ctx, t.ID, domain.TransferFailed, counts, err.Error()); finishErr != nil {
+			logger.Error("failed to record transfer failure", "error", finishErr)
+		}
+		return err
+	}
+
+	if err := s.repo.Finish(ctx, t.ID, domain.TransferCompleted, counts, ""); err != nil {
+		return err
+	}
+
+	logger.Info("transfer completed", "processed", counts.Processed, "created", counts.Created,
+		"updated", counts.Updated, "unchanged", counts.Unchanged, "failed", counts.Failed)
+	return nil
+}
+
+// writeFile stores what fn writes under name. Nothing is stored if fn
+// fails.
+func (s *userTransferService) writeFile(ctx context.Context, name string, fn func(w io.Writer) error) error {
+	pr, pw := io.Pipe()
+	stored := make(chan error, 1)
+	go func() {
+		err := s.store.Put(ctx, name, pr)
+		pr.CloseWithError(err) // unblock fn if Put gave up early
+		stored <- err
+	}()
+
+	err := fn(pw)
+	pw.CloseWithError(err) // a nil error ends the file
+	if putErr := <-stored; err == nil {
+		err = putErr
+	}
+	return err
+}
+
+func (s *userTransferService) deleteFiles(ctx context.Context, t *domain.UserTransfer) {
+	names := []string{exportName(t)}
+	if t.Kind == domain.TransferImport {
+		names = []string{importSourceName(t), importErrorsName(t)}
+	}
+
+	for _, name := range names {
+		if err := s.store.Delete(ctx, name); err != nil {
+			s.logger.Warn("failed to delete transfer file", "transfer_id", t.ID, "file", name, "error", err)
+		}
+	}
+}
+
+func newTransfer(ctx context.Context, kind, format string, opts domain.TransferOptions) *domain.UserTransfer {
+	return &domain.UserTransfer{
+		ID:          uuid.New().String(),
+		Kind:        kind,
+		Status:      domain.TransferPending,
+		Format:      format,
+		Options:     opts,
+		RequestedBy: domain.ActorFrom(ctx),
+		CreatedAt:   time.Now(),
+	}
+}
+
+func validateImportOptions(format string, opts domain.TransferOptions) error {
+	switch format {
+	case domain.FormatCSV, domain.FormatNDJSON:
+	default:
+		return invalidTransfer(errcode.InvalidFormat, "Format must be csv or ndjson", "format")
+	}
+
+	for field, source := range opts.Mapping {
+		if field != "email" && field != "name" {
+			return invalidTransfer(errcode.InvalidMapping, "Mapping keys must be email or name, got "+field, "mapping")
+		}
+		if source == "" {
+			return invalidTransfer(errcode.InvalidMapping, "Mapping for "+field+" must name a column", "mapping")
+		}
+	}
+
+	if opts.Delimiter != "" {
+		if format != domain.FormatCSV {
+			return invalidTransfer(errcode.InvalidDelimiter, "A delimiter only applies to csv", "delimiter")
+		}
+		if _, err := csvDelimiter(opts.Delimiter); err != nil {
+			return invalidTransfer(errcode.InvalidDelimiter, err.Error(), "delimiter")
+		}
+	}
+	return nil
+}
+
+func csvDelimiter(s string) (rune, error) {
+	if s == "" {
+		return ',', nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, errors.New("Delimiter must be a single character other than a quote or newline")
+	}
+	return r, nil
+}
+
+// File names in the store
+func importSourceName(t *domain.UserTransfer) string {
+	return "imports/" + t.ID + "." + t.Format
+}
+
+func importErrorsName(t *domain.UserTransfer) string {
+	return "imports/" + t.ID + ".errors.csv"
+}
+
+func exportName(t *domain.UserTransfer) string {
+	return "exports/" + t.ID + "." + t.Format
+}
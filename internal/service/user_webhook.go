@@ -0,0 +1,88 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/eventbus"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/riverenqueuer"
+)
+
+// userWebhookSubscriber names the durable subscription of queued jobs, so it
+// must not change across deploys
+const userWebhookSubscriber = "user-webhook"
+
+type UserWebhookConfig struct {
+	URL     string
+	Timeout time.Duration // per delivery attempt
+}
+
+type userWebhook struct {
+	url    string
+	client *http.Client
+	logger *slog.Logger
+}
+
+// NewUserWebhook POSTs every user event to cfg.URL as a durable subscriber
+// of bridge: each delivery is a River job inserted with the change, retried
+// until the endpoint responds with a 2xx status.
+func NewUserWebhook(bridge *riverenqueuer.EventBridge, cfg UserWebhookConfig, logger *slog.Logger) error {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook URL %q is not an absolute http or https URL", cfg.URL)
+	}
+
+	w := &userWebhook{
+		url:    cfg.URL,
+		client: &http.Client{Timeout: cfg.Timeout},
+		logger: logger,
+	}
+
+	riverenqueuer.OnDurable(bridge, userWebhookSubscriber, func(ctx context.Context, e domain.UserCreated) error {
+		return w.deliver(ctx, e)
+	})
+	riverenqueuer.OnDurable(bridge, userWebhookSubscriber, func(ctx context.Context, e domain.UserUpdated) error {
+		return w.deliver(ctx, e)
+	})
+	riverenqueuer.OnDurable(bridge, userWebhookSubscriber, func(ctx context.Context, e domain.UserDeleted) error {
+		return w.deliver(ctx, e)
+	})
+
+	return nil
+}
+
+func (w *userWebhook) deliver(ctx context.Context, event eventbus.Event) error {
+	body, err := json.Marshal(map[string]any{
+		"event": event.EventName(),
+		"data":  event,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		w.logger.Warn("failed to deliver user webhook", "event", event.EventName(), "error", err)
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		w.logger.Warn("user webhook rejected", "event", event.EventName(), "status", resp.StatusCode)
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+-- Short durable log of user changes, streamed at /api/v1/users/events.
+-- Old rows are pruned by the application after the configured retention.
+CREATE TABLE IF NOT EXISTS user_events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(32) NOT NULL,
+    user_id BIGINT NOT NULL,
+    data JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_events_created_at ON user_events(created_at);
+
+-- Record every change to users and notify listeners once it commits.
+-- The notification carries the whole event so it can be fanned out
+-- without a query.
+CREATE OR REPLACE FUNCTION record_user_event() RETURNS trigger AS $$
+DECLARE
+    changed users;
+    event user_events;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+    ELSE
+        changed := NEW;
+    END IF;
+
+    INSERT INTO user_events (type, user_id, data)
+    VALUES (
+        CASE TG_OP
+            WHEN 'INSERT' THEN 'user.created'
+            WHEN 'UPDATE' THEN 'user.updated'
+            ELSE 'user.deleted'
+        END,
+        changed.id,
+        json_build_object(
+            'id', changed.id,
+            'email', changed.email,
+            'name', changed.name,
+            'created_at', changed.created_at AT TIME ZONE 'UTC',
+            'updated_at', changed.updated_at AT TIME ZONE 'UTC'
+        )
+    )
+    RETURNING * INTO event;
+
+    PERFORM pg_notify('user_events', json_build_object(
+        'id', event.id,
+        'type', event.type,
+        'user_id', event.user_id,
+        'user', event.data,
+        'created_at', event.created_at
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS users_record_event ON users;
+CREATE TRIGGER users_record_event
+    AFTER INSERT OR UPDATE OR DELETE ON users
+    FOR EACH ROW EXECUTE FUNCTION record_user_event();
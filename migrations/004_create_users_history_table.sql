@@ -0,0 +1,47 @@
+-- Every version of every user, for point-in-time reads. A version is valid
+-- from valid_from until valid_to, which is NULL for the current version and
+-- set on the last version when the user is deleted.
+CREATE TABLE IF NOT EXISTS users_history (
+    user_id BIGINT NOT NULL,
+    version INT NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to TIMESTAMPTZ,
+    PRIMARY KEY (user_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_history_valid ON users_history(user_id, valid_from);
+
+-- Versions are numbered per user. Changes to a user are serialized by its
+-- row lock, so numbering cannot race.
+CREATE OR REPLACE FUNCTION record_user_version() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE users_history SET valid_to = NOW()
+        WHERE user_id = OLD.id AND valid_to IS NULL;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO users_history (user_id, version, email, name, created_at, updated_at, valid_from)
+        SELECT NEW.id, COALESCE(MAX(version), 0) + 1, NEW.email, NEW.name, NEW.created_at, NEW.updated_at, NOW()
+        FROM users_history
+        WHERE user_id = NEW.id;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS users_record_version ON users;
+CREATE TRIGGER users_record_version
+    AFTER INSERT OR UPDATE OR DELETE ON users
+    FOR EACH ROW EXECUTE FUNCTION record_user_version();
+
+-- Existing users start at version 1 from their last update
+INSERT INTO users_history (user_id, version, email, name, created_at, updated_at, valid_from)
+SELECT id, 1, email, name, created_at, updated_at, updated_at AT TIME ZONE 'UTC'
+FROM users
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,24 @@
+-- User imports and exports worked by background jobs. Files live in the
+-- file store under names derived from the id.
+CREATE TABLE IF NOT EXISTS user_transfers (
+    id UUID PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('import', 'export')),
+    status VARCHAR(16) NOT NULL CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    format VARCHAR(16) NOT NULL,
+    options JSONB NOT NULL DEFAULT '{}',
+    processed INTEGER NOT NULL DEFAULT 0,
+    created INTEGER NOT NULL DEFAULT 0,
+    updated INTEGER NOT NULL DEFAULT 0,
+    unchanged INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    -- Who requested the transfer; imported changes are audited as them
+    actor VARCHAR(255) NOT NULL,
+    api_id VARCHAR(64) NOT NULL,
+    ip VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_transfers_created_at ON user_transfers(created_at);
@@ -0,0 +1,16 @@
+-- Search over users: full-text on name words and the parts of the email,
+-- trigram similarity on names for misspellings, and trigram-backed ILIKE
+-- for email prefixes.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- The 'simple' configuration does not stem, which suits names. Email
+-- separators are turned into spaces so "smith" finds jon.smith@example.com.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', name), 'A') ||
+        setweight(to_tsvector('simple', translate(email, '@.+_-', '     ')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_users_name_trgm ON users USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING GIN (email gin_trgm_ops);
@@ -0,0 +1,3 @@
+-- Listing users newest first reads them in pages, each continuing after
+-- the created_at and id of the last user of the previous one.
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users (created_at DESC, id DESC);
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const auditPath = "/api/v2/audit-events"
+
+// AuditClient calls the audit endpoints, which need the admin scope
+type AuditClient struct {
+	c *Client
+}
+
+// AuditListOptions filter and page audit events. Zero fields match
+// everything; PageSize is the server's default when 0.
+type AuditListOptions struct {
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   string
+	Since      time.Time
+	Until      time.Time
+	PageSize   int
+	Cursor     string
+}
+
+// ListPage returns one page of audit events, newest first
+func (a *AuditClient) ListPage(ctx context.Context, opts *AuditListOptions) (*Page[*AuditEvent], error) {
+	query := url.Values{}
+	if opts != nil {
+		for param, value := range map[string]string{
+			"actor":       opts.Actor,
+			"action":      opts.Action,
+			"entity_type": opts.EntityType,
+			"entity_id":   opts.EntityID,
+			"cursor":      opts.Cursor,
+		} {
+			if value != "" {
+				query.Set(param, value)
+			}
+		}
+		if !opts.Since.IsZero() {
+			query.Set("since", opts.Since.Format(time.RFC3339Nano))
+		}
+		if !opts.Until.IsZero() {
+			query.Set("until", opts.Until.Format(time.RFC3339Nano))
+		}
+		if opts.PageSize > 0 {
+			query.Set("limit", strconv.Itoa(opts.PageSize))
+		}
+	}
+
+	var data struct {
+		Events     []*AuditEvent `json:"audit_events"`
+		NextCursor string        `json:"next_cursor"`
+	}
+	err := a.c.do(ctx, request{method: http.MethodGet, path: auditPath, query: query}, &data)
+	if err != nil {
+		return nil, err
+	}
+	return &Page[*AuditEvent]{Items: data.Events, NextCursor: data.NextCursor}, nil
+}
+
+// List iterates over every matching audit event, newest first
+func (a *AuditClient) List(ctx context.Context, opts *AuditListOptions) iter.Seq2[*AuditEvent, error] {
+	var pageOpts AuditListOptions
+	if opts != nil {
+		pageOpts = *opts
+	}
+
+	return paginate(func(cursor string) (*Page[*AuditEvent], error) {
+		if cursor != "" {
+			pageOpts.Cursor = cursor
+		}
+		return a.ListPage(ctx, &pageOpts)
+	})
+}
+
+// Verify checks the audit hash chain for tampering
+func (a *AuditClient) Verify(ctx context.Context) (*AuditVerification, error) {
+	var data struct {
+		Verification *AuditVerification `json:"verification"`
+	}
+	err := a.c.do(ctx, request{method: http.MethodGet, path: auditPath + "/verify"}, &data)
+	return data.Verification, err
+}
@@ -0,0 +1,316 @@
+// Package client is a typed Go client for the API. It decodes the
+// StandardResponse envelope into typed results, turns error responses into
+// *Error values, pages through cursor-paginated lists with iterators and
+// retries requests the server rejected as overloaded or unavailable.
+//
+//	c, err := client.New(client.Config{BaseURL: "http://localhost:8080", APIKey: key})
+//	user, err := c.Users.Get(ctx, 42)
+//	for result, err := range c.Users.Search(ctx, "jon", nil) { ... }
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	apiIDHeader          = "X-API-ID"
+	requestIDHeader      = "X-Request-Id"
+	idempotencyKeyHeader = "Idempotency-Key"
+)
+
+// Config configures a Client. Only BaseURL is required.
+type Config struct {
+	// Server root, such as https://api.example.com
+	BaseURL string
+	// API key sent as a bearer token; requests are anonymous without one
+	APIKey string
+	// HTTPClient sends the requests, http.DefaultClient when nil. Set its
+	// Timeout, or use context deadlines, to bound requests.
+	HTTPClient *http.Client
+	Retry      RetryConfig
+	UserAgent  string
+}
+
+// RetryConfig controls retries of requests answered with 429 Too Many
+// Requests or 503 Service Unavailable, and of idempotent requests that
+// failed to reach the server. A Retry-After header sets the wait; without
+// one the wait doubles from InitialBackoff up to MaxBackoff, with jitter.
+type RetryConfig struct {
+	MaxAttempts    int // total attempts, 3 when 0; 1 disables retries
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Client calls the API. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	apiKey     string
+	httpClient *http.Client
+	retry      RetryConfig
+	userAgent  string
+
+	Users *UserClient
+	Audit *AuditClient
+}
+
+func New(cfg Config) (*Client, error) {
+	baseURL, err := url.Parse(strings.TrimSuffix(cfg.BaseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+	if baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, fmt.Errorf("client: base URL %q must be absolute", cfg.BaseURL)
+	}
+
+	retry := cfg.Retry
+	if retry.MaxAttempts <= 0 {
+		retry.MaxAttempts = 3
+	}
+	if retry.InitialBackoff <= 0 {
+		retry.InitialBackoff = 250 * time.Millisecond
+	}
+	if retry.MaxBackoff < retry.InitialBackoff {
+		retry.MaxBackoff = max(5*time.Second, retry.InitialBackoff)
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	userAgent := cfg.UserAgent
+	if userAgent == "" {
+		userAgent = "go-rest-api-boilerplate-client"
+	}
+
+	c := &Client{
+		baseURL:    baseURL,
+		apiKey:     cfg.APIKey,
+		httpClient: httpClient,
+		retry:      retry,
+		userAgent:  userAgent,
+	}
+	c.Users = &UserClient{c: c}
+	c.Audit = &AuditClient{c: c}
+	return c, nil
+}
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	idempotencyKeyKey
+)
+
+// WithRequestID sends id as the X-Request-Id of the requests made with ctx.
+// The server logs it as the request_id of each request, and nothing more:
+// the api_id of the response is still one the server generates.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// WithIdempotencyKey sets the Idempotency-Key of the POST requests made
+// with ctx. Without one, each call gets a random key, kept across its
+// retries. The server does not deduplicate requests by the key, so it
+// guarantees nothing: a POST retried after a 429 or 503 that the server
+// had applied anyway is applied twice.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey, key)
+}
+
+// request describes a call: a JSON body, or a raw body with its content
+// type. Responses are JSON unless accept says otherwise. Requests marked
+// once are never retried.
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	json        any
+	body        io.Reader
+	contentType string
+	accept      string
+	once        bool
+}
+
+// standardResponse is the API's response envelope, with data left encoded
+type standardResponse struct {
+	APIID  string          `json:"api_id"`
+	Errors []ErrorDetail   `json:"errors"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// do sends req and decodes the response's data into out. When an error
+// response carries data, out is filled as well and the *Error returned.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		var apiErr *Error
+		if out != nil && errors.As(err, &apiErr) && len(apiErr.data) > 0 {
+			if decodeErr := json.Unmarshal(apiErr.data, out); decodeErr != nil {
+				return errors.Join(err, decodeErr)
+			}
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	var envelope standardResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("client: decoding %s %s response: %w", req.method, req.path, err)
+	}
+	if out == nil || len(envelope.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("client: decoding %s %s data: %w", req.method, req.path, err)
+	}
+	return nil
+}
+
+// send sends req, retrying as configured, and returns a successful
+// response, whose body the caller must close, or the error
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	var payload []byte
+	if req.json != nil {
+		var err error
+		if payload, err = json.Marshal(req.json); err != nil {
+			return nil, fmt.Errorf("client: encoding %s %s body: %w", req.method, req.path, err)
+		}
+		req.contentType = "application/json"
+	}
+
+	// A streamed body can only be sent again if it can be rewound
+	seeker, seekable := req.body.(io.Seeker)
+	replayable := req.body == nil || seekable
+
+	var key string
+	if req.method == http.MethodPost {
+		key, _ = ctx.Value(idempotencyKeyKey).(string)
+		if key == "" {
+			key = uuid.NewString()
+		}
+	}
+
+	backoff := c.retry.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		var body io.Reader
+		switch {
+		case payload != nil:
+			body = bytes.NewReader(payload)
+		case req.body != nil:
+			if seekable && attempt > 1 {
+				if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+					return nil, err
+				}
+			}
+			// Keep the transport from closing the caller's reader
+			body = io.NopCloser(req.body)
+		}
+
+		httpReq, err := c.newRequest(ctx, req, body, key)
+		if err != nil {
+			return nil, err
+		}
+
+		last := attempt >= c.retry.MaxAttempts || !replayable || req.once
+		wait := jitter(backoff)
+
+		resp, err := c.httpClient.Do(httpReq)
+		switch {
+		case err != nil:
+			// Only requests that are safe to repeat are sent again when
+			// it is unknown whether the server received them
+			if last || ctx.Err() != nil || !idempotent(req.method) {
+				return nil, err
+			}
+		case resp.StatusCode < http.StatusBadRequest:
+			return resp, nil
+		default:
+			apiErr := decodeError(resp)
+			if last || !retryable(resp.StatusCode) {
+				return nil, apiErr
+			}
+			if apiErr.RetryAfter > 0 {
+				wait = apiErr.RetryAfter
+			}
+			// Give up now rather than wait past the deadline
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+				return nil, apiErr
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, c.retry.MaxBackoff)
+	}
+}
+
+func (c *Client) newRequest(ctx context.Context, req request, body io.Reader, idempotencyKey string) (*http.Request, error) {
+	u := c.baseURL.JoinPath(req.path)
+	u.RawQuery = req.query.Encode()
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	accept := req.accept
+	if accept == "" {
+		accept = "application/json"
+	}
+	httpReq.Header.Set("Accept", accept)
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if idempotencyKey != "" {
+		httpReq.Header.Set(idempotencyKeyHeader, idempotencyKey)
+	}
+	if id, ok := ctx.Value(requestIDKey).(string); ok && id != "" {
+		httpReq.Header.Set(requestIDHeader, id)
+	}
+	return httpReq, nil
+}
+
+// retryable reports whether the server rejected a request without handling
+// it, so that it can be sent again
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// jitter returns a duration between d/2 and d
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	return half + rand.N(half+1)
+}
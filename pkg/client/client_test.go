@@ -0,0 +1,230 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/client"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/client/clienttest"
+)
+
+// newClient returns a client of a server running handler
+func newClient(t *testing.T, handler http.HandlerFunc, cfg client.Config) *client.Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	cfg.BaseURL = srv.URL
+	cfg.HTTPClient = srv.Client()
+	c, err := client.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRetriesOverloadedRequests(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			srv := clienttest.NewServer()
+			defer srv.Close()
+			added := srv.AddUser("jon@example.com", "Jon")
+			srv.Fail(status, 0, 2)
+
+			user, err := srv.Client(client.Config{}).Users.Get(context.Background(), added.ID)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if user.Email != added.Email {
+				t.Errorf("email = %q, want %q", user.Email, added.Email)
+			}
+			if n := len(srv.Requests()); n != 3 {
+				t.Errorf("got %d requests, want 3", n)
+			}
+		})
+	}
+}
+
+func TestDoesNotRetryOtherErrors(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	srv.Fail(http.StatusInternalServerError, 0, 1)
+
+	_, err := srv.Client(client.Config{}).Users.List(context.Background())
+	if got := client.StatusCode(err); got != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500 (err %v)", got, err)
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestStopsAfterMaxAttempts(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	srv.Fail(http.StatusServiceUnavailable, 0, 3)
+
+	_, err := srv.Client(client.Config{Retry: client.RetryConfig{MaxAttempts: 2}}).Users.List(context.Background())
+	if !client.HasCode(err, "INJECTED_FAULT") {
+		t.Fatalf("err = %v, want INJECTED_FAULT", err)
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("got %d requests, want 2", n)
+	}
+}
+
+func TestRetryBackoffDoubles(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		n := len(times)
+		mu.Unlock()
+
+		if n < 4 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"api_id":"1","data":{"users":[]}}`))
+	}, client.Config{Retry: client.RetryConfig{
+		MaxAttempts:    4,
+		InitialBackoff: 40 * time.Millisecond,
+		MaxBackoff:     80 * time.Millisecond,
+	}})
+
+	if _, err := c.Users.List(context.Background()); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	// Waits are jittered between half the backoff and the backoff, which
+	// doubles up to MaxBackoff
+	minimums := []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 40 * time.Millisecond}
+	for i, minimum := range minimums {
+		if gap := times[i+1].Sub(times[i]); gap < minimum {
+			t.Errorf("wait %d = %v, want at least %v", i+1, gap, minimum)
+		}
+	}
+}
+
+func TestHonorsRetryAfter(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	srv.Fail(http.StatusTooManyRequests, time.Second, 1)
+
+	// Retry-After replaces a backoff that would outlast the deadline
+	c := srv.Client(client.Config{Retry: client.RetryConfig{InitialBackoff: time.Minute, MaxBackoff: time.Minute}})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := c.Users.List(ctx); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want the Retry-After of 1s", elapsed)
+	}
+}
+
+func TestGivesUpWhenRetryAfterPassesDeadline(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	srv.Fail(http.StatusServiceUnavailable, time.Minute, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := srv.Client(client.Config{}).Users.List(ctx)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *client.Error", err)
+	}
+	if apiErr.RetryAfter != time.Minute {
+		t.Errorf("RetryAfter = %v, want 1m", apiErr.RetryAfter)
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestIdempotencyKeyKeptAcrossRetries(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	c := srv.Client(client.Config{})
+	ctx := context.Background()
+
+	srv.Fail(http.StatusServiceUnavailable, 0, 2)
+	if _, err := c.Users.Create(ctx, client.CreateUserRequest{Email: "jon@example.com", Name: "Jon"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := c.Users.Create(ctx, client.CreateUserRequest{Email: "ann@example.com", Name: "Ann"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	requests := srv.Requests()
+	if len(requests) != 4 {
+		t.Fatalf("got %d requests, want 4", len(requests))
+	}
+	key := requests[0].Header.Get("Idempotency-Key")
+	if key == "" {
+		t.Fatal("POST sent without an Idempotency-Key")
+	}
+	for i, req := range requests[1:3] {
+		if got := req.Header.Get("Idempotency-Key"); got != key {
+			t.Errorf("retry %d sent key %q, want %q", i+1, got, key)
+		}
+	}
+	if got := requests[3].Header.Get("Idempotency-Key"); got == key {
+		t.Errorf("a second call reused key %q", key)
+	}
+}
+
+func TestWithIdempotencyKey(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	srv.Fail(http.StatusTooManyRequests, 0, 1)
+
+	ctx := client.WithIdempotencyKey(context.Background(), "create-jon")
+	if _, err := srv.Client(client.Config{}).Users.Create(ctx, client.CreateUserRequest{Email: "jon@example.com", Name: "Jon"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	for i, req := range srv.Requests() {
+		if got := req.Header.Get("Idempotency-Key"); got != "create-jon" {
+			t.Errorf("request %d sent key %q, want create-jon", i, got)
+		}
+	}
+}
+
+func TestSendsAuthAndRequestID(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	added := srv.AddUser("jon@example.com", "Jon")
+
+	ctx := client.WithRequestID(context.Background(), "upstream-api-id")
+	if _, err := srv.Client(client.Config{APIKey: "secret"}).Users.Get(ctx, added.ID); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if _, err := srv.Client(client.Config{}).Users.Get(context.Background(), added.ID); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	requests := srv.Requests()
+	if got := requests[0].Header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Authorization = %q, want Bearer secret", got)
+	}
+	if got := requests[0].Header.Get("X-Request-Id"); got != "upstream-api-id" {
+		t.Errorf("X-Request-Id = %q, want upstream-api-id", got)
+	}
+	if got := requests[1].Header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization = %q without an API key", got)
+	}
+	if got := requests[1].Header.Get("X-Request-Id"); got != "" {
+		t.Errorf("X-Request-Id = %q without one in the context", got)
+	}
+}
@@ -0,0 +1,373 @@
+// Package clienttest runs an in-memory fake of the API for tests of code
+// that uses package client. The fake serves the user endpoints from a map,
+// responds in the API's StandardResponse format, records every request and
+// can be told to fail requests, to exercise retries.
+//
+//	srv := clienttest.NewServer()
+//	defer srv.Close()
+//	srv.Fail(http.StatusServiceUnavailable, time.Second, 1)
+//	c := srv.Client(client.Config{APIKey: "test"})
+package clienttest
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/client"
+)
+
+// Request is a request received by the server
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   []byte
+}
+
+type fault struct {
+	status     int
+	retryAfter time.Duration
+}
+
+// Server is a fake API server. Its zero value is not usable; create one
+// with NewServer and Close it when done.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	users    map[int64]*domain.User
+	nextID   int64
+	faults   []fault
+	requests []Request
+}
+
+func NewServer() *Server {
+	s := &Server{
+		users:  make(map[int64]*domain.User),
+		nextID: 1,
+	}
+
+	r := chi.NewRouter()
+	r.Use(s.record, s.injectFaults)
+	r.Route("/api/v2/users", func(r chi.Router) {
+		r.Get("/", s.listUsers)
+		r.Post("/", s.createUser)
+		r.Get("/search", s.searchUsers)
+		r.Get("/{id}", s.getUser)
+		r.Put("/{id}", s.updateUser)
+		r.Delete("/{id}", s.deleteUser)
+	})
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		respondError(w, http.StatusNotFound, "NOT_FOUND", "The fake server does not implement "+r.Method+" "+r.URL.Path, "")
+	})
+
+	s.Server = httptest.NewServer(r)
+	return s
+}
+
+// Client returns a client of the server. BaseURL and HTTPClient in cfg
+// are set to the server's; retries wait at most a millisecond unless cfg
+// sets a backoff.
+func (s *Server) Client(cfg client.Config) *client.Client {
+	cfg.BaseURL = s.URL
+	cfg.HTTPClient = s.Server.Client()
+	if cfg.Retry.InitialBackoff == 0 {
+		cfg.Retry.InitialBackoff = time.Millisecond
+		cfg.Retry.MaxBackoff = time.Millisecond
+	}
+
+	c, err := client.New(cfg)
+	if err != nil {
+		// The server's URL is always valid
+		panic(err)
+	}
+	return c
+}
+
+// AddUser stores a user, as if it had been created through the API
+func (s *Server) AddUser(email, name string) *domain.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.add(email, name)
+}
+
+// Users returns the stored users in id order
+func (s *Server) Users() []domain.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sorted()
+}
+
+// Fail makes the next times requests fail with status, and a Retry-After
+// header when retryAfter is positive, before they reach the endpoints
+func (s *Server) Fail(status int, retryAfter time.Duration, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for range times {
+		s.faults = append(s.faults, fault{status: status, retryAfter: retryAfter})
+	}
+}
+
+// Requests returns every request received so far, in order
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+func (s *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Keep the body for the handler as well as the record
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.RawQuery,
+			Header: r.Header.Clone(),
+			Body:   body,
+		})
+		s.mu.Unlock()
+
+		w.Header().Set("X-API-ID", uuid.NewString())
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) injectFaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		var f *fault
+		if len(s.faults) > 0 {
+			f = &s.faults[0]
+			s.faults = s.faults[1:]
+		}
+		s.mu.Unlock()
+
+		if f == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if f.retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(max(int(f.retryAfter.Seconds()), 1)))
+		}
+		respondError(w, f.status, "INJECTED_FAULT", "Failure injected by the fake server", "")
+	})
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	users := s.sorted()
+	s.mu.Unlock()
+
+	respond(w, http.StatusOK, map[string]interface{}{"users": users})
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateUserRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Email == "" || req.Name == "" {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Email and name are required", "")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.users {
+		if user.Email == req.Email {
+			respondError(w, http.StatusConflict, "EMAIL_TAKEN", "Email is already taken", "email")
+			return
+		}
+	}
+
+	respond(w, http.StatusCreated, map[string]interface{}{"user": s.add(req.Email, req.Name)})
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	respond(w, http.StatusOK, map[string]interface{}{"user": user})
+}
+
+func (s *Server) updateUser(w http.ResponseWriter, r *http.Request) {
+	var req domain.UpdateUserRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	if req.Email != "" {
+		user.Email = req.Email
+	}
+	if req.Name != "" {
+		user.Name = req.Name
+	}
+	user.UpdatedAt = time.Now().UTC()
+
+	respond(w, http.StatusOK, map[string]interface{}{"user": user})
+}
+
+func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	delete(s.users, user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// searchUsers matches case-insensitive substrings of names and emails, in
+// id order, with the same paging parameters as the API
+func (s *Server) searchUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := strings.ToLower(strings.TrimSpace(query.Get("q")))
+	if q == "" {
+		respondError(w, http.StatusBadRequest, "INVALID_QUERY", "q is required", "q")
+		return
+	}
+
+	limit := 20
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 100 {
+			respondError(w, http.StatusBadRequest, "INVALID_LIMIT", "Limit must be between 1 and 100", "limit")
+			return
+		}
+		limit = n
+	}
+
+	var afterID int64
+	if value := query.Get("cursor"); value != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(value)
+		if err == nil {
+			afterID, err = strconv.ParseInt(string(raw), 10, 64)
+		}
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "INVALID_CURSOR", "Invalid cursor", "cursor")
+			return
+		}
+	}
+
+	s.mu.Lock()
+	results := []*domain.UserSearchResult{}
+	for _, user := range s.sorted() {
+		if user.ID <= afterID {
+			continue
+		}
+		if strings.Contains(strings.ToLower(user.Name), q) || strings.Contains(strings.ToLower(user.Email), q) {
+			results = append(results, &domain.UserSearchResult{User: user, Score: 1})
+		}
+	}
+	s.mu.Unlock()
+
+	data := map[string]interface{}{}
+	if len(results) > limit {
+		results = results[:limit]
+		last := strconv.FormatInt(results[limit-1].ID, 10)
+		data["next_cursor"] = base64.RawURLEncoding.EncodeToString([]byte(last))
+	}
+	data["users"] = results
+
+	respond(w, http.StatusOK, data)
+}
+
+// lookup finds the user of the id parameter; the caller holds s.mu
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) (*domain.User, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "INVALID_ID", "Invalid user ID", "id")
+		return nil, false
+	}
+
+	user, ok := s.users[id]
+	if !ok {
+		respondError(w, http.StatusNotFound, "NOT_FOUND", "user not found", "")
+		return nil, false
+	}
+	return user, true
+}
+
+// add stores a new user; the caller holds s.mu
+func (s *Server) add(email, name string) *domain.User {
+	now := time.Now().UTC()
+	user := &domain.User{ID: s.nextID, Email: email, Name: name, CreatedAt: now, UpdatedAt: now}
+	s.users[user.ID] = user
+	s.nextID++
+
+	copied := *user
+	return &copied
+}
+
+// sorted copies the users in id order; the caller holds s.mu
+func (s *Server) sorted() []domain.User {
+	users := make([]domain.User, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, *user)
+	}
+	slices.SortFunc(users, func(a, b domain.User) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return users
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request payload", "")
+		return false
+	}
+	return true
+}
+
+func respond(w http.ResponseWriter, code int, data interface{}) {
+	write(w, code, domain.StandardResponse{
+		APIID:  w.Header().Get("X-API-ID"),
+		Errors: []domain.ErrorDetail{},
+		Data:   data,
+	})
+}
+
+func respondError(w http.ResponseWriter, code int, errorCode, message, field string) {
+	write(w, code, domain.StandardResponse{
+		APIID:  w.Header().Get("X-API-ID"),
+		Errors: []domain.ErrorDetail{{Code: errorCode, Message: message, Field: field}},
+	})
+}
+
+func write(w http.ResponseWriter, code int, response domain.StandardResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(response)
+}
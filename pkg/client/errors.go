@@ -0,0 +1,119 @@
+package client
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error is an error response from the API. Details holds the errors of its
+// StandardResponse; most responses have exactly one.
+type Error struct {
+	StatusCode int
+	APIID      string
+	Details    []ErrorDetail
+	// RetryAfter is the wait the server asked for, if any
+	RetryAfter time.Duration
+
+	// Data of responses that carry some alongside the errors, such as the
+	// per-item results of a failed batch
+	data json.RawMessage
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "api: %d", e.StatusCode)
+	for i, d := range e.Details {
+		if i > 0 {
+			b.WriteString(";")
+		}
+		fmt.Fprintf(&b, " %s: %s", d.Code, d.Message)
+		if d.Field != "" {
+			fmt.Fprintf(&b, " (field %s)", d.Field)
+		}
+	}
+	if e.APIID != "" {
+		fmt.Fprintf(&b, " [api_id %s]", e.APIID)
+	}
+	return b.String()
+}
+
+// Code returns the code of the first error detail
+func (e *Error) Code() string {
+	if len(e.Details) == 0 {
+		return ""
+	}
+	return e.Details[0].Code
+}
+
+// HasCode reports whether err is an API error with a detail of code, such
+// as "NOT_FOUND"
+func HasCode(err error, code string) bool {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return slices.ContainsFunc(apiErr.Details, func(d ErrorDetail) bool {
+		return d.Code == code
+	})
+}
+
+// StatusCode returns the HTTP status of an API error, or 0 for other errors
+func StatusCode(err error) int {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// decodeError reads an error response and closes its body. Bodies that are
+// neither a StandardResponse nor Problem Details, such as a proxy's error
+// page, still produce an Error with the status.
+func decodeError(resp *http.Response) *Error {
+	defer resp.Body.Close()
+
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		APIID:      resp.Header.Get(apiIDHeader),
+		RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	// Servers may send Problem Details, whose errors extension carries the
+	// same details as the StandardResponse
+	var envelope struct {
+		standardResponse
+		Instance string `json:"instance"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err == nil {
+		apiErr.Details = envelope.Errors
+		apiErr.data = envelope.Data
+		if id := cmp.Or(envelope.APIID, envelope.Instance); id != "" {
+			apiErr.APIID = id
+		}
+	}
+	if len(apiErr.Details) == 0 {
+		apiErr.Details = []ErrorDetail{{Code: "HTTP_" + strconv.Itoa(resp.StatusCode), Message: http.StatusText(resp.StatusCode)}}
+	}
+	return apiErr
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/client"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/client/clienttest"
+)
+
+func TestDecodesStandardResponseErrors(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+
+	_, err := srv.Client(client.Config{}).Users.Get(context.Background(), 42)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *client.Error", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Code() != "NOT_FOUND" {
+		t.Errorf("got %d %s, want 404 NOT_FOUND", apiErr.StatusCode, apiErr.Code())
+	}
+	if !client.HasCode(err, "NOT_FOUND") || client.HasCode(err, "INVALID_ID") {
+		t.Error("HasCode does not match the details")
+	}
+
+	// The api_id identifies the request in the server's logs
+	if apiErr.APIID == "" {
+		t.Error("APIID is empty")
+	}
+}
+
+func TestDecodesProblemDetails(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{
+			"type": "/api/v1/errors/INVALID_ID",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "Invalid user ID",
+			"instance": "problem-api-id",
+			"errors": [{"code": "INVALID_ID", "message": "Invalid user ID", "field": "id"}]
+		}`))
+	}, client.Config{})
+
+	_, err := c.Users.Get(context.Background(), 1)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *client.Error", err)
+	}
+	if apiErr.Code() != "INVALID_ID" || apiErr.Details[0].Field != "id" {
+		t.Errorf("details = %+v, want INVALID_ID of field id", apiErr.Details)
+	}
+	if apiErr.APIID != "problem-api-id" {
+		t.Errorf("APIID = %q, want the instance problem-api-id", apiErr.APIID)
+	}
+}
+
+func TestDecodesErrorsWithoutEnvelope(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-API-ID", "proxied-api-id")
+		http.Error(w, "upstream unavailable", http.StatusBadGateway)
+	}, client.Config{})
+
+	_, err := c.Users.List(context.Background())
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *client.Error", err)
+	}
+	if apiErr.Code() != "HTTP_502" {
+		t.Errorf("code = %q, want HTTP_502", apiErr.Code())
+	}
+	if apiErr.APIID != "proxied-api-id" {
+		t.Errorf("APIID = %q, want the X-API-ID header", apiErr.APIID)
+	}
+}
+
+func TestDecodesDataOfErrors(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{
+			"api_id": "batch-api-id",
+			"errors": [{"code": "BATCH_FAILED", "message": "1 of 1 item failed"}],
+			"data": {"batch": {"applied": false, "failed": 1, "results": [{"index": 0, "errors": [{"code": "EMAIL_TAKEN", "message": "taken"}]}]}}
+		}`))
+	}, client.Config{})
+
+	result, err := c.Users.BatchCreate(context.Background(), []client.CreateUserRequest{{Email: "jon@example.com", Name: "Jon"}}, client.BatchAtomic)
+	if !client.HasCode(err, "BATCH_FAILED") {
+		t.Fatalf("err = %v, want BATCH_FAILED", err)
+	}
+	if result == nil || len(result.Results) != 1 || result.Results[0].Errors[0].Code != "EMAIL_TAKEN" {
+		t.Errorf("result = %+v, want the per-item results", result)
+	}
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Health is the status reported by the health endpoints. Only Ready lists
+// the dependencies it checked.
+type Health struct {
+	Status    string            `json:"status"`
+	Timestamp string            `json:"timestamp"`
+	Checks    map[string]string `json:"checks,omitempty"`
+}
+
+// Health reports whether the server process is alive
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	var health Health
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/health"}, &health); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+// Ready reports whether the server can handle traffic. It is not retried:
+// an *Error with status 503 and code SERVICE_UNAVAILABLE means it cannot.
+func (c *Client) Ready(ctx context.Context) (*Health, error) {
+	var health Health
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/readyz", once: true}, &health); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
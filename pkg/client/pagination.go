@@ -0,0 +1,37 @@
+package client
+
+import "iter"
+
+// Page is one page of a cursor-paginated list. NextCursor is empty on the
+// last page.
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+}
+
+// paginate iterates over every item of a list, fetching the pages as the
+// iteration reaches them. An error ends the iteration after being yielded.
+func paginate[T any](fetch func(cursor string) (*Page[T], error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		cursor := ""
+		for {
+			page, err := fetch(cursor)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range page.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			if page.NextCursor == "" {
+				return
+			}
+			cursor = page.NextCursor
+		}
+	}
+}
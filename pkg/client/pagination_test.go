@@ -0,0 +1,89 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/client"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/client/clienttest"
+)
+
+func TestSearchIteratesEveryPage(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	for i := range 5 {
+		srv.AddUser(fmt.Sprintf("jon%d@example.com", i), "Jon")
+	}
+	srv.AddUser("ann@example.com", "Ann")
+
+	var ids []int64
+	for result, err := range srv.Client(client.Config{}).Users.Search(context.Background(), "jon", &client.SearchOptions{PageSize: 2}) {
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		ids = append(ids, result.ID)
+	}
+
+	if fmt.Sprint(ids) != "[1 2 3 4 5]" {
+		t.Errorf("ids = %v, want [1 2 3 4 5]", ids)
+	}
+	requests := srv.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want a page of 2, 2 and 1", len(requests))
+	}
+	for i, req := range requests[1:] {
+		if req.Query == requests[0].Query {
+			t.Errorf("page %d was requested without the cursor of the previous page", i+2)
+		}
+	}
+}
+
+func TestSearchFetchesOnlyPagesReached(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	for i := range 5 {
+		srv.AddUser(fmt.Sprintf("jon%d@example.com", i), "Jon")
+	}
+
+	for _, err := range srv.Client(client.Config{}).Users.Search(context.Background(), "jon", &client.SearchOptions{PageSize: 2}) {
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		break
+	}
+
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestSearchYieldsErrorOfPage(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	for i := range 3 {
+		srv.AddUser(fmt.Sprintf("jon%d@example.com", i), "Jon")
+	}
+
+	c := srv.Client(client.Config{Retry: client.RetryConfig{MaxAttempts: 1}})
+	var results, errs int
+	for _, err := range c.Users.Search(context.Background(), "jon", &client.SearchOptions{PageSize: 2}) {
+		if err != nil {
+			errs++
+			if client.StatusCode(err) != http.StatusServiceUnavailable {
+				t.Errorf("err = %v, want 503", err)
+			}
+			continue
+		}
+		results++
+		if results == 2 {
+			// Fail the request of the second page
+			srv.Fail(http.StatusServiceUnavailable, 0, 1)
+		}
+	}
+
+	if results != 2 || errs != 1 {
+		t.Errorf("got %d results and %d errors, want 2 and 1", results, errs)
+	}
+}
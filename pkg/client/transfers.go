@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var importContentTypes = map[string]string{
+	FormatCSV:    "text/csv",
+	FormatNDJSON: "application/x-ndjson",
+}
+
+// StartImport uploads a CSV or NDJSON file of users and returns the import,
+// which a background job works. The upload is only retried when file is
+// an io.Seeker, such as an *os.File.
+func (u *UserClient) StartImport(ctx context.Context, file io.Reader, format string, opts TransferOptions) (*UserTransfer, error) {
+	query := url.Values{"format": {format}}
+	if len(opts.Mapping) > 0 {
+		mapping, err := json.Marshal(opts.Mapping)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("mapping", string(mapping))
+	}
+	if opts.Delimiter != "" {
+		query.Set("delimiter", opts.Delimiter)
+	}
+	if opts.DryRun {
+		query.Set("dry_run", "true")
+	}
+
+	contentType, ok := importContentTypes[format]
+	if !ok {
+		contentType = "application/octet-stream"
+	}
+
+	var data struct {
+		Import *UserTransfer `json:"import"`
+	}
+	req := request{
+		method:      http.MethodPost,
+		path:        usersPath + "/imports",
+		query:       query,
+		body:        file,
+		contentType: contentType,
+	}
+	err := u.c.do(ctx, req, &data)
+	return data.Import, err
+}
+
+func (u *UserClient) GetImport(ctx context.Context, id string) (*UserTransfer, error) {
+	var data struct {
+		Import *UserTransfer `json:"import"`
+	}
+	err := u.c.do(ctx, request{method: http.MethodGet, path: usersPath + "/imports/" + url.PathEscape(id)}, &data)
+	return data.Import, err
+}
+
+// ImportErrors downloads the CSV report of the rows an import rejected.
+// The caller must close it.
+func (u *UserClient) ImportErrors(ctx context.Context, id string) (io.ReadCloser, error) {
+	return u.download(ctx, usersPath+"/imports/"+url.PathEscape(id)+"/errors")
+}
+
+// StartExport writes every user to a file in format, FormatCSV when empty
+func (u *UserClient) StartExport(ctx context.Context, format string) (*UserTransfer, error) {
+	var data struct {
+		Export *UserTransfer `json:"export"`
+	}
+	req := request{
+		method: http.MethodPost,
+		path:   usersPath + "/exports",
+		json:   map[string]string{"format": format},
+	}
+	err := u.c.do(ctx, req, &data)
+	return data.Export, err
+}
+
+func (u *UserClient) GetExport(ctx context.Context, id string) (*UserTransfer, error) {
+	var data struct {
+		Export *UserTransfer `json:"export"`
+	}
+	err := u.c.do(ctx, request{method: http.MethodGet, path: usersPath + "/exports/" + url.PathEscape(id)}, &data)
+	return data.Export, err
+}
+
+// DownloadExport downloads a completed export. The caller must close it.
+func (u *UserClient) DownloadExport(ctx context.Context, id string) (io.ReadCloser, error) {
+	return u.download(ctx, usersPath+"/exports/"+url.PathEscape(id)+"/download")
+}
+
+// WaitForTransfer polls an import or export every interval until it has
+// completed or failed, and returns it in that state
+func (u *UserClient) WaitForTransfer(ctx context.Context, t *UserTransfer, interval time.Duration) (*UserTransfer, error) {
+	get := u.GetImport
+	if t.Kind == TransferExport {
+		get = u.GetExport
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for t.Status != TransferCompleted && t.Status != TransferFailed {
+		select {
+		case <-ctx.Done():
+			return t, ctx.Err()
+		case <-ticker.C:
+		}
+
+		latest, err := get(ctx, t.ID)
+		if err != nil {
+			return t, err
+		}
+		t = latest
+	}
+	return t, nil
+}
+
+func (u *UserClient) download(ctx context.Context, path string) (io.ReadCloser, error) {
+	resp, err := u.c.send(ctx, request{method: http.MethodGet, path: path, accept: "*/*"})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
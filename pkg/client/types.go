@@ -0,0 +1,48 @@
+package client
+
+import "github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+
+// The API's own types, so that callers outside this module can use them
+// without re-declaring them
+type (
+	User              = domain.User
+	CreateUserRequest = domain.CreateUserRequest
+	UpdateUserRequest = domain.UpdateUserRequest
+	UserVersion       = domain.UserVersion
+	UserSearchResult  = domain.UserSearchResult
+
+	BatchUpdateItem = domain.BatchUpdateItem
+	BatchResult     = domain.BatchResult
+	BatchItemResult = domain.BatchItemResult
+
+	UserTransfer    = domain.UserTransfer
+	TransferOptions = domain.TransferOptions
+	TransferCounts  = domain.TransferCounts
+
+	AuditEvent        = domain.AuditEvent
+	AuditVerification = domain.AuditVerification
+	FieldChange       = domain.FieldChange
+
+	ErrorDetail = domain.ErrorDetail
+)
+
+// Batch modes
+const (
+	BatchAtomic     = domain.BatchAtomic
+	BatchBestEffort = domain.BatchBestEffort
+)
+
+// Transfer kinds, formats and statuses
+const (
+	TransferImport = domain.TransferImport
+	TransferExport = domain.TransferExport
+
+	FormatCSV    = domain.FormatCSV
+	FormatNDJSON = domain.FormatNDJSON
+	FormatJSON   = domain.FormatJSON
+
+	TransferPending   = domain.TransferPending
+	TransferRunning   = domain.TransferRunning
+	TransferCompleted = domain.TransferCompleted
+	TransferFailed    = domain.TransferFailed
+)
@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/internal/domain"
+)
+
+const usersPath = "/api/v2/users"
+
+// UserClient calls the user endpoints
+type UserClient struct {
+	c *Client
+}
+
+func userPath(id int64) string {
+	return usersPath + "/" + strconv.FormatInt(id, 10)
+}
+
+func (u *UserClient) Create(ctx context.Context, req CreateUserRequest) (*User, error) {
+	var data struct {
+		User *User `json:"user"`
+	}
+	err := u.c.do(ctx, request{method: http.MethodPost, path: usersPath, json: req}, &data)
+	return data.User, err
+}
+
+func (u *UserClient) Get(ctx context.Context, id int64) (*User, error) {
+	return u.get(ctx, id, nil)
+}
+
+// GetAsOf returns the user as it was at asOf
+func (u *UserClient) GetAsOf(ctx context.Context, id int64, asOf time.Time) (*User, error) {
+	return u.get(ctx, id, url.Values{"as_of": {asOf.Format(time.RFC3339Nano)}})
+}
+
+func (u *UserClient) get(ctx context.Context, id int64, query url.Values) (*User, error) {
+	var data struct {
+		User *User `json:"user"`
+	}
+	err := u.c.do(ctx, request{method: http.MethodGet, path: userPath(id), query: query}, &data)
+	return data.User, err
+}
+
+func (u *UserClient) List(ctx context.Context) ([]*User, error) {
+	var data struct {
+		Users []*User `json:"users"`
+	}
+	err := u.c.do(ctx, request{method: http.MethodGet, path: usersPath}, &data)
+	return data.Users, err
+}
+
+func (u *UserClient) Update(ctx context.Context, id int64, req UpdateUserRequest) (*User, error) {
+	var data struct {
+		User *User `json:"user"`
+	}
+	err := u.c.do(ctx, request{method: http.MethodPut, path: userPath(id), json: req}, &data)
+	return data.User, err
+}
+
+func (u *UserClient) Delete(ctx context.Context, id int64) error {
+	return u.c.do(ctx, request{method: http.MethodDelete, path: userPath(id)}, nil)
+}
+
+// History returns every version of a user, oldest first
+func (u *UserClient) History(ctx context.Context, id int64) ([]*UserVersion, error) {
+	var data struct {
+		History []*UserVersion `json:"history"`
+	}
+	err := u.c.do(ctx, request{method: http.MethodGet, path: userPath(id) + "/history"}, &data)
+	return data.History, err
+}
+
+// Revert restores a user to an earlier version, recording a new one
+func (u *UserClient) Revert(ctx context.Context, id int64, version int) (*User, error) {
+	var data struct {
+		User *User `json:"user"`
+	}
+	req := request{
+		method: http.MethodPost,
+		path:   userPath(id) + "/revert",
+		json:   domain.RevertUserRequest{Version: version},
+	}
+	err := u.c.do(ctx, req, &data)
+	return data.User, err
+}
+
+// SearchOptions page a search. PageSize is the server's default when 0.
+type SearchOptions struct {
+	PageSize int
+	Cursor   string
+}
+
+// SearchPage returns one page of users matching query, best match first
+func (u *UserClient) SearchPage(ctx context.Context, query string, opts *SearchOptions) (*Page[*UserSearchResult], error) {
+	params := url.Values{"q": {query}}
+	if opts != nil {
+		if opts.PageSize > 0 {
+			params.Set("limit", strconv.Itoa(opts.PageSize))
+		}
+		if opts.Cursor != "" {
+			params.Set("cursor", opts.Cursor)
+		}
+	}
+
+	var data struct {
+		Users      []*UserSearchResult `json:"users"`
+		NextCursor string              `json:"next_cursor"`
+	}
+	err := u.c.do(ctx, request{method: http.MethodGet, path: usersPath + "/search", query: params}, &data)
+	if err != nil {
+		return nil, err
+	}
+	return &Page[*UserSearchResult]{Items: data.Users, NextCursor: data.NextCursor}, nil
+}
+
+// Search iterates over every user matching query, best match first,
+// starting at opts.Cursor when set
+func (u *UserClient) Search(ctx context.Context, query string, opts *SearchOptions) iter.Seq2[*UserSearchResult, error] {
+	var pageOpts SearchOptions
+	if opts != nil {
+		pageOpts = *opts
+	}
+
+	return paginate(func(cursor string) (*Page[*UserSearchResult], error) {
+		if cursor != "" {
+			pageOpts.Cursor = cursor
+		}
+		return u.SearchPage(ctx, query, &pageOpts)
+	})
+}
+
+// BatchCreate creates many users in one request. Mode is BatchAtomic or
+// BatchBestEffort, and BatchAtomic when empty. When no item was applied the
+// result is returned along with an *Error with code BATCH_FAILED.
+func (u *UserClient) BatchCreate(ctx context.Context, items []CreateUserRequest, mode string) (*BatchResult, error) {
+	return u.batch(ctx, ":batchCreate", domain.BatchRequest[CreateUserRequest]{Items: items, Mode: mode})
+}
+
+// BatchUpdate updates many users in one request, like BatchCreate
+func (u *UserClient) BatchUpdate(ctx context.Context, items []BatchUpdateItem, mode string) (*BatchResult, error) {
+	return u.batch(ctx, ":batchUpdate", domain.BatchRequest[BatchUpdateItem]{Items: items, Mode: mode})
+}
+
+// BatchDelete deletes many users in one request, like BatchCreate
+func (u *UserClient) BatchDelete(ctx context.Context, ids []int64, mode string) (*BatchResult, error) {
+	items := make([]domain.BatchDeleteItem, len(ids))
+	for i, id := range ids {
+		items[i] = domain.BatchDeleteItem{ID: id}
+	}
+	return u.batch(ctx, ":batchDelete", domain.BatchRequest[domain.BatchDeleteItem]{Items: items, Mode: mode})
+}
+
+func (u *UserClient) batch(ctx context.Context, method string, body any) (*BatchResult, error) {
+	var data struct {
+		Batch *BatchResult `json:"batch"`
+	}
+	err := u.c.do(ctx, request{method: http.MethodPost, path: usersPath + method, json: body}, &data)
+	return data.Batch, err
+}
@@ -0,0 +1,52 @@
+package codec
+
+import (
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MessagePack encodes and decodes application/msgpack. Integers are
+// written in the fewest bytes that hold them, and times with the
+// MessagePack timestamp extension.
+type MessagePack struct{}
+
+func (MessagePack) ContentType() string {
+	return "application/msgpack"
+}
+
+func (MessagePack) Encode(w io.Writer, v any) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	return enc.Encode(v)
+}
+
+func (MessagePack) Decode(r io.Reader, v any) error {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// cborEncMode writes times as RFC 3339 strings with the date/time tag, as
+// JSON writes them
+var cborEncMode, _ = cbor.EncOptions{
+	Time:    cbor.TimeRFC3339Nano,
+	TimeTag: cbor.EncTagRequired,
+}.EncMode()
+
+// CBOR encodes and decodes application/cbor (RFC 8949)
+type CBOR struct{}
+
+func (CBOR) ContentType() string {
+	return "application/cbor"
+}
+
+func (CBOR) Encode(w io.Writer, v any) error {
+	return cborEncMode.NewEncoder(w).Encode(v)
+}
+
+func (CBOR) Decode(r io.Reader, v any) error {
+	return cbor.NewDecoder(r).Decode(v)
+}
@@ -0,0 +1,228 @@
+// Package codec encodes responses and decodes requests in the media types
+// clients ask for. A Registry holds the encoders and decoders a server
+// supports and chooses between them by the Accept and Content-Type headers.
+// Every codec follows the json struct tags of the values it handles, so
+// types need no tags of their own except for XML requests.
+package codec
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Encoder writes values in a media type
+type Encoder interface {
+	// ContentType is the media type written, without parameters
+	ContentType() string
+	Encode(w io.Writer, v any) error
+}
+
+// ListEncoder is an Encoder of lists alone, such as NDJSON, which writes
+// each element of a slice on its own. Registries only choose it for
+// responses that are a list.
+type ListEncoder interface {
+	Encoder
+	// EncodeElement writes one element of a list, so that lists can be
+	// written as they are read
+	EncodeElement(w io.Writer, v any) error
+}
+
+// Decoder reads values of a media type
+type Decoder interface {
+	// ContentType is the media type read, without parameters
+	ContentType() string
+	Decode(r io.Reader, v any) error
+}
+
+// Registry holds the encoders and decoders of a server, in order of
+// preference. It must not be changed once in use.
+type Registry struct {
+	encoders []Encoder
+	decoders []Decoder
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds c as an encoder, a decoder, or both. The first encoder and
+// decoder registered are the defaults.
+func (r *Registry) Register(c any) {
+	if e, ok := c.(Encoder); ok {
+		r.encoders = append(r.encoders, e)
+	}
+	if d, ok := c.(Decoder); ok {
+		r.decoders = append(r.decoders, d)
+	}
+}
+
+// Default returns the first encoder registered
+func (r *Registry) Default() Encoder {
+	return r.encoders[0]
+}
+
+// ContentTypes returns the media types of the encoders a response may be
+// sent with: ListEncoders only when list is true
+func (r *Registry) ContentTypes(list bool) []string {
+	var types []string
+	for _, e := range r.encoders {
+		if _, ok := e.(ListEncoder); ok && !list {
+			continue
+		}
+		types = append(types, e.ContentType())
+	}
+	return types
+}
+
+// DecoderTypes returns the media types of the decoders
+func (r *Registry) DecoderTypes() []string {
+	types := make([]string, len(r.decoders))
+	for i, d := range r.decoders {
+		types[i] = d.ContentType()
+	}
+	return types
+}
+
+// Negotiate returns the encoder the Accept header values give the highest
+// quality, each taken from the most specific range matching it, with ties
+// going to the encoder registered first. ListEncoders are only offered when
+// list is true. Without an Accept header it returns the default encoder;
+// when Accept gives every encoder a quality of 0, it returns false.
+func (r *Registry) Negotiate(accept []string, list bool) (Encoder, bool) {
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return r.Default(), true
+	}
+
+	var best Encoder
+	bestQ := 0.0
+	for _, e := range r.encoders {
+		if _, ok := e.(ListEncoder); ok && !list {
+			continue
+		}
+		if q := quality(ranges, e.ContentType()); q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best, best != nil
+}
+
+// Decoder returns the decoder of a Content-Type header value: the one of
+// its media type, or of its structured syntax suffix, e.g. application/json
+// for application/merge-patch+json. An empty value gets the default
+// decoder.
+func (r *Registry) Decoder(contentType string) (Decoder, bool) {
+	if strings.TrimSpace(contentType) == "" {
+		return r.decoders[0], true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	for _, d := range r.decoders {
+		if matchRank(mediaType, d.ContentType()) >= suffixMatch {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// mediaRange is a range of an Accept header with its quality
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+func parseAccept(accept []string) []mediaRange {
+	var ranges []mediaRange
+	for _, header := range accept {
+		for _, part := range strings.Split(header, ",") {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			mediaType, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if value, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(value, 64); err != nil {
+					continue
+				}
+			}
+			ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+		}
+	}
+	return ranges
+}
+
+// quality returns the quality of the most specific range matching
+// mediaType, and 0 when none does
+func quality(ranges []mediaRange, mediaType string) float64 {
+	q, rank := 0.0, noMatch
+	for _, r := range ranges {
+		if m := matchRank(r.mediaType, mediaType); m > rank {
+			q, rank = r.q, m
+		}
+	}
+	return q
+}
+
+// How specifically a media range matches a media type
+const (
+	noMatch = iota - 1
+	anyMatch
+	typeMatch
+	suffixMatch
+	exactMatch
+)
+
+// matchRank returns how specifically mediaRange matches mediaType. Ranges
+// with a structured syntax suffix (RFC 6839) match the media type of the
+// suffix, so application/problem+json matches application/json.
+func matchRank(mediaRange, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return exactMatch
+	case mediaRange == "*/*":
+		return anyMatch
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return typeMatch
+	}
+
+	typ, subtype, _ := strings.Cut(mediaRange, "/")
+	if i := strings.LastIndexByte(subtype, '+'); i >= 0 && typ+"/"+subtype[i+1:] == mediaType {
+		return suffixMatch
+	}
+	return noMatch
+}
+
+// encodeList writes each element of list, a slice, with e
+func encodeList(e ListEncoder, w io.Writer, list any) error {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return &UnsupportedValueError{Encoder: e, Type: reflect.TypeOf(list)}
+	}
+	for i := range v.Len() {
+		if err := e.EncodeElement(w, v.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnsupportedValueError is returned by encoders given a value they cannot
+// write, such as a ListEncoder given something other than a slice
+type UnsupportedValueError struct {
+	Encoder Encoder
+	Type    reflect.Type
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return fmt.Sprintf("codec: %s cannot encode %v", e.Encoder.ContentType(), e.Type)
+}
@@ -0,0 +1,36 @@
+package codec
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSON encodes and decodes application/json
+type JSON struct{}
+
+func (JSON) ContentType() string {
+	return "application/json"
+}
+
+func (JSON) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (JSON) Decode(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// NDJSON encodes lists as newline-delimited JSON, one element per line
+type NDJSON struct{}
+
+func (NDJSON) ContentType() string {
+	return "application/x-ndjson"
+}
+
+func (e NDJSON) Encode(w io.Writer, list any) error {
+	return encodeList(e, w, list)
+}
+
+func (NDJSON) EncodeElement(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
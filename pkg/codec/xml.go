@@ -0,0 +1,107 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// XML encodes and decodes application/xml.
+//
+// Values are encoded by way of their JSON encoding, so they need no xml
+// tags and maps can be written: the root is a response element, objects
+// have an element per key, arrays an item element per element, and null
+// is an empty element. Keys that are not XML names are written as entry
+// elements with a key attribute.
+//
+// Values are decoded with encoding/xml, so types read from XML need xml
+// tags that mirror the encoding, e.g. `xml:"items>item"` for a list.
+type XML struct{}
+
+func (XML) ContentType() string {
+	return "application/xml"
+}
+
+func (XML) Encode(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if err := writeElement(enc, dec, xml.StartElement{Name: xml.Name{Local: "response"}}); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func (XML) Decode(r io.Reader, v any) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// writeElement writes the next JSON value of dec as start's element
+func writeElement(enc *xml.Encoder, dec *json.Decoder, start xml.StartElement) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		for dec.More() {
+			child := xml.StartElement{Name: xml.Name{Local: "item"}}
+			if tok == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				child = keyElement(key.(string))
+			}
+			if err := writeElement(enc, dec, child); err != nil {
+				return err
+			}
+		}
+		// The closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	case nil:
+	default:
+		if err := enc.EncodeToken(xml.CharData(fmt.Sprint(tok))); err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// xmlName matches the names keyElement writes as is: XML names without a
+// namespace prefix, not starting with the reserved xml
+var xmlName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9._-]*$`)
+
+// keyElement returns the element of an object's key
+func keyElement(key string) xml.StartElement {
+	if xmlName.MatchString(key) && !strings.HasPrefix(strings.ToLower(key), "xml") {
+		return xml.StartElement{Name: xml.Name{Local: key}}
+	}
+	return xml.StartElement{
+		Name: xml.Name{Local: "entry"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: key}},
+	}
+}
@@ -0,0 +1,266 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Querier is the subset of *sql.DB and *sql.Tx used to run statements.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// DB routes statements between a primary pool and zero or more read
+// replicas. Reads are spread round-robin across healthy replicas; writes,
+// transactions and reads from a request that has already written go to the
+// primary.
+type DB struct {
+	primary  *sql.DB
+	replicas []*replica
+	next     atomic.Uint64
+	logger   *slog.Logger
+}
+
+type replica struct {
+	name    string
+	db      *sql.DB
+	monitor *Monitor
+}
+
+type txKey struct{}
+
+// txState is the transaction started by InTx and the callbacks to run once
+// it commits.
+type txState struct {
+	tx          *sql.Tx
+	mu          sync.Mutex
+	afterCommit []func()
+}
+
+type sessionKey struct{}
+
+// session records whether a request has written to the primary.
+type session struct {
+	wrote atomic.Bool
+}
+
+func NewDB(primary *sql.DB, logger *slog.Logger) *DB {
+	return &DB{primary: primary, logger: logger}
+}
+
+// AddReplica registers a read replica whose health is checked every
+// interval once Run is started. Unhealthy replicas receive no reads.
+func (db *DB) AddReplica(name string, pool *sql.DB, interval time.Duration) {
+	logger := db.logger.With("replica", name)
+	db.replicas = append(db.replicas, &replica{
+		name:    name,
+		db:      pool,
+		monitor: NewMonitor(pool, interval, false, logger),
+	})
+}
+
+// Run checks replica health until ctx is cancelled.
+func (db *DB) Run(ctx context.Context) {
+	for _, r := range db.replicas {
+		go r.monitor.Run(ctx)
+	}
+}
+
+// Primary returns the primary pool, for callers such as migrations that
+// need a *sql.DB.
+func (db *DB) Primary() *sql.DB {
+	return db.primary
+}
+
+// Read runs fn against a replica, unless ctx is inside a transaction, the
+// request has already written, or no replica is healthy.
+func (db *DB) Read(ctx context.Context, fn func(ctx context.Context, q Querier) error) error {
+	if tx, ok := Tx(ctx); ok {
+		return fn(ctx, tx)
+	}
+	if pinned(ctx) {
+		return db.run(ctx, db.primary, true, fn)
+	}
+	return db.run(ctx, db.reader(), true, fn)
+}
+
+// Write runs fn against the primary, or the transaction in ctx, and pins
+// the rest of the request to the primary.
+func (db *DB) Write(ctx context.Context, fn func(ctx context.Context, q Querier) error) error {
+	markWrote(ctx)
+	if tx, ok := Tx(ctx); ok {
+		return fn(ctx, tx)
+	}
+	return db.run(ctx, db.primary, false, fn)
+}
+
+// InTx runs fn in a transaction on the primary. Read and Write calls made
+// with the context passed to fn join the transaction. Nested calls reuse
+// the outer transaction. The transaction is rolled back if fn returns an
+// error and committed otherwise, after which AfterCommit callbacks run.
+func (db *DB) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if InTransaction(ctx) {
+		return fn(ctx)
+	}
+
+	markWrote(ctx)
+	state := &txState{}
+	err := db.withTx(ctx, db.primary, false, func(tx *sql.Tx) error {
+		state.tx = tx
+		return fn(context.WithValue(ctx, txKey{}, state))
+	})
+	if err != nil {
+		return err
+	}
+
+	state.mu.Lock()
+	callbacks := state.afterCommit
+	state.mu.Unlock()
+	for _, callback := range callbacks {
+		callback()
+	}
+	return nil
+}
+
+// run calls fn with pool directly, or, when ctx has a deadline, inside a
+// transaction whose statement_timeout matches the time remaining so that
+// Postgres abandons the work once the caller has given up on it.
+func (db *DB) run(ctx context.Context, pool *sql.DB, readOnly bool, fn func(ctx context.Context, q Querier) error) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return fn(ctx, pool)
+	}
+
+	return db.withTx(ctx, pool, readOnly, func(tx *sql.Tx) error {
+		return fn(ctx, tx)
+	})
+}
+
+func (db *DB) withTx(ctx context.Context, pool *sql.DB, readOnly bool, fn func(tx *sql.Tx) error) error {
+	tx, err := pool.BeginTx(ctx, &sql.TxOptions{ReadOnly: readOnly})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := setStatementTimeout(ctx, tx); err != nil {
+		db.rollback(tx)
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		db.rollback(tx)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (db *DB) rollback(tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		db.logger.Error("failed to roll back transaction", "error", err)
+	}
+}
+
+// setStatementTimeout limits every statement in tx to the time left before
+// the ctx deadline. It does nothing when ctx has no deadline.
+func setStatementTimeout(ctx context.Context, tx *sql.Tx) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+
+	remaining := time.Until(deadline).Milliseconds()
+	if remaining <= 0 {
+		return context.DeadlineExceeded
+	}
+
+	_, err := tx.ExecContext(ctx, `SELECT set_config('statement_timeout', $1, true)`, strconv.FormatInt(remaining, 10))
+	return err
+}
+
+// Close closes the primary and every replica pool.
+func (db *DB) Close() error {
+	errs := []error{db.primary.Close()}
+	for _, r := range db.replicas {
+		errs = append(errs, r.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (db *DB) reader() *sql.DB {
+	n := len(db.replicas)
+	if n == 0 {
+		return db.primary
+	}
+
+	start := db.next.Add(1)
+	for i := range n {
+		r := db.replicas[(start+uint64(i))%uint64(n)]
+		if r.monitor.Healthy() {
+			return r.db
+		}
+	}
+	return db.primary
+}
+
+// WithSession starts read-your-writes tracking: once a Write or InTx call
+// is made with the returned context, later reads with it use the primary.
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+// UsePrimary pins every read made with the returned context to the primary.
+func UsePrimary(ctx context.Context) context.Context {
+	s := &session{}
+	s.wrote.Store(true)
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+func markWrote(ctx context.Context) {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		s.wrote.Store(true)
+	}
+}
+
+func pinned(ctx context.Context) bool {
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	return ok && s.wrote.Load()
+}
+
+// InTransaction reports whether ctx carries a transaction started by InTx.
+func InTransaction(ctx context.Context) bool {
+	_, ok := Tx(ctx)
+	return ok
+}
+
+// Tx returns the transaction started by InTx that ctx carries, for libraries
+// that need to join it, such as River's InsertTx.
+func Tx(ctx context.Context) (*sql.Tx, bool) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok {
+		return nil, false
+	}
+	return state.tx, true
+}
+
+// AfterCommit runs fn once the transaction in ctx commits, and not at all if
+// it rolls back. Outside a transaction fn runs immediately.
+func AfterCommit(ctx context.Context, fn func()) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok {
+		fn()
+		return
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.afterCommit = append(state.afterCommit, fn)
+}
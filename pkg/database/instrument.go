@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"log/slog"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// InstrumentConfig controls how statements are timed, logged and tagged.
+type InstrumentConfig struct {
+	// SlowThreshold logs statements taking at least this long at warn
+	// level. Zero disables slow query logging.
+	SlowThreshold time.Duration
+
+	// Comment appends the context's tags to each statement as a
+	// sqlcommenter comment, e.g. /*api_id='...',route='...'*/, so that
+	// pg_stat_activity and Postgres logs can be matched to requests.
+	Comment bool
+
+	// Tagger returns attributes of the request a statement belongs to.
+	Tagger func(ctx context.Context) map[string]string
+
+	Stats  *QueryStats
+	Logger *slog.Logger
+}
+
+// instrumenter times statements and records them in stats and logs.
+// Only the normalized SQL and the number of arguments are ever recorded,
+// never argument values.
+type instrumenter struct {
+	cfg InstrumentConfig
+}
+
+func (in *instrumenter) enabled() bool {
+	return in.cfg.Logger != nil || in.cfg.Stats != nil
+}
+
+// prepare returns the statement to send to the server and the tags of ctx.
+func (in *instrumenter) prepare(ctx context.Context, query string) (string, map[string]string) {
+	if in.cfg.Tagger == nil {
+		return query, nil
+	}
+
+	tags := in.cfg.Tagger(ctx)
+	if in.cfg.Comment && len(tags) > 0 {
+		query += " " + sqlComment(tags)
+	}
+	return query, tags
+}
+
+func (in *instrumenter) record(ctx context.Context, query string, args int, tags map[string]string, start time.Time, err error) {
+	if !in.enabled() {
+		return
+	}
+
+	elapsed := time.Since(start)
+	slow := in.cfg.SlowThreshold > 0 && elapsed >= in.cfg.SlowThreshold
+	failed := err != nil && err != driver.ErrSkip
+
+	statement := NormalizeQuery(query)
+	if in.cfg.Stats != nil {
+		in.cfg.Stats.record(statement, elapsed, failed, slow)
+	}
+
+	logger := in.cfg.Logger
+	if logger == nil {
+		return
+	}
+
+	level := slog.LevelDebug
+	message := "query executed"
+	if slow {
+		level = slog.LevelWarn
+		message = "slow query"
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("statement", statement),
+		slog.Int("args", args),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+	}
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		attrs = append(attrs, slog.String(key, tags[key]))
+	}
+	if failed {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	logger.LogAttrs(ctx, level, message, attrs...)
+}
+
+var (
+	commentPattern    = regexp.MustCompile(`(?s)/\*.*?\*/|--[^\n]*`)
+	stringPattern     = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numberPattern     = regexp.MustCompile(`(^|[^\w$])\d+(?:\.\d+)?\b`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// NormalizeQuery strips comments and literals and collapses whitespace so
+// that statements differing only in values are grouped together.
+func NormalizeQuery(query string) string {
+	query = commentPattern.ReplaceAllString(query, " ")
+	query = stringPattern.ReplaceAllString(query, "?")
+	query = numberPattern.ReplaceAllString(query, "${1}?")
+	query = whitespacePattern.ReplaceAllString(query, " ")
+	return strings.TrimSpace(query)
+}
+
+// sqlComment formats tags following the sqlcommenter specification:
+// sorted keys with URL-encoded, single-quoted values.
+func sqlComment(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		pairs = append(pairs, url.PathEscape(key)+"='"+url.PathEscape(tags[key])+"'")
+	}
+	return "/*" + strings.Join(pairs, ",") + "*/"
+}
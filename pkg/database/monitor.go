@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Monitor periodically pings the database and tracks whether it is
+// reachable. It logs every transition between up and down and runs the
+// registered recovery callbacks each time the database comes back.
+type Monitor struct {
+	db       *sql.DB
+	interval time.Duration
+	logger   *slog.Logger
+
+	healthy atomic.Bool
+
+	mu        sync.Mutex
+	onRecover []func(ctx context.Context)
+}
+
+func NewMonitor(db *sql.DB, interval time.Duration, healthy bool, logger *slog.Logger) *Monitor {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	m := &Monitor{
+		db:       db,
+		interval: interval,
+		logger:   logger,
+	}
+	m.healthy.Store(healthy)
+	return m
+}
+
+// Healthy reports whether the last check reached the database.
+func (m *Monitor) Healthy() bool {
+	return m.healthy.Load()
+}
+
+// RetryAfter is how long clients should wait before retrying while the
+// database is down.
+func (m *Monitor) RetryAfter() time.Duration {
+	return m.interval
+}
+
+// OnRecover registers fn to run every time the database becomes reachable
+// after being down.
+func (m *Monitor) OnRecover(fn func(ctx context.Context)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onRecover = append(m.onRecover, fn)
+}
+
+// Run checks the database immediately and then every interval until ctx
+// is cancelled.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	m.check(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.check(ctx)
+		}
+	}
+}
+
+func (m *Monitor) check(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, m.interval)
+	err := m.db.PingContext(pingCtx)
+	cancel()
+
+	healthy := err == nil
+	if m.healthy.Swap(healthy) == healthy {
+		return
+	}
+
+	if !healthy {
+		m.logger.Error("database connection lost", "error", err)
+		return
+	}
+
+	m.logger.Info("database connection restored")
+
+	m.mu.Lock()
+	callbacks := append([]func(context.Context){}, m.onRecover...)
+	m.mu.Unlock()
+	for _, fn := range callbacks {
+		fn(ctx)
+	}
+}
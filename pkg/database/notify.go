@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Notify sends a Postgres NOTIFY on channel. Inside a transaction the
+// notification is only delivered if the transaction commits.
+func (db *DB) Notify(ctx context.Context, channel, payload string) error {
+	return db.Write(ctx, func(ctx context.Context, q Querier) error {
+		_, err := q.ExecContext(ctx, `SELECT pg_notify($1, $2)`, channel, payload)
+		return err
+	})
+}
+
+// Listener receives Postgres notifications on a dedicated connection and
+// dispatches them to the handlers subscribed to each channel. It reconnects
+// automatically; because notifications sent while disconnected are lost,
+// reconnect handlers are called so subscribers can resynchronize.
+type Listener struct {
+	dsn    string
+	logger *slog.Logger
+
+	mu          sync.RWMutex
+	handlers    map[string][]func(payload string)
+	onReconnect []func()
+}
+
+func NewListener(dsn string, logger *slog.Logger) *Listener {
+	return &Listener{
+		dsn:      dsn,
+		logger:   logger,
+		handlers: make(map[string][]func(payload string)),
+	}
+}
+
+// Subscribe registers fn for notifications on channel. It must be called
+// before Run.
+func (l *Listener) Subscribe(channel string, fn func(payload string)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handlers[channel] = append(l.handlers[channel], fn)
+}
+
+// OnReconnect registers fn to run after the connection was re-established
+// and notifications may have been missed. It must be called before Run.
+func (l *Listener) OnReconnect(fn func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onReconnect = append(l.onReconnect, fn)
+}
+
+// Run listens on every subscribed channel until ctx is cancelled.
+func (l *Listener) Run(ctx context.Context) {
+	listener := pq.NewListener(l.dsn, time.Second, 30*time.Second, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			l.logger.Warn("notification listener disconnected", "error", err)
+		case pq.ListenerEventReconnected:
+			l.logger.Info("notification listener reconnected")
+		case pq.ListenerEventConnectionAttemptFailed:
+			l.logger.Debug("notification listener connection attempt failed", "error", err)
+		}
+	})
+	defer listener.Close()
+
+	l.mu.RLock()
+	channels := make([]string, 0, len(l.handlers))
+	for channel := range l.handlers {
+		channels = append(channels, channel)
+	}
+	l.mu.RUnlock()
+
+	// Listen blocks until the first connection succeeds, so issue it in the
+	// background to let Run return promptly on shutdown
+	go func() {
+		for _, channel := range channels {
+			if err := listener.Listen(channel); err != nil {
+				l.logger.Error("failed to listen for notifications", "channel", channel, "error", err)
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			l.dispatch(n)
+		}
+	}
+}
+
+func (l *Listener) dispatch(n *pq.Notification) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	// pq sends nil after reconnecting
+	if n == nil {
+		for _, fn := range l.onReconnect {
+			fn()
+		}
+		return
+	}
+
+	for _, fn := range l.handlers[n.Channel] {
+		fn(n.Extra)
+	}
+}
@@ -1,11 +1,13 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type DBConfig struct {
@@ -13,21 +15,45 @@ type DBConfig struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	Retry           RetryConfig
+	Instrument      InstrumentConfig
 }
 
-func NewPostgresConnection(cfg DBConfig) (*sql.DB, error) {
-	db, err := sql.Open("postgres", cfg.DSN)
+// NewPostgresConnection opens a connection pool and waits for the database
+// to accept connections, retrying according to cfg.Retry.
+func NewPostgresConnection(ctx context.Context, cfg DBConfig, logger *slog.Logger) (*sql.DB, error) {
+	db, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := PingWithRetry(ctx, db, cfg.Retry, logger); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Open configures a connection pool without contacting the database.
+// Connections are established lazily on first use, and every statement
+// they run is instrumented according to cfg.Instrument.
+func Open(cfg DBConfig) (*sql.DB, error) {
+	connector, err := pq.NewConnector(cfg.DSN)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
 
+	db := sql.OpenDB(&instrumentedConnector{
+		base: connector,
+		in:   &instrumenter{cfg: cfg.Instrument},
+	})
+
 	db.SetMaxOpenConns(cfg.MaxOpenConns)
 	db.SetMaxIdleConns(cfg.MaxIdleConns)
 	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
-
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
-	}
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	return db, nil
 }
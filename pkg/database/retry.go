@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryConfig controls how often and how patiently a connection is retried.
+type RetryConfig struct {
+	MaxAttempts    int // 0 retries until the context is cancelled
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// PingWithRetry pings db until it succeeds, the attempts are exhausted or
+// ctx is done, waiting between attempts as Retry does.
+func PingWithRetry(ctx context.Context, db *sql.DB, cfg RetryConfig, logger *slog.Logger) error {
+	if err := Retry(ctx, cfg, "database not ready", logger, db.PingContext); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
+
+// Retry calls fn until it succeeds, the attempts are exhausted or ctx is
+// done, logging each failure with msg. The wait between attempts doubles
+// each time up to MaxBackoff, with jitter so that many instances do not
+// retry in lockstep.
+func Retry(ctx context.Context, cfg RetryConfig, msg string, logger *slog.Logger, fn func(ctx context.Context) error) error {
+	backoff := cfg.InitialBackoff
+	if backoff <= 0 {
+		backoff = 500 * time.Millisecond
+	}
+	maxBackoff := cfg.MaxBackoff
+	if maxBackoff < backoff {
+		maxBackoff = backoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if cfg.MaxAttempts > 0 && attempt >= cfg.MaxAttempts {
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+
+		wait := jitter(backoff)
+		logger.Warn(msg+", retrying",
+			"attempt", attempt,
+			"retry_in", wait.String(),
+			"error", err,
+		)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// jitter returns a duration between d/2 and d.
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	return half + rand.N(half+1)
+}
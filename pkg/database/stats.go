@@ -0,0 +1,71 @@
+package database
+
+import (
+	"sync"
+	"time"
+)
+
+// maxTrackedStatements bounds the memory used by QueryStats. Statements
+// beyond the limit are aggregated under otherStatement.
+const (
+	maxTrackedStatements = 500
+	otherStatement       = "other"
+)
+
+// QueryStats aggregates per-statement timings. It is safe for concurrent use.
+type QueryStats struct {
+	mu         sync.Mutex
+	statements map[string]*StatementStats
+}
+
+// StatementStats are the counters for one normalized statement.
+type StatementStats struct {
+	Calls   int64   `json:"calls"`
+	Errors  int64   `json:"errors"`
+	Slow    int64   `json:"slow"`
+	TotalMs float64 `json:"total_ms"`
+	MaxMs   float64 `json:"max_ms"`
+}
+
+func NewQueryStats() *QueryStats {
+	return &QueryStats{statements: make(map[string]*StatementStats)}
+}
+
+// Snapshot returns a copy of the counters keyed by normalized statement.
+func (s *QueryStats) Snapshot() map[string]StatementStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := make(map[string]StatementStats, len(s.statements))
+	for statement, stats := range s.statements {
+		snapshot[statement] = *stats
+	}
+	return snapshot
+}
+
+func (s *QueryStats) record(statement string, elapsed time.Duration, failed, slow bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.statements[statement]
+	if !ok {
+		if len(s.statements) >= maxTrackedStatements {
+			statement = otherStatement
+		}
+		if stats, ok = s.statements[statement]; !ok {
+			stats = &StatementStats{}
+			s.statements[statement] = stats
+		}
+	}
+
+	ms := float64(elapsed.Microseconds()) / 1000
+	stats.Calls++
+	stats.TotalMs += ms
+	stats.MaxMs = max(stats.MaxMs, ms)
+	if failed {
+		stats.Errors++
+	}
+	if slow {
+		stats.Slow++
+	}
+}
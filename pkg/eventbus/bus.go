@@ -0,0 +1,208 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+const defaultQueueSize = 256
+
+// Event is a domain event. Its name identifies the event type to subscribers.
+type Event interface {
+	EventName() string
+}
+
+// Handler reacts to a published event.
+type Handler func(ctx context.Context, event Event) error
+
+type Config struct {
+	// Schedule decides when events are handed to asynchronous subscribers.
+	// By default they are queued as soon as they are published; use
+	// database.AfterCommit so they only see committed changes.
+	Schedule func(ctx context.Context, fn func())
+
+	// QueueSize is the number of events each asynchronous subscriber can
+	// fall behind before Publish blocks.
+	QueueSize int
+
+	Logger *slog.Logger
+}
+
+// Bus delivers published events to subscribers in-process. Synchronous
+// subscribers run inside Publish, so their errors fail the publisher (and
+// roll back its transaction, if any). Asynchronous subscribers run on their
+// own goroutine, in publish order; their errors are only logged.
+type Bus struct {
+	cfg Config
+
+	mu     sync.RWMutex
+	sync   map[string][]Handler
+	async  map[string][]*asyncSubscriber
+	closed bool // queues are closed
+	wg     sync.WaitGroup
+}
+
+type asyncSubscriber struct {
+	name    string
+	handler Handler
+	queue   chan queuedEvent
+}
+
+type queuedEvent struct {
+	ctx   context.Context
+	event Event
+}
+
+func New(cfg Config) *Bus {
+	if cfg.Schedule == nil {
+		cfg.Schedule = func(_ context.Context, fn func()) { fn() }
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	return &Bus{
+		cfg:   cfg,
+		sync:  make(map[string][]Handler),
+		async: make(map[string][]*asyncSubscriber),
+	}
+}
+
+// Subscribe runs handler inside Publish for every event with the given name.
+func (b *Bus) Subscribe(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sync[name] = append(b.sync[name], handler)
+}
+
+// SubscribeAsync runs handler on a dedicated goroutine for every event with
+// the given name. The subscriber name identifies it in logs.
+func (b *Bus) SubscribeAsync(name, subscriber string, handler Handler) {
+	sub := &asyncSubscriber{
+		name:    subscriber,
+		handler: handler,
+		queue:   make(chan queuedEvent, b.cfg.QueueSize),
+	}
+
+	b.mu.Lock()
+	b.async[name] = append(b.async[name], sub)
+	b.mu.Unlock()
+
+	b.wg.Add(1)
+	go b.work(sub)
+}
+
+// Publish delivers event to its synchronous subscribers, stopping at the
+// first error, and then schedules it for its asynchronous subscribers.
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	name := event.EventName()
+
+	b.mu.RLock()
+	handlers := b.sync[name]
+	subscribers := b.async[name]
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			return fmt.Errorf("%s subscriber failed: %w", name, err)
+		}
+	}
+
+	if len(subscribers) > 0 {
+		// Asynchronous work outlives the request but keeps its values
+		asyncCtx := context.WithoutCancel(ctx)
+		b.cfg.Schedule(ctx, func() {
+			// The callback may run after Close, such as when a transaction
+			// commits during shutdown
+			b.mu.RLock()
+			defer b.mu.RUnlock()
+			if b.closed {
+				b.cfg.Logger.Warn("dropped event scheduled after the event bus closed", "event", name)
+				return
+			}
+			for _, sub := range subscribers {
+				sub.queue <- queuedEvent{ctx: asyncCtx, event: event}
+			}
+		})
+	}
+
+	return nil
+}
+
+// Close stops accepting asynchronous work and waits for queued events to be
+// handled, or for ctx to be done. Events scheduled for asynchronous
+// subscribers afterwards are dropped and logged.
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for _, subscribers := range b.async {
+			for _, sub := range subscribers {
+				close(sub.queue)
+			}
+		}
+		b.async = make(map[string][]*asyncSubscriber)
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.New("event bus closed with events still queued")
+	}
+}
+
+func (b *Bus) work(sub *asyncSubscriber) {
+	defer b.wg.Done()
+
+	for queued := range sub.queue {
+		b.handleAsync(sub, queued)
+	}
+}
+
+func (b *Bus) handleAsync(sub *asyncSubscriber, queued queuedEvent) {
+	defer func() {
+		if p := recover(); p != nil {
+			b.cfg.Logger.Error("event subscriber panicked", "subscriber", sub.name, "event", queued.event.EventName(), "panic", p)
+		}
+	}()
+
+	if err := sub.handler(queued.ctx, queued.event); err != nil {
+		b.cfg.Logger.Error("event subscriber failed", "subscriber", sub.name, "event", queued.event.EventName(), "error", err)
+	}
+}
+
+// On subscribes a handler for events of type E synchronously.
+func On[E Event](b *Bus, handler func(ctx context.Context, event E) error) {
+	var zero E
+	b.Subscribe(zero.EventName(), typed(handler))
+}
+
+// OnAsync subscribes a handler for events of type E asynchronously.
+func OnAsync[E Event](b *Bus, subscriber string, handler func(ctx context.Context, event E) error) {
+	var zero E
+	b.SubscribeAsync(zero.EventName(), subscriber, typed(handler))
+}
+
+func typed[E Event](handler func(ctx context.Context, event E) error) Handler {
+	return func(ctx context.Context, event Event) error {
+		e, ok := event.(E)
+		if !ok {
+			return fmt.Errorf("unexpected event type %T for %s", event, event.EventName())
+		}
+		return handler(ctx, e)
+	}
+}
@@ -0,0 +1,42 @@
+package eventbus
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+type testEvent struct{}
+
+func (testEvent) EventName() string { return "test" }
+
+func TestScheduledAfterCloseIsDropped(t *testing.T) {
+	var scheduled []func()
+	b := New(Config{
+		Schedule: func(_ context.Context, fn func()) { scheduled = append(scheduled, fn) },
+		Logger:   slog.New(slog.DiscardHandler),
+	})
+	handled := 0
+	b.SubscribeAsync("test", "counter", func(context.Context, Event) error {
+		handled++
+		return nil
+	})
+
+	if err := b.Publish(context.Background(), testEvent{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// Like an AfterCommit callback of a transaction still open at shutdown
+	for _, fn := range scheduled {
+		fn()
+	}
+
+	if handled != 0 {
+		t.Errorf("handled %d events after Close, want 0", handled)
+	}
+	if err := b.Close(context.Background()); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Store keeps files that outlive a request, such as uploads waiting for a
+// background job and the files those jobs produce. Names are slash-separated
+// paths chosen by the application, e.g. "imports/<id>.csv".
+type Store interface {
+	// Put stores everything read from r under name, replacing any existing
+	// file. Nothing is stored if reading r fails.
+	Put(ctx context.Context, name string, r io.Reader) error
+	// Open returns the file stored under name. The error wraps
+	// fs.ErrNotExist when there is none.
+	Open(ctx context.Context, name string) (io.ReadSeekCloser, error)
+	// Delete removes the file stored under name. Deleting a missing file is
+	// not an error.
+	Delete(ctx context.Context, name string) error
+}
+
+// Dir stores files in a local directory. Instances that share files, for
+// example an API server and a separate job worker, need the directory on a
+// shared volume.
+type Dir struct {
+	root string
+}
+
+// NewDir creates root if needed and returns a store backed by it.
+func NewDir(root string) (*Dir, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create file store directory: %w", err)
+	}
+	return &Dir{root: root}, nil
+}
+
+// Put writes to a temporary file that is renamed into place once r is fully
+// read, so readers never see a partial file.
+func (d *Dir) Put(ctx context.Context, name string, r io.Reader) error {
+	path, err := d.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".put-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := io.Copy(tmp, contextReader{ctx: ctx, r: r}); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (d *Dir) Open(ctx context.Context, name string) (io.ReadSeekCloser, error) {
+	path, err := d.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (d *Dir) Delete(ctx context.Context, name string) error {
+	path, err := d.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path maps name into the root, rejecting names that would escape it.
+func (d *Dir) path(name string) (string, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return "", fmt.Errorf("invalid file name %q", name)
+	}
+	return filepath.Join(d.root, filepath.FromSlash(name)), nil
+}
+
+// contextReader stops a copy once ctx is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
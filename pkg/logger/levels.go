@@ -0,0 +1,259 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Well-known component names used by the application layers.
+const (
+	ComponentHandler    = "handler"
+	ComponentService    = "service"
+	ComponentRepository = "repository"
+	ComponentRiver      = "river"
+)
+
+// Levels holds the root log level and per-component overrides. All methods
+// are safe for concurrent use and take effect immediately on every logger
+// derived from the owning Logger.
+type Levels struct {
+	root *slog.LevelVar
+
+	mu         sync.Mutex
+	components map[string]*componentLevel
+	reverts    map[string]*pendingRevert
+}
+
+// ComponentLevel describes the effective level of a single component.
+type ComponentLevel struct {
+	Level      string     `json:"level"`
+	Overridden bool       `json:"overridden"`
+	RevertAt   *time.Time `json:"revert_at,omitempty"`
+}
+
+// LevelsSnapshot is a point-in-time view of all configured levels.
+type LevelsSnapshot struct {
+	Root       string                    `json:"root"`
+	RootRevert *time.Time                `json:"root_revert_at,omitempty"`
+	Components map[string]ComponentLevel `json:"components"`
+}
+
+type componentLevel struct {
+	root     *slog.LevelVar
+	override atomic.Pointer[slog.Level]
+}
+
+// pendingRevert remembers the state to restore when a temporary level
+// change expires.
+type pendingRevert struct {
+	timer    *time.Timer
+	at       time.Time
+	previous *slog.Level
+}
+
+func NewLevels(root slog.Level) *Levels {
+	l := &Levels{
+		root:       new(slog.LevelVar),
+		components: make(map[string]*componentLevel),
+		reverts:    make(map[string]*pendingRevert),
+	}
+	l.root.Set(root)
+
+	for _, name := range []string{ComponentHandler, ComponentService, ComponentRepository, ComponentRiver} {
+		l.components[name] = &componentLevel{root: l.root}
+	}
+	return l
+}
+
+// Set changes the level of a component, or the root level when component
+// is empty. A positive ttl reverts the change automatically once it expires.
+func (l *Levels) Set(component string, level slog.Level, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.scheduleRevert(component, ttl)
+	l.setLocked(component, &level)
+}
+
+// Reset removes a component's override so it follows the root level again.
+func (l *Levels) Reset(component string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cancelRevert(component)
+	l.setLocked(component, nil)
+}
+
+// Apply replaces all levels with the given configuration, discarding any
+// overrides and pending reverts. It is used when configuration is reloaded.
+func (l *Levels) Apply(root string, components map[string]string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for name := range l.reverts {
+		l.cancelRevert(name)
+	}
+
+	l.root.Set(ParseLevel(root))
+	for name := range l.components {
+		l.setLocked(name, nil)
+	}
+	for name, level := range components {
+		parsed := ParseLevel(level)
+		l.setLocked(name, &parsed)
+	}
+}
+
+// Components returns the names of the components loggers have been
+// created or configured for, sorted.
+func (l *Levels) Components() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return slices.Sorted(maps.Keys(l.components))
+}
+
+// Snapshot returns the current root and component levels.
+func (l *Levels) Snapshot() LevelsSnapshot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	snapshot := LevelsSnapshot{
+		Root:       l.root.Level().String(),
+		Components: make(map[string]ComponentLevel, len(l.components)),
+	}
+	if r, ok := l.reverts[""]; ok {
+		snapshot.RootRevert = &r.at
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(l.components)) {
+		c := l.components[name]
+		entry := ComponentLevel{
+			Level:      c.Level().String(),
+			Overridden: c.override.Load() != nil,
+		}
+		if r, ok := l.reverts[name]; ok {
+			entry.RevertAt = &r.at
+		}
+		snapshot.Components[name] = entry
+	}
+	return snapshot
+}
+
+func (l *Levels) component(name string) *componentLevel {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c, ok := l.components[name]
+	if !ok {
+		c = &componentLevel{root: l.root}
+		l.components[name] = c
+	}
+	return c
+}
+
+// setLocked sets the root level (component "") or a component override;
+// a nil level clears the override. The caller must hold l.mu.
+func (l *Levels) setLocked(component string, level *slog.Level) {
+	if component == "" {
+		if level != nil {
+			l.root.Set(*level)
+		}
+		return
+	}
+
+	c, ok := l.components[component]
+	if !ok {
+		c = &componentLevel{root: l.root}
+		l.components[component] = c
+	}
+	c.override.Store(level)
+}
+
+// currentLocked returns the value setLocked would need to restore the
+// present state of component. The caller must hold l.mu.
+func (l *Levels) currentLocked(component string) *slog.Level {
+	if component == "" {
+		level := l.root.Level()
+		return &level
+	}
+	if c, ok := l.components[component]; ok {
+		return c.override.Load()
+	}
+	return nil
+}
+
+// scheduleRevert arranges for component to return to its current state
+// after ttl. If a revert is already pending, the originally saved state is
+// kept so repeated temporary changes still end at the permanent level.
+// The caller must hold l.mu.
+func (l *Levels) scheduleRevert(component string, ttl time.Duration) {
+	existing, pending := l.reverts[component]
+	if pending {
+		existing.timer.Stop()
+		delete(l.reverts, component)
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	previous := l.currentLocked(component)
+	if pending {
+		previous = existing.previous
+	}
+
+	r := &pendingRevert{at: time.Now().Add(ttl), previous: previous}
+	r.timer = time.AfterFunc(ttl, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if l.reverts[component] != r {
+			return
+		}
+		delete(l.reverts, component)
+		l.setLocked(component, r.previous)
+	})
+	l.reverts[component] = r
+}
+
+// cancelRevert stops a pending revert for component. The caller must hold l.mu.
+func (l *Levels) cancelRevert(component string) {
+	if r, ok := l.reverts[component]; ok {
+		r.timer.Stop()
+		delete(l.reverts, component)
+	}
+}
+
+func (c *componentLevel) Level() slog.Level {
+	if level := c.override.Load(); level != nil {
+		return *level
+	}
+	return c.root.Level()
+}
+
+// levelHandler gates records on a Leveler that may change at runtime.
+type levelHandler struct {
+	next  slog.Handler
+	level slog.Leveler
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.next.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.next.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{next: h.next.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{next: h.next.WithGroup(name), level: h.level}
+}
@@ -1,31 +1,136 @@
 package logger
 
 import (
+	"io"
 	"log/slog"
 	"os"
 	"strings"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-func New(level string) *slog.Logger {
-	var logLevel slog.Level
+// Config describes how log records are formatted, where they are written,
+// and which of them are sampled or redacted before output.
+type Config struct {
+	Level      string
+	Format     string // json, text or pretty
+	Output     string // stdout, stderr or file
+	File       FileConfig
+	Sampling   SamplingConfig
+	RedactKeys []string
+
+	// ComponentLevels overrides the level for individual components,
+	// e.g. {"repository": "debug"}.
+	ComponentLevels map[string]string
+}
+
+// FileConfig controls the rotating file sink used when Output is "file".
+type FileConfig struct {
+	Path       string
+	MaxSizeMB  int
+	MaxAgeDays int
+	MaxBackups int
+	Compress   bool
+}
+
+// SamplingConfig limits high-volume info and debug logs. Within each Tick,
+// the first Initial records with the same message are logged and after that
+// only every Thereafter-th one.
+type SamplingConfig struct {
+	Enabled    bool
+	Tick       time.Duration
+	Initial    int
+	Thereafter int
+}
+
+// Logger is the application's root logger. It hands out named component
+// loggers that share one output but each have a level that can be changed
+// at runtime through Levels.
+type Logger struct {
+	*slog.Logger
+	handler slog.Handler
+	levels  *Levels
+}
+
+func New(cfg Config) *Logger {
+	// Level filtering happens in levelHandler so that it can be changed at
+	// runtime; the underlying handler accepts everything.
+	opts := &slog.HandlerOptions{
+		Level:       slog.LevelDebug,
+		ReplaceAttr: redactAttrs(cfg.RedactKeys),
+	}
+
+	out := newOutput(cfg)
+
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "text":
+		handler = slog.NewTextHandler(out, opts)
+	case "pretty":
+		handler = newPrettyHandler(out, opts)
+	default:
+		handler = slog.NewJSONHandler(out, opts)
+	}
+
+	if cfg.Sampling.Enabled {
+		handler = newSamplingHandler(handler, cfg.Sampling)
+	}
+
+	levels := NewLevels(ParseLevel(cfg.Level))
+	levels.Apply(cfg.Level, cfg.ComponentLevels)
 
+	return &Logger{
+		Logger:  slog.New(&levelHandler{next: handler, level: levels.root}),
+		handler: handler,
+		levels:  levels,
+	}
+}
+
+// Component returns a logger tagged with the component name whose level
+// follows the root level unless it has been overridden for that component.
+func (l *Logger) Component(name string) *slog.Logger {
+	h := &levelHandler{next: l.handler, level: l.levels.component(name)}
+	return slog.New(h).With("component", name)
+}
+
+// Levels returns the runtime level controls for this logger.
+func (l *Logger) Levels() *Levels {
+	return l.levels
+}
+
+// ParseLevel converts a level name to a slog.Level, defaulting to info.
+func ParseLevel(level string) slog.Level {
 	switch strings.ToLower(level) {
 	case "debug":
-		logLevel = slog.LevelDebug
+		return slog.LevelDebug
 	case "info":
-		logLevel = slog.LevelInfo
+		return slog.LevelInfo
 	case "warn":
-		logLevel = slog.LevelWarn
+		return slog.LevelWarn
 	case "error":
-		logLevel = slog.LevelError
+		return slog.LevelError
 	default:
-		logLevel = slog.LevelInfo
+		return slog.LevelInfo
 	}
+}
 
-	opts := &slog.HandlerOptions{
-		Level: logLevel,
+func newOutput(cfg Config) io.Writer {
+	switch strings.ToLower(cfg.Output) {
+	case "stderr":
+		return os.Stderr
+	case "file":
+		if cfg.File.Path == "" {
+			return os.Stdout
+		}
+		return &lumberjack.Logger{
+			Filename:   cfg.File.Path,
+			MaxSize:    cfg.File.MaxSizeMB,
+			MaxAge:     cfg.File.MaxAgeDays,
+			MaxBackups: cfg.File.MaxBackups,
+			Compress:   cfg.File.Compress,
+		}
+	default:
+		return os.Stdout
 	}
-
-	handler := slog.NewJSONHandler(os.Stdout, opts)
-	return slog.New(handler)
 }
@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorGray   = "\033[90m"
+	colorRed    = "\033[31m"
+	colorYellow = "\033[33m"
+	colorBlue   = "\033[34m"
+	colorCyan   = "\033[36m"
+)
+
+// prettyHandler writes human-readable, colorized single-line records.
+// It is meant for local development; use JSON or text in production.
+type prettyHandler struct {
+	opts   *slog.HandlerOptions
+	mu     *sync.Mutex
+	out    io.Writer
+	groups []string
+	attrs  []byte
+}
+
+func newPrettyHandler(out io.Writer, opts *slog.HandlerOptions) *prettyHandler {
+	return &prettyHandler{opts: opts, mu: &sync.Mutex{}, out: out}
+}
+
+func (h *prettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *prettyHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+
+	buf.WriteString(colorGray)
+	buf.WriteString(r.Time.Format(time.TimeOnly + ".000"))
+	buf.WriteString(colorReset)
+	buf.WriteByte(' ')
+	buf.WriteString(levelColor(r.Level))
+	fmt.Fprintf(&buf, "%-5s", r.Level.String())
+	buf.WriteString(colorReset)
+	buf.WriteByte(' ')
+	buf.WriteString(r.Message)
+	buf.Write(h.attrs)
+
+	r.Attrs(func(a slog.Attr) bool {
+		h.appendAttr(&buf, h.groups, a)
+		return true
+	})
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.out.Write(buf.Bytes())
+	return err
+}
+
+func (h *prettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var buf bytes.Buffer
+	buf.Write(h.attrs)
+	for _, a := range attrs {
+		h.appendAttr(&buf, h.groups, a)
+	}
+
+	clone := *h
+	clone.attrs = buf.Bytes()
+	return &clone
+}
+
+func (h *prettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	clone := *h
+	clone.groups = append(slices.Clip(h.groups), name)
+	return &clone
+}
+
+func (h *prettyHandler) appendAttr(buf *bytes.Buffer, groups []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup && h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		nested := groups
+		if a.Key != "" {
+			nested = append(slices.Clip(groups), a.Key)
+		}
+		for _, ga := range a.Value.Group() {
+			h.appendAttr(buf, nested, ga)
+		}
+		return
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(colorCyan)
+	for _, g := range groups {
+		buf.WriteString(g)
+		buf.WriteByte('.')
+	}
+	buf.WriteString(a.Key)
+	buf.WriteString(colorReset)
+	buf.WriteByte('=')
+	fmt.Fprintf(buf, "%v", a.Value.Any())
+}
+
+func levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return colorRed
+	case level >= slog.LevelWarn:
+		return colorYellow
+	case level >= slog.LevelInfo:
+		return colorBlue
+	default:
+		return colorGray
+	}
+}
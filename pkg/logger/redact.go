@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"log/slog"
+	"strings"
+)
+
+const redactedValue = "[REDACTED]"
+
+// redactAttrs returns a ReplaceAttr function that masks the value of any
+// attribute whose key matches one of keys, case-insensitively. Attributes
+// nested inside groups are matched on their own key.
+func redactAttrs(keys []string) func(groups []string, a slog.Attr) slog.Attr {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	redacted := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key != "" {
+			redacted[key] = struct{}{}
+		}
+	}
+
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if _, ok := redacted[strings.ToLower(a.Key)]; ok {
+			return slog.String(a.Key, redactedValue)
+		}
+		return a
+	}
+}
@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// samplingHandler drops repeated info and debug records once a message has
+// been seen more than Initial times within the current tick. Warnings and
+// errors are never sampled.
+type samplingHandler struct {
+	next  slog.Handler
+	state *samplingState
+}
+
+type samplingState struct {
+	mu         sync.Mutex
+	tick       time.Duration
+	initial    int
+	thereafter int
+	windowEnd  time.Time
+	counts     map[string]int
+}
+
+func newSamplingHandler(next slog.Handler, cfg SamplingConfig) *samplingHandler {
+	if cfg.Tick <= 0 {
+		cfg.Tick = time.Second
+	}
+	if cfg.Initial <= 0 {
+		cfg.Initial = 100
+	}
+	if cfg.Thereafter <= 0 {
+		cfg.Thereafter = 100
+	}
+
+	return &samplingHandler{
+		next: next,
+		state: &samplingState{
+			tick:       cfg.Tick,
+			initial:    cfg.Initial,
+			thereafter: cfg.Thereafter,
+			counts:     make(map[string]int),
+		},
+	}
+}
+
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level > slog.LevelInfo || h.state.allow(r.Message, r.Time) {
+		return h.next.Handle(ctx, r)
+	}
+	return nil
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{next: h.next.WithAttrs(attrs), state: h.state}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{next: h.next.WithGroup(name), state: h.state}
+}
+
+func (s *samplingState) allow(message string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.After(s.windowEnd) {
+		s.windowEnd = now.Add(s.tick)
+		clear(s.counts)
+	}
+
+	s.counts[message]++
+	n := s.counts[message]
+	if n <= s.initial {
+		return true
+	}
+	return (n-s.initial)%s.thereafter == 0
+}
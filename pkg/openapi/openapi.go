@@ -0,0 +1,199 @@
+// Package openapi builds OpenAPI 3.1 documents. Operations are added per
+// route and the schemas of the Go types they exchange are derived by
+// reflection, so the document follows the code it describes.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Version is the OpenAPI version documents are written in
+const Version = "3.1.0"
+
+// Document is the root of an OpenAPI document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path, keyed by lower-case method
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+// Parameter locations
+const (
+	InPath   = "path"
+	InQuery  = "query"
+	InHeader = "header"
+)
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Spec collects operations, and the schemas they use, into a Document.
+// Errors, such as an unsupported type or a reused operation id, are kept
+// and returned by Document.
+type Spec struct {
+	doc   *Document
+	types map[string]reflect.Type
+	ids   map[string]string
+	err   error
+}
+
+func New(info Info) *Spec {
+	return &Spec{
+		doc: &Document{
+			OpenAPI: Version,
+			Info:    info,
+			Paths:   make(map[string]PathItem),
+			Components: Components{
+				Schemas: make(map[string]*Schema),
+			},
+		},
+		types: make(map[string]reflect.Type),
+		ids:   make(map[string]string),
+	}
+}
+
+func (s *Spec) AddSecurityScheme(name string, scheme *SecurityScheme) {
+	if s.doc.Components.SecuritySchemes == nil {
+		s.doc.Components.SecuritySchemes = make(map[string]*SecurityScheme)
+	}
+	s.doc.Components.SecuritySchemes[name] = scheme
+}
+
+// pathParam matches a chi path parameter, with an optional regexp
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// Add documents op as the operation for method on a chi route pattern.
+// Path parameters of the pattern that op does not declare are added as
+// strings.
+func (s *Spec) Add(method, pattern string, op *Operation) {
+	route := method + " " + pattern
+	if op.OperationID == "" {
+		s.fail(fmt.Errorf("openapi: %s has no operation id", route))
+		return
+	}
+	if other, ok := s.ids[op.OperationID]; ok {
+		s.fail(fmt.Errorf("openapi: %s and %s share the operation id %q", other, route, op.OperationID))
+		return
+	}
+	s.ids[op.OperationID] = route
+
+	for _, match := range pathParam.FindAllStringSubmatch(pattern, -1) {
+		declared := slices.ContainsFunc(op.Parameters, func(p *Parameter) bool {
+			return p.In == InPath && p.Name == match[1]
+		})
+		if !declared {
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name:     match[1],
+				In:       InPath,
+				Required: true,
+				Schema:   &Schema{Type: Types{"string"}},
+			})
+		}
+	}
+
+	path := Path(pattern)
+	if s.doc.Paths[path] == nil {
+		s.doc.Paths[path] = make(PathItem)
+	}
+	s.doc.Paths[path][strings.ToLower(method)] = op
+}
+
+// Document returns the document, or the first error met while building it
+func (s *Spec) Document() (*Document, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.doc, nil
+}
+
+func (s *Spec) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Path turns a chi route pattern into an OpenAPI path: parameter regexps
+// and trailing slashes are dropped, so "/users/{id:[0-9]+}/" becomes
+// "/users/{id}".
+func Path(pattern string) string {
+	path := pathParam.ReplaceAllString(pattern, "{$1}")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+// Marshal encodes the document as indented JSON ending in a newline, so
+// that generated files diff cleanly
+func (d *Document) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
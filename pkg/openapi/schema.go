@@ -0,0 +1,260 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema (draft 2020-12), as used by OpenAPI 3.1
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 Types              `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	ContentMediaType     string             `json:"contentMediaType,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+}
+
+// Types is a schema's type keyword, written as a single string when it
+// holds one type
+type Types []string
+
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// Object describes a JSON object by example, for payloads that are not Go
+// structs. Each value is a Go value, or a *Schema, of the property's type.
+// Properties are required unless wrapped with Optional.
+type Object map[string]any
+
+type optional struct {
+	value any
+}
+
+// Optional marks a property of an Object as not always present
+func Optional(value any) any {
+	return optional{value}
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// Schema returns the schema of value's type. Named struct types are added
+// to the document's components and referenced; a *Schema or nil value is
+// returned as is.
+func (s *Spec) Schema(value any) *Schema {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case *Schema:
+		return v
+	case Object:
+		return s.object(v)
+	}
+	return s.schemaOf(reflect.TypeOf(value))
+}
+
+func (s *Spec) object(obj Object) *Schema {
+	schema := &Schema{Type: Types{"object"}, Properties: make(map[string]*Schema, len(obj))}
+	for name, value := range obj {
+		if opt, ok := value.(optional); ok {
+			schema.Properties[name] = s.Schema(opt.value)
+			continue
+		}
+		schema.Properties[name] = s.Schema(value)
+		schema.Required = append(schema.Required, name)
+	}
+	// Map order is random; keep the document stable
+	slices.Sort(schema.Required)
+	return schema
+}
+
+func (s *Spec) schemaOf(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: Types{"string"}, Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: Types{"string"}}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.schemaOf(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: Types{"integer"}, Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: Types{"integer"}, Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: Types{"number"}, Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: Types{"number"}, Format: "double"}
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: Types{"string"}, ContentEncoding: "base64"}
+		}
+		return &Schema{Type: Types{"array"}, Items: s.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: Types{"object"}, AdditionalProperties: s.schemaOf(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		return s.component(t)
+	}
+
+	s.fail(fmt.Errorf("openapi: unsupported type %s", t))
+	return &Schema{}
+}
+
+// component adds the schema of a named struct type to the components,
+// once, and returns a reference to it
+func (s *Spec) component(t reflect.Type) *Schema {
+	name := componentName(t)
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+
+	if other, ok := s.types[name]; ok {
+		if other != t {
+			s.fail(fmt.Errorf("openapi: %s and %s are both named %s", other, t, name))
+		}
+		return ref
+	}
+
+	// Register the name first so that recursive types end in a reference
+	s.types[name] = t
+	s.doc.Components.Schemas[name] = s.structSchema(t)
+	return ref
+}
+
+// typeArgPackage matches the package path qualifying a type argument
+var typeArgPackage = regexp.MustCompile(`[\w./-]*\.`)
+
+// componentName names a struct type's schema after the type, with type
+// arguments appended: BatchRequest[domain.CreateUserRequest] becomes
+// BatchRequestCreateUserRequest
+func componentName(t reflect.Type) string {
+	name, args, generic := strings.Cut(t.Name(), "[")
+	if !generic {
+		return name
+	}
+	args = typeArgPackage.ReplaceAllString(strings.TrimSuffix(args, "]"), "")
+	return name + strings.Map(func(r rune) rune {
+		if r == ',' || r == '*' || r == '[' || r == ']' || r == ' ' {
+			return -1
+		}
+		return r
+	}, args)
+}
+
+// structSchema follows encoding/json: fields named by their json tag,
+// embedded structs inlined, and shallower fields winning over deeper ones.
+// Fields without omitempty are required; pointers among them may be null.
+func (s *Spec) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: Types{"object"}, Properties: make(map[string]*Schema)}
+
+	level := []reflect.Type{t}
+	for len(level) > 0 {
+		var embedded []reflect.Type
+		found := make(map[string]bool)
+
+		for _, t := range level {
+			for i := range t.NumField() {
+				f := t.Field(i)
+				tag := f.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+
+				if f.Anonymous && name == "" {
+					ft := f.Type
+					if ft.Kind() == reflect.Pointer {
+						ft = ft.Elem()
+					}
+					if ft.Kind() == reflect.Struct {
+						embedded = append(embedded, ft)
+						continue
+					}
+				}
+				if !f.IsExported() {
+					continue
+				}
+				if name == "" {
+					name = f.Name
+				}
+				if _, ok := schema.Properties[name]; ok || found[name] {
+					continue
+				}
+				found[name] = true
+
+				field := s.schemaOf(f.Type)
+				if hasOption(opts, "omitempty") || hasOption(opts, "omitzero") {
+					schema.Properties[name] = field
+					continue
+				}
+				if f.Type.Kind() == reflect.Pointer {
+					field = nullable(field)
+				}
+				schema.Properties[name] = field
+				schema.Required = append(schema.Required, name)
+			}
+		}
+		level = embedded
+	}
+
+	return schema
+}
+
+func hasOption(opts, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// nullable allows null as well as the values schema allows
+func nullable(schema *Schema) *Schema {
+	if len(schema.Type) == 0 {
+		if schema.Ref == "" {
+			// Already anything
+			return schema
+		}
+		return &Schema{AnyOf: []*Schema{schema, {Type: Types{"null"}}}}
+	}
+
+	copied := *schema
+	copied.Type = append(Types{}, schema.Type...)
+	copied.Type = append(copied.Type, "null")
+	return &copied
+}
@@ -0,0 +1,22 @@
+package riverenqueuer
+
+import (
+	"database/sql"
+	"log/slog"
+
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/riverdriver/riverdatabasesql"
+)
+
+// NewClient creates a River client that works jobs with the given workers.
+// The database/sql driver cannot LISTEN, so the client polls for new jobs.
+func NewClient(db *sql.DB, workers *river.Workers, maxWorkers int, logger *slog.Logger) (*river.Client[*sql.Tx], error) {
+	return river.NewClient(riverdatabasesql.New(db), &river.Config{
+		Queues: map[string]river.QueueConfig{
+			river.QueueDefault: {MaxWorkers: maxWorkers},
+		},
+		Workers:  workers,
+		PollOnly: true,
+		Logger:   logger,
+	})
+}
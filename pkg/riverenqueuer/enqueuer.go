@@ -0,0 +1,49 @@
+package riverenqueuer
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/riverdriver/riverdatabasesql"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/database"
+)
+
+// Enqueuer inserts jobs without working them. A job enqueued with a context
+// carrying a database.DB transaction is inserted in that transaction, so it
+// exists if and only if the transaction commits.
+type Enqueuer struct {
+	client *river.Client[*sql.Tx]
+}
+
+func NewEnqueuer(db *sql.DB, logger *slog.Logger) (*Enqueuer, error) {
+	// Insert-only client; jobs are worked by the client from NewClient
+	client, err := river.NewClient(riverdatabasesql.New(db), &river.Config{Logger: logger})
+	if err != nil {
+		return nil, err
+	}
+	return &Enqueuer{client: client}, nil
+}
+
+// Enqueue inserts one job. opts may be nil.
+func (e *Enqueuer) Enqueue(ctx context.Context, args river.JobArgs, opts *river.InsertOpts) error {
+	var err error
+	if tx, ok := database.Tx(ctx); ok {
+		_, err = e.client.InsertTx(ctx, tx, args, opts)
+	} else {
+		_, err = e.client.Insert(ctx, args, opts)
+	}
+	return err
+}
+
+// EnqueueMany inserts several jobs in one statement.
+func (e *Enqueuer) EnqueueMany(ctx context.Context, params []river.InsertManyParams) error {
+	var err error
+	if tx, ok := database.Tx(ctx); ok {
+		_, err = e.client.InsertManyTx(ctx, tx, params)
+	} else {
+		_, err = e.client.InsertMany(ctx, params)
+	}
+	return err
+}
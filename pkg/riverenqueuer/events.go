@@ -0,0 +1,124 @@
+package riverenqueuer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/riverqueue/river"
+	"github.com/sathwik-aileneni/go-rest-api-boilerplate/pkg/eventbus"
+)
+
+// EventArgs hands one domain event to one durable subscriber.
+type EventArgs struct {
+	Subscriber string          `json:"subscriber"`
+	Event      string          `json:"event"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+func (EventArgs) Kind() string { return "domain_event" }
+
+// EventBridge turns domain events into River jobs for durable subscribers.
+// The job is inserted in the publisher's transaction when there is one, so
+// it exists if and only if the change was committed. Each subscriber gets
+// its own job and is retried independently, at least once.
+type EventBridge struct {
+	bus      *eventbus.Bus
+	enqueuer *Enqueuer
+	logger   *slog.Logger
+
+	mu       sync.RWMutex
+	handlers map[string]map[string]func(ctx context.Context, payload json.RawMessage) error // event -> subscriber
+}
+
+// NewEventBridge registers the bridge's worker with workers, which must then
+// be used by the client that works jobs.
+func NewEventBridge(db *sql.DB, bus *eventbus.Bus, workers *river.Workers, logger *slog.Logger) (*EventBridge, error) {
+	enqueuer, err := NewEnqueuer(db, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &EventBridge{
+		bus:      bus,
+		enqueuer: enqueuer,
+		logger:   logger,
+		handlers: make(map[string]map[string]func(ctx context.Context, payload json.RawMessage) error),
+	}
+	river.AddWorker(workers, &eventWorker{bridge: b})
+
+	return b, nil
+}
+
+// Subscribe delivers events with the given name to handler through River.
+// The subscriber name must be stable across deploys: queued jobs are
+// matched to handlers by it.
+func (b *EventBridge) Subscribe(event, subscriber string, handler func(ctx context.Context, payload json.RawMessage) error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.handlers[event]; !ok {
+		b.handlers[event] = make(map[string]func(ctx context.Context, payload json.RawMessage) error)
+		b.bus.Subscribe(event, b.enqueue)
+	}
+	b.handlers[event][subscriber] = handler
+}
+
+// OnDurable subscribes a handler for events of type E through River.
+func OnDurable[E eventbus.Event](b *EventBridge, subscriber string, handler func(ctx context.Context, event E) error) {
+	var zero E
+	b.Subscribe(zero.EventName(), subscriber, func(ctx context.Context, payload json.RawMessage) error {
+		var event E
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return river.JobCancel(fmt.Errorf("failed to decode %s: %w", zero.EventName(), err))
+		}
+		return handler(ctx, event)
+	})
+}
+
+func (b *EventBridge) enqueue(ctx context.Context, event eventbus.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	b.mu.RLock()
+	params := make([]river.InsertManyParams, 0, len(b.handlers[event.EventName()]))
+	for subscriber := range b.handlers[event.EventName()] {
+		params = append(params, river.InsertManyParams{Args: EventArgs{
+			Subscriber: subscriber,
+			Event:      event.EventName(),
+			Payload:    payload,
+		}})
+	}
+	b.mu.RUnlock()
+
+	if err := b.enqueuer.EnqueueMany(ctx, params); err != nil {
+		return fmt.Errorf("failed to enqueue event: %w", err)
+	}
+	return nil
+}
+
+func (b *EventBridge) handler(event, subscriber string) (func(ctx context.Context, payload json.RawMessage) error, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	handler, ok := b.handlers[event][subscriber]
+	return handler, ok
+}
+
+type eventWorker struct {
+	river.WorkerDefaults[EventArgs]
+	bridge *EventBridge
+}
+
+func (w *eventWorker) Work(ctx context.Context, job *river.Job[EventArgs]) error {
+	handler, ok := w.bridge.handler(job.Args.Event, job.Args.Subscriber)
+	if !ok {
+		// The subscriber was removed; retrying will not help
+		return river.JobCancel(fmt.Errorf("no durable subscriber %q for %s", job.Args.Subscriber, job.Args.Event))
+	}
+	return handler(ctx, job.Args.Payload)
+}
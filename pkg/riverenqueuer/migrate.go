@@ -3,6 +3,7 @@ package riverenqueuer
 import (
 	"context"
 	"database/sql"
+	"log/slog"
 
 	"github.com/riverqueue/river/riverdriver/riverdatabasesql"
 	"github.com/riverqueue/river/rivermigrate"
@@ -11,8 +12,8 @@ import (
 // MigrateUp runs River schema migrations.
 // This is idempotent and safe to call on every application startup.
 // It creates the river_job, river_leader, and river_migration tables if they don't exist.
-func MigrateUp(ctx context.Context, db *sql.DB) error {
-	migrator, err := rivermigrate.New(riverdatabasesql.New(db), nil)
+func MigrateUp(ctx context.Context, db *sql.DB, logger *slog.Logger) error {
+	migrator, err := rivermigrate.New(riverdatabasesql.New(db), &rivermigrate.Config{Logger: logger})
 	if err != nil {
 		return err
 	}